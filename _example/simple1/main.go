@@ -88,13 +88,8 @@ func createFileKey() (*totp.Key, error) {
 		return nil, errors.Wrap(err, "Failed to generate key")
 	}
 
-	keyPEM, err := keyObj.PEM()
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to get PEM encoded key")
-	}
-
-	if err = os.WriteFile(NameFilePEM, []byte(keyPEM), FilePerm); err != nil {
-		return nil, errors.Wrap(err, "Failed to write PEM encoded key")
+	if err = keyObj.SavePEM(NameFilePEM, FilePerm); err != nil {
+		return nil, errors.Wrap(err, "Failed to save PEM encoded key")
 	}
 
 	return keyObj, nil
@@ -107,17 +102,18 @@ func createFilePNG(key *totp.Key) error {
 		return errors.Wrap(err, "fail to get QR code object")
 	}
 
-	imgByte, err := qrCode.PNG(ImageWidth, ImageHeight)
-	if err != nil {
-		return errors.Wrap(err, "fail to get PNG image")
+	if err := qrCode.Save(NameFileQRCode, ImageWidth, ImageHeight, FilePerm); err != nil {
+		return errors.Wrap(err, "failed to save PNG image")
 	}
 
-	if err := os.WriteFile(NameFileQRCode, imgByte, FilePerm); err != nil {
-		return errors.Wrap(err, "failed to write PNG image")
+	qrTerminal, err := key.QRCodeTerminal(FixLevel)
+	if err != nil {
+		return errors.Wrap(err, "failed to get QR code for terminal")
 	}
 
 	fmt.Println("- PNG image saved to:", NameFileQRCode)
-	fmt.Println("  Please add your TOTP to your OTP Application now!")
+	fmt.Print(qrTerminal)
+	fmt.Println("  Please scan the QR code above or add your TOTP to your OTP Application now!")
 
 	return nil
 }
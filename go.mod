@@ -5,7 +5,7 @@ go 1.22
 require (
 	github.com/boombuler/barcode v1.0.2
 	github.com/pkg/errors v0.9.1
-	github.com/pquerna/otp v1.4.0
+	github.com/pquerna/otp v1.5.0
 	github.com/stretchr/testify v1.10.0
 	github.com/zeebo/blake3 v0.2.4
 )
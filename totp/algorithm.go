@@ -1,6 +1,11 @@
 package totp
 
 import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -72,6 +77,65 @@ func NewAlgorithmID(algoID int) (Algorithm, error) {
 //  Methods
 // ----------------------------------------------------------------------------
 
+// BlockSize returns the block size of the hash function of the algorithm in
+// bytes. Which is 64 for MD5, SHA1 and SHA256, and 128 for SHA512.
+//
+// Unsupported algorithms return 0.
+func (algo Algorithm) BlockSize() int {
+	switch algo {
+	case "MD5":
+		return md5.BlockSize
+	case OptionAlgorithmDefault: // SHA1
+		return sha1.BlockSize
+	case "SHA256":
+		return sha256.BlockSize
+	case "SHA512":
+		return sha512.BlockSize
+	default:
+		return 0
+	}
+}
+
+// HashFunc returns the constructor of the hash function of the algorithm from
+// the standard library, such as sha1.New. It complements OTPAlgorithm() for
+// building custom primitives, such as HMAC or the options of the underlying OTP
+// library.
+//
+// Unsupported algorithms return an error wrapping ErrUnsupportedAlgorithm.
+func (algo Algorithm) HashFunc() (func() hash.Hash, error) {
+	switch algo {
+	case "MD5":
+		return md5.New, nil
+	case OptionAlgorithmDefault: // SHA1
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedAlgorithm, "%q", algo)
+	}
+}
+
+// HashSize returns the output size of the hash function of the algorithm in
+// bytes. Which is 16 for MD5, 20 for SHA1, 32 for SHA256 and 64 for SHA512.
+//
+// Unsupported algorithms return 0.
+func (algo Algorithm) HashSize() int {
+	switch algo {
+	case "MD5":
+		return md5.Size
+	case OptionAlgorithmDefault: // SHA1
+		return sha1.Size
+	case "SHA256":
+		return sha256.Size
+	case "SHA512":
+		return sha512.Size
+	default:
+		return 0
+	}
+}
+
 // ID returns the ID of the algorithm which is the same int value as the
 // original OTP library.
 //
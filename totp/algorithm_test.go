@@ -46,6 +46,44 @@ func TestAlgorithm_IsSupported_golden(t *testing.T) {
 	}
 }
 
+func TestAlgorithm_HashSize_BlockSize(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		algo      string
+		hashSize  int
+		blockSize int
+	}{
+		{"MD5", 16, 64},
+		{"SHA1", 20, 64},
+		{"SHA256", 32, 64},
+		{"SHA512", 64, 128},
+		{"BLAKE3", 0, 0}, // Unsupported algorithm should return 0.
+	} {
+		algo := Algorithm(test.algo)
+
+		require.Equal(t, test.hashSize, algo.HashSize(), "unexpected hash size of %s", test.algo)
+		require.Equal(t, test.blockSize, algo.BlockSize(), "unexpected block size of %s", test.algo)
+	}
+}
+
+func TestAlgorithm_HashFunc(t *testing.T) {
+	t.Parallel()
+
+	for _, algo := range []Algorithm{"MD5", "SHA1", "SHA256", "SHA512"} {
+		hashFunc, err := algo.HashFunc()
+		require.NoError(t, err)
+
+		hasher := hashFunc()
+		require.Equal(t, algo.HashSize(), hasher.Size(), "unexpected hash size of %s", algo)
+		require.Equal(t, algo.BlockSize(), hasher.BlockSize(), "unexpected block size of %s", algo)
+	}
+
+	hashFunc, err := Algorithm("BLAKE3").HashFunc()
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	require.Nil(t, hashFunc)
+}
+
 func TestAlgorithm_ID_unsupported(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,140 @@
+package totp
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: AppProfile
+// ----------------------------------------------------------------------------
+
+// AppProfile represents a target authenticator app with its quirks. Use the
+// `App*` constants with GenerateKeyForApp() to generate a key compatible with
+// the app.
+//
+// Many apps silently ignore the algorithm, digits or period of the URI and
+// generate passcodes with their own fixed values. Which leads to a validation
+// mismatch that is hard to debug.
+type AppProfile int
+
+// Profiles of the authenticator apps.
+const (
+	// AppGeneric has no restrictions. Any supported algorithm, digits and
+	// period are allowed. Same as GenerateKey().
+	AppGeneric AppProfile = iota
+	// AppGoogleAuthenticator forces SHA1, 6 digits and 30 seconds of period.
+	// Google Authenticator ignores other values.
+	AppGoogleAuthenticator
+	// AppMicrosoftAuthenticator forces SHA1, 6 digits and 30 seconds of period.
+	// Microsoft Authenticator ignores other values.
+	AppMicrosoftAuthenticator
+	// AppAuthy forces SHA1 and 30 seconds of period. Both 6 and 8 digits are
+	// allowed.
+	AppAuthy
+)
+
+// ----------------------------------------------------------------------------
+//  Constructor
+// ----------------------------------------------------------------------------
+
+// GenerateKeyForApp is similar to GenerateKey() but generates a key compatible
+// with the given authenticator app profile.
+//
+// The algorithm, digits and period are preset to the values the app supports.
+// It returns an error if the opts override them with the values the app does
+// not support. Such as WithAlgorithm("SHA256") for AppGoogleAuthenticator.
+func GenerateKeyForApp(issuer, accountName string, profile AppProfile, opts ...Option) (*Key, error) {
+	if !profile.IsSupported() {
+		return nil, errors.Errorf("unsupported app profile: %d", profile)
+	}
+
+	// Apply the app profile and then the custom options.
+	optsPreset, err := resolveOptions(issuer, accountName, append([]Option{profile.preset()}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := profile.check(*optsPreset); err != nil {
+		return nil, errors.Wrapf(err, "incompatible options for %s", profile)
+	}
+
+	return GenerateKeyCustom(*optsPreset)
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// IsSupported returns true if the app profile is one of the `App*` constants.
+func (p AppProfile) IsSupported() bool {
+	return p >= AppGeneric && p <= AppAuthy
+}
+
+// String returns the name of the app profile.
+func (p AppProfile) String() string {
+	switch p {
+	case AppGeneric:
+		return "Generic"
+	case AppGoogleAuthenticator:
+		return "Google Authenticator"
+	case AppMicrosoftAuthenticator:
+		return "Microsoft Authenticator"
+	case AppAuthy:
+		return "Authy"
+	}
+
+	return "Unknown"
+}
+
+// check returns an error if the options are not supported by the app.
+func (p AppProfile) check(opts Options) error {
+	algos, digits, periods := p.constraints()
+
+	switch {
+	case algos != nil && !slices.Contains(algos, opts.Algorithm):
+		return errors.Errorf("unsupported algorithm: %s. it should be %v", opts.Algorithm, algos)
+	case digits != nil && !slices.Contains(digits, opts.Digits):
+		return errors.Errorf("unsupported digits: %d. it should be %v", opts.Digits, digits)
+	case periods != nil && !slices.Contains(periods, opts.Period):
+		return errors.Errorf("unsupported period: %d. it should be %v", opts.Period, periods)
+	}
+
+	return nil
+}
+
+// constraints returns the allowed algorithms, digits and periods of the app.
+// Nil means no restriction.
+func (p AppProfile) constraints() ([]Algorithm, []Digits, []uint) {
+	switch p {
+	case AppGoogleAuthenticator, AppMicrosoftAuthenticator:
+		return []Algorithm{"SHA1"}, []Digits{DigitsSix}, []uint{OptionPeriodDefault}
+	case AppAuthy:
+		return []Algorithm{"SHA1"}, []Digits{DigitsSix, DigitsEight}, []uint{OptionPeriodDefault}
+	case AppGeneric:
+	}
+
+	return nil, nil, nil
+}
+
+// preset returns an option that sets the first allowed values of the app.
+func (p AppProfile) preset() Option {
+	return func(opts *Options) error {
+		algos, digits, periods := p.constraints()
+
+		if len(algos) > 0 {
+			opts.Algorithm = algos[0]
+		}
+
+		if len(digits) > 0 {
+			opts.Digits = digits[0]
+		}
+
+		if len(periods) > 0 {
+			opts.Period = periods[0]
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  GenerateKeyForApp()
+// ----------------------------------------------------------------------------
+
+func TestGenerateKeyForApp_presets(t *testing.T) {
+	t.Parallel()
+
+	for _, profile := range []AppProfile{
+		AppGeneric, AppGoogleAuthenticator, AppMicrosoftAuthenticator, AppAuthy,
+	} {
+		key, err := GenerateKeyForApp("Example.com", "alice@example.com", profile)
+		require.NoError(t, err, "profile: %s", profile)
+
+		require.Equal(t, Algorithm("SHA1"), key.Options.Algorithm, "profile: %s", profile)
+		require.Equal(t, DigitsSix, key.Options.Digits, "profile: %s", profile)
+		require.Equal(t, uint(30), key.Options.Period, "profile: %s", profile)
+	}
+}
+
+func TestGenerateKeyForApp_compatible_override(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKeyForApp("Example.com", "alice@example.com", AppAuthy, WithDigits(DigitsEight))
+	require.NoError(t, err)
+	require.Equal(t, DigitsEight, key.Options.Digits)
+
+	key, err = GenerateKeyForApp("Example.com", "alice@example.com", AppGeneric, WithAlgorithm("SHA512"))
+	require.NoError(t, err)
+	require.Equal(t, Algorithm("SHA512"), key.Options.Algorithm)
+}
+
+func TestGenerateKeyForApp_skew_duration(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKeyForApp("Example.com", "alice@example.com", AppGeneric,
+		WithSkewDuration(2*time.Minute), WithPeriod(60))
+	require.NoError(t, err)
+	require.Equal(t, uint(60), key.Options.Period)
+	require.Equal(t, uint(2), key.Options.Skew, "the skew duration should be resolved with the final period")
+}
+
+func TestGenerateKeyForApp_incompatible_override(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		profile AppProfile
+		opt     Option
+		expect  string
+	}{
+		{AppGoogleAuthenticator, WithAlgorithm("SHA256"), "unsupported algorithm: SHA256"},
+		{AppGoogleAuthenticator, WithDigits(DigitsEight), "unsupported digits: 8"},
+		{AppMicrosoftAuthenticator, WithPeriod(60), "unsupported period: 60"},
+		{AppAuthy, WithAlgorithm("SHA512"), "unsupported algorithm: SHA512"},
+	} {
+		key, err := GenerateKeyForApp("Example.com", "alice@example.com", test.profile, test.opt)
+
+		require.Error(t, err, "profile: %s", test.profile)
+		require.Contains(t, err.Error(), "incompatible options for "+test.profile.String())
+		require.Contains(t, err.Error(), test.expect)
+		require.Nil(t, key)
+	}
+}
+
+func TestGenerateKeyForApp_bad_args(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKeyForApp("Example.com", "alice@example.com", AppProfile(-1))
+	require.ErrorContains(t, err, "unsupported app profile: -1")
+	require.Nil(t, key)
+
+	key, err = GenerateKeyForApp("", "alice@example.com", AppGeneric)
+	require.ErrorContains(t, err, "issuer and accountName are required")
+	require.Nil(t, key)
+
+	key, err = GenerateKeyForApp("Example.com", "alice@example.com", AppGeneric, WithAlgorithm("BAD"))
+	require.ErrorContains(t, err, "failed to apply custom options")
+	require.Nil(t, key)
+}
+
+// ----------------------------------------------------------------------------
+//  AppProfile.String()
+// ----------------------------------------------------------------------------
+
+func TestAppProfile_String(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Google Authenticator", AppGoogleAuthenticator.String())
+	require.Equal(t, "Unknown", AppProfile(100).String())
+}
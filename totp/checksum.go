@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"github.com/pkg/errors"
+)
+
+// The checksum digit appended to the passcodes if WithChecksum() is set. It is
+// the Luhn (mod 10) check digit of the passcode, the same as the calcChecksum()
+// of the reference implementation in RFC-4226, Appendix C:
+//
+//	from the rightmost digit of the passcode, double every other digit starting
+//	with the rightmost one. If a doubled digit is greater than 9, subtract 9.
+//	total    = sum of all the digits
+//	checksum = (10 - total mod 10) mod 10
+//
+// e.g. "123456" → "1234566", "000000" → "0000000".
+//
+// See: https://www.rfc-editor.org/rfc/rfc4226#appendix-C
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// appendChecksum returns the passcode with the checksum digit appended. The
+// passcode is returned as is if it is not numeric.
+func appendChecksum(passcode string) string {
+	checkDigit, ok := luhnDigit(passcode)
+	if !ok {
+		return passcode
+	}
+
+	return passcode + string(checkDigit)
+}
+
+// stripChecksum verifies the trailing checksum digit of the passcode and returns
+// the passcode without it. The returned error wraps ErrEmptyPasscode or
+// ErrMalformedPasscode.
+func stripChecksum(passcode string) (string, error) {
+	if passcode == "" {
+		return "", errors.WithStack(ErrEmptyPasscode)
+	}
+
+	split := len(passcode) - 1
+	code := passcode[:split]
+
+	checkDigit, ok := luhnDigit(code)
+	if !ok || code == "" || checkDigit != passcode[split] {
+		return "", errors.Wrap(ErrMalformedPasscode, "checksum mismatch")
+	}
+
+	return code, nil
+}
+
+// luhnDigit returns the Luhn check digit of the given number as a character. It
+// returns false if the number contains non-digit characters.
+func luhnDigit(number string) (byte, bool) {
+	const (
+		base       = 10
+		maxDoubled = 9
+	)
+
+	total := 0
+	double := true
+
+	for index := len(number) - 1; index >= 0; index-- {
+		char := number[index]
+		if char < '0' || char > '9' {
+			return 0, false
+		}
+
+		digit := int(char - '0')
+		if double {
+			digit *= 2
+			if digit > maxDoubled {
+				digit -= maxDoubled
+			}
+		}
+
+		total += digit
+		double = !double
+	}
+
+	return byte('0' + (base-total%base)%base), true
+}
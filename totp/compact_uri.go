@@ -0,0 +1,173 @@
+package totp
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CompactURIScheme is the prefix of the compact URI of the key. See
+// Key.CompactURI().
+const CompactURIScheme = "otpc:"
+
+// Keys of the query parameters of the compact URI.
+const (
+	compactKeyAccount   = "n"
+	compactKeyAlgorithm = "a"
+	compactKeyCreated   = "c"
+	compactKeyDigits    = "d"
+	compactKeyIssuer    = "i"
+	compactKeyPeriod    = "p"
+	compactKeySecret    = "s"
+)
+
+// CompactURI returns the key in a compact form which is shorter than URI(). Such
+// as to share the key via constrained channels or to get a less dense QR code.
+//
+// WARNING: This is a non-standard format specific to this package. The
+// authenticator apps can NOT read it. Use GenKeyFromCompactURI() to restore
+// the key and URI() for the standard otpauth URI.
+//
+// The format is the "otpc:" prefix followed by the URL encoded query with the
+// short parameter keys. The secret is encoded in base62 and the parameters
+// equal to the defaults are omitted. e.g.:
+//
+//	otpc:i=Example.com&n=alice%40example.com&s=itzpUtVd3gQpHSq1nxITmFQbnJN
+//
+//	i: issuer
+//	n: account name
+//	s: secret in base62 (see Secret.Base62())
+//	a: algorithm ID (see Algorithm.ID()). Omitted if SHA1.
+//	d: digits. Omitted if 6.
+//	p: period in seconds. Omitted if 30.
+//	c: creation time in Unix seconds. Omitted if unknown.
+//
+// It holds the same values as URI(), so it round-trips losslessly.
+func (k *Key) CompactURI() string {
+	values := url.Values{}
+
+	values.Set(compactKeyIssuer, k.Options.Issuer)
+	values.Set(compactKeyAccount, k.Options.AccountName)
+	values.Set(compactKeySecret, k.PepperedSecret().Base62())
+
+	if k.Options.Algorithm != OptionAlgorithmDefault {
+		values.Set(compactKeyAlgorithm, strconv.Itoa(k.Options.Algorithm.ID()))
+	}
+
+	if k.Options.Digits != OptionDigitsDefault {
+		values.Set(compactKeyDigits, k.Options.Digits.String())
+	}
+
+	if k.Options.Period != OptionPeriodDefault {
+		values.Set(compactKeyPeriod, strconv.FormatUint(uint64(k.Options.Period), 10))
+	}
+
+	if !k.Options.Created.IsZero() {
+		values.Set(compactKeyCreated, strconv.FormatInt(k.Options.Created.Unix(), 10))
+	}
+
+	return CompactURIScheme + values.Encode()
+}
+
+// GenKeyFromCompactURI creates a new Key object from the compact URI generated
+// by Key.CompactURI().
+//
+// The opts are applied once before the values of the compact URI are set, and
+// the values are checked the same way as GenKeyFromURI(). Unknown or duplicate
+// parameters are rejected.
+func GenKeyFromCompactURI(compactURI string, opts ...Option) (*Key, error) {
+	query, ok := strings.CutPrefix(compactURI, CompactURIScheme)
+	if !ok {
+		return nil, errors.Errorf("failed to parse compact URI: it should start with %q", CompactURIScheme)
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse compact URI")
+	}
+
+	for name, value := range values {
+		switch name {
+		case compactKeyAccount, compactKeyAlgorithm, compactKeyCreated, compactKeyDigits,
+			compactKeyIssuer, compactKeyPeriod, compactKeySecret:
+			if len(value) > 1 {
+				return nil, errors.Errorf("failed to parse compact URI: duplicate parameter %q", name)
+			}
+		default:
+			return nil, errors.Errorf("failed to parse compact URI: unknown parameter %q", name)
+		}
+	}
+
+	secret, err := NewSecretBase62(values.Get(compactKeySecret))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse compact URI: invalid secret")
+	}
+
+	// Options applied the same way as parsing, so that the URI below is generated
+	// in the form that the parser expects. Such as the label separator.
+	tmpOpts, err := resolveOptions(values.Get(compactKeyIssuer), values.Get(compactKeyAccount), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse compact URI")
+	}
+
+	if algoID := values.Get(compactKeyAlgorithm); algoID != "" {
+		id, err := strconv.Atoi(algoID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse compact URI: invalid algorithm ID %q", algoID)
+		}
+
+		if tmpOpts.Algorithm, err = NewAlgorithmID(id); err != nil {
+			return nil, errors.Wrap(err, "failed to parse compact URI")
+		}
+	} else {
+		tmpOpts.Algorithm = OptionAlgorithmDefault
+	}
+
+	tmpOpts.Digits = OptionDigitsDefault
+	if digits := values.Get(compactKeyDigits); digits != "" {
+		tmpOpts.Digits = NewDigitsStr(digits)
+	}
+
+	tmpOpts.Period = OptionPeriodDefault
+	if period := values.Get(compactKeyPeriod); period != "" {
+		tmpOpts.Period = StrToUint(period)
+	}
+
+	tmpOpts.Created = time.Time{}
+	if created := values.Get(compactKeyCreated); created != "" {
+		unix, err := strconv.ParseInt(created, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse compact URI: invalid creation time %q", created)
+		}
+
+		tmpOpts.Created = time.Unix(unix, 0).UTC()
+	}
+
+	// The period is set after the options are applied
+	tmpOpts.resolveSkewDuration()
+
+	key := &Key{
+		Secret:  secret,
+		Options: *tmpOpts,
+	}
+
+	// The same checks as the standard URI. See URI.check().
+	if err := key.Options.Validate(); err != nil {
+		return nil, errors.Wrap(err, "failed to create key from compact URI")
+	}
+
+	if err := key.Secret.Validate(); err != nil {
+		return nil, errors.Wrap(err, "failed to create key from compact URI: invalid secret")
+	}
+
+	key.NormalizeSecretSize()
+
+	key.Options.log().Debugf("totp: key imported from compact URI: algorithm=%s issuer=%q account=%q fingerprint=%s",
+		key.Options.Algorithm, key.Options.Issuer, key.Options.AccountName, fingerprint(key.Secret))
+	logKeyWarnings(key)
+
+	return key, nil
+}
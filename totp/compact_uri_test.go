@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  Key.CompactURI() and GenKeyFromCompactURI()
+// ----------------------------------------------------------------------------
+
+func TestKey_CompactURI_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for index, opts := range [][]Option{
+		nil,
+		{WithSecretSize(20)},
+		{WithAlgorithm(Algorithm("SHA512")), WithDigits(DigitsEight), WithPeriod(60)},
+		{WithAlgorithm(Algorithm("SHA256")), WithIssuerInPath(false)},
+		{WithLabelSeparator("|")},
+	} {
+		key, err := GenerateKey("Example Co+1", "alice@example.com", opts...)
+		require.NoError(t, err, "test #%d: failed to create key during test setup", index+1)
+
+		compact := key.CompactURI()
+		require.Less(t, len(compact), len(key.URI()), "test #%d: compact URI should be shorter", index+1)
+
+		keyRestored, err := GenKeyFromCompactURI(compact, opts...)
+		require.NoError(t, err, "test #%d: failed to restore the key", index+1)
+
+		require.True(t, key.Secret.Equal(keyRestored.Secret), "test #%d: secret mismatch", index+1)
+		require.Empty(t, key.Options.Diff(keyRestored.Options), "test #%d: options mismatch", index+1)
+		require.Equal(t, key.URI(), keyRestored.URI(), "test #%d: URI mismatch", index+1)
+	}
+}
+
+func TestKey_CompactURI_format(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenKeyFromURI("otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+	require.NoError(t, err)
+
+	require.Equal(t, "otpc:i=Example.com&n=alice%40example.com&s=itzpUtVd3gQpHSq1nxITmFQbnJN", key.CompactURI())
+
+	key.Options.Algorithm = Algorithm("SHA256")
+	key.Options.Digits = DigitsEight
+	key.Options.Period = 60
+
+	require.Equal(t,
+		"otpc:a=1&d=8&i=Example.com&n=alice%40example.com&p=60&s=itzpUtVd3gQpHSq1nxITmFQbnJN",
+		key.CompactURI())
+}
+
+func TestGenKeyFromCompactURI_options_applied_once(t *testing.T) {
+	t.Parallel()
+
+	keyOrig, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	// Stateful option which counts the calls
+	calls := 0
+	countCalls := func(opts *Options) error {
+		calls++
+
+		return WithSkew(uint(calls))(opts)
+	}
+
+	key, err := GenKeyFromCompactURI(keyOrig.CompactURI(), countCalls, WithSecretPepper([]byte("pepper")))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls, "options should be applied only once")
+	require.Equal(t, uint(1), key.Options.Skew)
+	require.Equal(t, keyOrig.Secret, key.Secret, "secret should be the one in the compact URI")
+	require.Equal(t, []byte("pepper"), key.Options.pepper)
+}
+
+func TestGenKeyFromCompactURI_bad_input(t *testing.T) {
+	t.Parallel()
+
+	for index, test := range []struct {
+		input  string
+		expect string
+	}{
+		{"otpauth://totp/Example.com:alice@example.com", "it should start with"},
+		{"otpc:i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN&%zz", "failed to parse compact URI"},
+		{"otpc:i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN&x=1", `unknown parameter "x"`},
+		{"otpc:i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN&s=a", `duplicate parameter "s"`},
+		{"otpc:i=Example.com&n=alice", "invalid secret"},
+		{"otpc:i=Example.com&n=alice&s=-1", "invalid secret"},
+		{"otpc:n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN", "issuer and accountName are required"},
+		{"otpc:a=x&i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN", `invalid algorithm ID "x"`},
+		{"otpc:a=9&i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN", "invalid algorithm ID"},
+		{"otpc:c=x&i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN", `invalid creation time "x"`},
+		{"otpc:d=7&i=Example.com&n=alice&s=itzpUtVd3gQpHSq1nxITmFQbnJN", "failed to create key from compact URI"},
+		{"otpc:i=Example.com&n=alice&p=0&s=itzpUtVd3gQpHSq1nxITmFQbnJN", "failed to create key from compact URI"},
+		{"otpc:i=Example.com&n=alice&s=abc", "failed to create key from compact URI"},
+	} {
+		key, err := GenKeyFromCompactURI(test.input)
+
+		require.Error(t, err, "test #%d should fail", index+1)
+		require.Contains(t, err.Error(), test.expect, "test #%d: unexpected error message", index+1)
+		require.Nil(t, key)
+	}
+}
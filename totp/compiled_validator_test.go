@@ -0,0 +1,101 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  Key.Compile()
+// ----------------------------------------------------------------------------
+
+func TestKey_Compile(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(fixedTime))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	validator, err := key.Compile()
+	require.NoError(t, err)
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	require.True(t, validator.Validate(passcode), "should use the fixed time of the key")
+	require.True(t, validator.ValidateCustom(passcode, fixedTime.Add(30*time.Second)),
+		"should allow the skew of the key")
+	require.False(t, validator.ValidateCustom(passcode, fixedTime.Add(time.Hour)))
+
+	for _, invalid := range []string{"", "abcdef", passcode + "0"} {
+		require.False(t, validator.Validate(invalid), "input: %q", invalid)
+	}
+}
+
+func TestKey_Compile_unsupported_algorithm(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	key.Options.Algorithm = Algorithm("UNKNOWN")
+
+	validator, err := key.Compile()
+
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	require.Nil(t, validator)
+}
+
+// ----------------------------------------------------------------------------
+//  Benchmarks
+// ----------------------------------------------------------------------------
+
+func BenchmarkKey_Validate(b *testing.B) {
+	key, passcode := benchKeyAndPassCode(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if !key.Validate(passcode) {
+			b.Fatal("passcode should be valid")
+		}
+	}
+}
+
+func BenchmarkCompiledValidator_Validate(b *testing.B) {
+	key, passcode := benchKeyAndPassCode(b)
+
+	validator, err := key.Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if !validator.Validate(passcode) {
+			b.Fatal("passcode should be valid")
+		}
+	}
+}
+
+func benchKeyAndPassCode(b *testing.B) (*Key, string) {
+	b.Helper()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(time.Unix(1700000000, 0)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	passcode, err := key.PassCode()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return key, passcode
+}
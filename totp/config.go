@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: ConfigurableOptions
+// ----------------------------------------------------------------------------
+
+// ConfigurableOptions is the set of the options in plain types to be loaded from
+// the configuration, such as environment variables, JSON or YAML files via
+// "envconfig" or "viper". Use ToOptions() to convert it to the Options.
+//
+// The zero values mean the defaults. e.g. in JSON:
+//
+//	{"issuer": "Example.com", "algorithm": "sha256", "digits": "8"}
+type ConfigurableOptions struct {
+	// Issuer is the name of the issuer of the secret key.
+	Issuer string `env:"TOTP_ISSUER" json:"issuer" mapstructure:"issuer"`
+	// AccountName is the name of the secret key owner.
+	AccountName string `env:"TOTP_ACCOUNT_NAME" json:"account_name" mapstructure:"account_name"`
+	// Algorithm is the HMAC algorithm. MD5, SHA1, SHA256 or SHA512 in any case.
+	// (Default: SHA1)
+	Algorithm string `env:"TOTP_ALGORITHM" json:"algorithm" mapstructure:"algorithm"`
+	// Digits is the number of digits of the passcode. "6" or "8". (Default: 6)
+	Digits string `env:"TOTP_DIGITS" json:"digits" mapstructure:"digits"`
+	// Period is the period in seconds of the passcode. (Default: 30)
+	Period uint `env:"TOTP_PERIOD" json:"period" mapstructure:"period"`
+	// SecretSize is the size of the secret in bytes. (Default: 128, 16 for MD5)
+	SecretSize uint `env:"TOTP_SECRET_SIZE" json:"secret_size" mapstructure:"secret_size"`
+	// Skew is the number of periods of tolerance on both sides. (Default: 1)
+	Skew uint `env:"TOTP_SKEW" json:"skew" mapstructure:"skew"`
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// ToOptions converts the configuration to the Options. The algorithm and digits
+// are converted via NewAlgorithmStr() and NewDigitsInt(), and the undefined
+// values are set to the defaults. See Options.SetDefault().
+//
+// It returns an error with the name of the field if the value is invalid, which
+// wraps ErrUnsupportedAlgorithm or ErrUnsupportedDigits. The returned Options
+// are checked with Options.Validate().
+func (c ConfigurableOptions) ToOptions() (Options, error) {
+	//nolint:exhaustruct // the rest of the fields are set by SetDefault
+	options := Options{
+		Issuer:      c.Issuer,
+		AccountName: c.AccountName,
+		Period:      c.Period,
+		SecretSize:  c.SecretSize,
+		Skew:        c.Skew,
+	}
+
+	if algo := strings.TrimSpace(c.Algorithm); algo != "" {
+		algorithm, err := NewAlgorithmStr(algo)
+		if err != nil {
+			return Options{}, errors.Wrapf(ErrUnsupportedAlgorithm, "invalid config: field %q: %q", "algorithm", algo)
+		}
+
+		options.Algorithm = algorithm
+	}
+
+	if digits := strings.TrimSpace(c.Digits); digits != "" {
+		value, err := strconv.Atoi(digits)
+		if err != nil || value < 0 || !NewDigitsInt(value).IsSupported() {
+			return Options{}, errors.Wrapf(ErrUnsupportedDigits, "invalid config: field %q: %q", "digits", digits)
+		}
+
+		options.Digits = NewDigitsInt(value)
+	}
+
+	options.SetDefault()
+
+	if err := options.Validate(); err != nil {
+		return Options{}, errors.Wrap(err, "invalid config")
+	}
+
+	return options, nil
+}
@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  ConfigurableOptions.ToOptions()
+// ----------------------------------------------------------------------------
+
+func TestConfigurableOptions_ToOptions(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	config := ConfigurableOptions{}
+
+	err := json.Unmarshal([]byte(`{
+		"issuer": "Example.com",
+		"account_name": "alice@example.com",
+		"algorithm": "sha256",
+		"digits": "8",
+		"period": 60
+	}`), &config)
+	require.NoError(t, err)
+
+	options, err := config.ToOptions()
+	require.NoError(t, err)
+
+	require.Equal(t, "Example.com", options.Issuer)
+	require.Equal(t, "alice@example.com", options.AccountName)
+	require.Equal(t, Algorithm("SHA256"), options.Algorithm)
+	require.Equal(t, DigitsEight, options.Digits)
+	require.Equal(t, uint(60), options.Period)
+	require.Equal(t, OptionSecretSizeDefault, options.SecretSize, "undefined values should be default")
+	require.Equal(t, OptionSkewDefault, options.Skew, "undefined values should be default")
+
+	key, err := GenerateKeyCustom(options)
+	require.NoError(t, err)
+	require.Equal(t, Algorithm("SHA256"), key.Options.Algorithm)
+}
+
+func TestConfigurableOptions_ToOptions_default(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	options, err := ConfigurableOptions{}.ToOptions()
+	require.NoError(t, err)
+
+	require.Equal(t, OptionAlgorithmDefault, options.Algorithm)
+	require.Equal(t, OptionDigitsDefault, options.Digits)
+	require.Equal(t, OptionPeriodDefault, options.Period)
+}
+
+func TestConfigurableOptions_ToOptions_bad_values(t *testing.T) {
+	t.Parallel()
+
+	for index, test := range []struct {
+		config ConfigurableOptions
+		expect error
+		field  string
+	}{
+		//nolint:exhaustruct // allow missing fields
+		{ConfigurableOptions{Algorithm: "SHA3"}, ErrUnsupportedAlgorithm, `field "algorithm"`},
+		//nolint:exhaustruct // allow missing fields
+		{ConfigurableOptions{Digits: "7"}, ErrUnsupportedDigits, `field "digits"`},
+		//nolint:exhaustruct // allow missing fields
+		{ConfigurableOptions{Digits: "six"}, ErrUnsupportedDigits, `field "digits"`},
+		//nolint:exhaustruct // allow missing fields
+		{ConfigurableOptions{Digits: "-6"}, ErrUnsupportedDigits, `field "digits"`},
+	} {
+		options, err := test.config.ToOptions()
+
+		require.ErrorIs(t, err, test.expect, "test #%d", index+1)
+		require.ErrorContains(t, err, test.field, "test #%d: error should contain the field name", index+1)
+		require.Empty(t, options.Algorithm)
+	}
+}
@@ -45,8 +45,20 @@ func NewDigitsStr(digits string) Digits {
 //  Methods
 // ----------------------------------------------------------------------------
 
+// IsSupported returns true if the number of digits is supported. Currently
+// DigitsSix and DigitsEight are supported.
+func (d Digits) IsSupported() bool {
+	switch d {
+	case DigitsSix, DigitsEight:
+		return true
+	}
+
+	return false
+}
+
 // OTPDigits returns the value in otp.Digits type. Undefined Digits will always
-// return otp.DigitsSix.
+// return otp.DigitsSix. Use IsSupported() to check the value beforehand since
+// WithDigits, GenerateKeyCustom and Options.Validate reject them.
 func (d Digits) OTPDigits() otp.Digits {
 	switch d {
 	case DigitsSix:
@@ -0,0 +1,159 @@
+package totp
+
+import (
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sharedSecretFingerprintPrefix is the domain separator of the fingerprint of
+// the secret. See Key.SharedSecretFingerprint().
+const sharedSecretFingerprintPrefix = "go-totp shared secret fingerprint v1\x00"
+
+// ----------------------------------------------------------------------------
+//  Functions
+// ----------------------------------------------------------------------------
+
+// DeriveKeys derives multiple independent TOTP keys from a single ECDH shared
+// secret. One key is derived per context via the KDF set in the options (the
+// default is OptionKDFDefault).
+//
+// The shared secret is the output of the ECDH key agreement, such as:
+//
+//	shared, err := alicePriv.ECDH(bobPub)
+//
+// This allows two parties to have several independent TOTP streams (e.g. per
+// purpose) with a single key agreement. Contexts must be non-empty and unique
+// so that each derived secret is independent.
+//
+// The options are resolved the same way as GenerateKey, so the issuer and the
+// account name are required and the undefined options are set to their default
+// values. It returns an error if the options are invalid (see Options.Validate).
+// The returned keys are in the same order as the contexts.
+func DeriveKeys(shared []byte, contexts []string, opts Options) ([]*Key, error) {
+	if len(shared) == 0 {
+		return nil, errors.New("empty shared secret")
+	}
+
+	if len(contexts) == 0 {
+		return nil, errors.New("no context given. at least one context is required")
+	}
+
+	seen := make(map[string]struct{}, len(contexts))
+
+	for index, ctx := range contexts {
+		if ctx == "" {
+			return nil, errors.Errorf("empty context at index %d", index)
+		}
+
+		if _, ok := seen[ctx]; ok {
+			return nil, errors.Errorf("duplicate context at index %d: %q", index, ctx)
+		}
+
+		seen[ctx] = struct{}{}
+	}
+
+	resolved, err := resolveOptions(opts.Issuer, opts.AccountName, withOptions(opts))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve options")
+	}
+
+	if err := resolved.Validate(); err != nil {
+		return nil, errors.Wrap(err, "failed to derive keys")
+	}
+
+	opts = *resolved
+
+	kdf := opts.kdf
+	if kdf == nil {
+		kdf = OptionKDFDefault
+	}
+
+	keys := make([]*Key, 0, len(contexts))
+
+	for index, ctx := range contexts {
+		secret, err := kdf(shared, []byte(ctx), opts.SecretSize)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to derive key for context at index %d", index)
+		}
+
+		keyOpts := opts
+		keyOpts.ecdhCtx = ctx
+		keyOpts.ecdhPrivateKey = nil
+		keyOpts.ecdhPublicKey = nil
+
+		keys = append(keys, &Key{
+			Secret:  secret,
+			Options: keyOpts,
+		})
+	}
+
+	return keys, nil
+}
+
+// IsSupportedCurve returns true if the given curve is one of the curves in
+// SupportedECDHCurves().
+func IsSupportedCurve(curve ecdh.Curve) bool {
+	if curve == nil {
+		return false
+	}
+
+	for _, supported := range SupportedECDHCurves() {
+		if curve == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SupportedECDHCurves returns the ECDH curves supported by WithECDH. Which are
+// X25519, P-256, P-384 and P-521.
+//
+// Note that X448 is not supported since the standard crypto/ecdh package does
+// not implement it.
+func SupportedECDHCurves() []ecdh.Curve {
+	return []ecdh.Curve{
+		ecdh.X25519(),
+		ecdh.P256(),
+		ecdh.P384(),
+		ecdh.P521(),
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// SharedSecretFingerprint returns a short digest of the secret for the parties
+// of the ECDH key agreement, such as Alice and Bob, to confirm out-of-band that
+// they derived the same secret. If the fingerprints match, the derivation
+// succeeded on both sides.
+//
+// The fingerprint is the first 16 bytes of the domain-separated SHA-256 hash of
+// the secret, in uppercase hex grouped by 4 characters for reading aloud. e.g.:
+//
+//	1A2B 3C4D 5E6F 7A8B 9C0D 1E2F 3A4B 5C6D
+//
+// It is non-reversible and does not expose the secret. It is also different
+// from the fingerprints in the logs (see WithLogger()). Any key can have the
+// fingerprint, not only the ones generated via ECDH.
+func (k *Key) SharedSecretFingerprint() string {
+	const (
+		lenDigest = 16
+		lenGroup  = 4
+	)
+
+	hash := sha256.Sum256(append([]byte(sharedSecretFingerprintPrefix), k.Secret...))
+	digest := strings.ToUpper(hex.EncodeToString(hash[:lenDigest]))
+
+	groups := make([]string, 0, len(digest)/lenGroup)
+	for i := 0; i < len(digest); i += lenGroup {
+		groups = append(groups, digest[i:i+lenGroup])
+	}
+
+	return strings.Join(groups, " ")
+}
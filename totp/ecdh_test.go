@@ -0,0 +1,216 @@
+package totp
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  DeriveKeys()
+// ----------------------------------------------------------------------------
+
+func TestDeriveKeys_golden(t *testing.T) {
+	t.Parallel()
+
+	curve := ecdh.X25519()
+
+	privKeyA, err := curve.GenerateKey(rand.Reader)
+	require.NoError(t, err, "failed to generate ECDH private key for Alice during test")
+
+	privKeyB, err := curve.GenerateKey(rand.Reader)
+	require.NoError(t, err, "failed to generate ECDH private key for Bob during test")
+
+	sharedA, err := privKeyA.ECDH(privKeyB.PublicKey())
+	require.NoError(t, err)
+
+	sharedB, err := privKeyB.ECDH(privKeyA.PublicKey())
+	require.NoError(t, err)
+
+	contexts := []string{"example.com login v1", "example.com payment v1"}
+
+	//nolint:exhaustruct // allow missing fields
+	opts := Options{
+		Issuer:      "Example.com",
+		AccountName: "alice@example.com",
+		SecretSize:  32,
+	}
+
+	keysA, err := DeriveKeys(sharedA, contexts, opts)
+	require.NoError(t, err)
+	require.Len(t, keysA, len(contexts))
+
+	keysB, err := DeriveKeys(sharedB, contexts, opts)
+	require.NoError(t, err)
+
+	require.NotEqual(t, keysA[0].Secret, keysA[1].Secret,
+		"keys derived from different contexts must be independent")
+
+	for index := range contexts {
+		require.Equal(t, keysA[index].Secret, keysB[index].Secret,
+			"both parties should derive the same secret for the same context")
+		require.Len(t, keysA[index].Secret, 32)
+		require.Equal(t, OptionAlgorithmDefault, keysA[index].Options.Algorithm,
+			"undefined options should be set to default")
+
+		passcode, err := keysA[index].PassCode()
+		require.NoError(t, err)
+		require.True(t, keysB[index].Validate(passcode))
+	}
+}
+
+func TestDeriveKeys_bad_contexts(t *testing.T) {
+	t.Parallel()
+
+	shared := []byte("dummy shared secret")
+
+	for _, test := range []struct {
+		contexts []string
+		msgErr   string
+	}{
+		{nil, "no context given"},
+		{[]string{"context v1", ""}, "empty context at index 1"},
+		{[]string{"context v1", "context v2", "context v1"}, "duplicate context at index 2"},
+	} {
+		//nolint:exhaustruct // allow missing fields
+		keys, err := DeriveKeys(shared, test.contexts, Options{})
+
+		require.Error(t, err, "contexts: %v", test.contexts)
+		require.Contains(t, err.Error(), test.msgErr)
+		require.Nil(t, keys)
+	}
+}
+
+func TestDeriveKeys_bad_input(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	keys, err := DeriveKeys(nil, []string{"context"}, Options{})
+
+	require.Error(t, err, "empty shared secret should return error")
+	require.Contains(t, err.Error(), "empty shared secret")
+	require.Nil(t, keys)
+
+	//nolint:exhaustruct // allow missing fields
+	opts := Options{
+		Issuer:      "Example.com",
+		AccountName: "alice@example.com",
+		kdf: func(_, _ []byte, _ uint) ([]byte, error) {
+			return nil, errors.New("forced error")
+		},
+	}
+
+	keys, err = DeriveKeys([]byte("dummy shared secret"), []string{"context"}, opts)
+
+	require.Error(t, err, "KDF error should be returned")
+	require.Contains(t, err.Error(), "failed to derive key for context at index 0: forced error")
+	require.Nil(t, keys)
+}
+
+func TestDeriveKeys_bad_options(t *testing.T) {
+	t.Parallel()
+
+	shared := []byte("dummy shared secret")
+
+	for _, test := range []struct {
+		opts   Options
+		msgErr string
+	}{
+		//nolint:exhaustruct // allow missing fields
+		{Options{}, "issuer and accountName are required"},
+		//nolint:exhaustruct // allow missing fields
+		{Options{Issuer: "Example.com", AccountName: "alice", Digits: 7}, "unsupported digits"},
+		//nolint:exhaustruct // allow missing fields
+		{Options{Issuer: "Example.com", AccountName: "alice", Algorithm: "SHA3"}, "unsupported algorithm"},
+		//nolint:exhaustruct // allow missing fields
+		{Options{Issuer: "Example.com", AccountName: "alice", Period: 45, acceptedPeriods: []uint{30}}, "accepted periods"},
+	} {
+		keys, err := DeriveKeys(shared, []string{"context"}, test.opts)
+
+		require.ErrorContains(t, err, test.msgErr, "options: %#v", test.opts)
+		require.Nil(t, keys)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  SupportedECDHCurves() and IsSupportedCurve()
+// ----------------------------------------------------------------------------
+
+func TestSupportedECDHCurves(t *testing.T) {
+	t.Parallel()
+
+	curves := SupportedECDHCurves()
+	require.Len(t, curves, 4)
+
+	for _, curve := range curves {
+		require.True(t, IsSupportedCurve(curve), "curve: %v", curve)
+
+		privKeyA, err := curve.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		privKeyB, err := curve.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		key, err := GenerateKey("Example.com", "alice@example.com",
+			WithECDH(privKeyA, privKeyB.PublicKey(), "example.com alice bob TOTP v1"),
+		)
+		require.NoError(t, err, "curve: %v", curve)
+		require.NotNil(t, key)
+	}
+
+	require.False(t, IsSupportedCurve(nil), "nil curve should not be supported")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.SharedSecretFingerprint()
+// ----------------------------------------------------------------------------
+
+func TestKey_SharedSecretFingerprint(t *testing.T) {
+	t.Parallel()
+
+	curve := ecdh.X25519()
+
+	privKeyA, err := curve.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privKeyB, err := curve.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	genKey := func(priv *ecdh.PrivateKey, pub *ecdh.PublicKey, context string) *Key {
+		key, err := GenerateKey("Example.com", "alice@example.com", WithECDH(priv, pub, context))
+		require.NoError(t, err)
+
+		return key
+	}
+
+	keyA := genKey(privKeyA, privKeyB.PublicKey(), "example.com alice bob TOTP v1")
+	keyB := genKey(privKeyB, privKeyA.PublicKey(), "example.com alice bob TOTP v1")
+	keyOther := genKey(privKeyA, privKeyB.PublicKey(), "example.com alice bob TOTP v2")
+
+	fingerprint := keyA.SharedSecretFingerprint()
+
+	require.Equal(t, fingerprint, keyB.SharedSecretFingerprint(),
+		"both parties should get the same fingerprint")
+	require.NotEqual(t, fingerprint, keyOther.SharedSecretFingerprint(),
+		"different secrets should have different fingerprints")
+	require.Regexp(t, regexp.MustCompile(`^[0-9A-F]{4}( [0-9A-F]{4}){7}$`), fingerprint)
+
+	compact := strings.ReplaceAll(fingerprint, " ", "")
+	require.NotContains(t, strings.ToUpper(keyA.Secret.Hex()), compact,
+		"fingerprint should not expose the secret")
+	require.NotContains(t, keyA.Secret.Base32(), compact)
+}
+
+func TestKey_SharedSecretFingerprint_golden(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	key := &Key{Secret: Secret("12345678901234567890")}
+
+	require.Equal(t, "9CA8 F481 4C2C DADD 4DE0 F9A3 18FB 13FE", key.SharedSecretFingerprint())
+}
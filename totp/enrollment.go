@@ -0,0 +1,131 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// enrollmentToken is the payload of the enrollment token.
+type enrollmentToken struct {
+	URI     string `json:"uri"`
+	Expires int64  `json:"exp"` // Unix time in seconds
+}
+
+// EnrollmentToken returns a short-lived token that embeds the URI of the key,
+// signed with the given server-side secret key. Use ParseEnrollmentToken() to
+// verify and import it. Such as for an enrollment link sent by email that can
+// not be used after the expiry.
+//
+// The token is in the form of:
+//
+//	base64url(payload) "." base64url(HMAC-SHA256(secretKey, base64url(payload)))
+//
+// Where the payload is a JSON object of the URI and the expiry time in Unix
+// seconds. The token is signed but NOT encrypted. The TOTP secret is readable
+// by anyone who has the token, so deliver it through a trusted channel.
+//
+// The expiry is computed from the time of the key options (see WithNow()). The
+// TTL must be at least a second since the expiry is in seconds.
+func (k *Key) EnrollmentToken(secretKey []byte, ttl time.Duration) (string, error) {
+	if len(secretKey) == 0 {
+		return "", errors.New("empty secret key to sign the enrollment token")
+	}
+
+	if ttl < time.Second {
+		return "", errors.Errorf("invalid TTL of the enrollment token: %v. it should be a second or longer", ttl)
+	}
+
+	payload, err := json.Marshal(enrollmentToken{
+		URI:     k.URI(),
+		Expires: k.Options.timeNow().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal enrollment token")
+	}
+
+	encPayload := base64.RawURLEncoding.EncodeToString(payload)
+	signature := signEnrollmentToken(encPayload, secretKey)
+
+	return encPayload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParseEnrollmentToken verifies the token created by Key.EnrollmentToken() with
+// the given secret key and returns the Key object of the embedded URI.
+//
+// The opts are passed to GenKeyFromURI(). The expiry is checked against the
+// time of the options, such as WithNow(), the same clock as the key that issued
+// the token.
+//
+// It returns an error wrapping ErrTokenTampered if the token is malformed or the
+// signature does not match, and ErrTokenExpired if the token is expired.
+func ParseEnrollmentToken(token string, secretKey []byte, opts ...Option) (*Key, error) {
+	if len(secretKey) == 0 {
+		return nil, errors.New("empty secret key to verify the enrollment token")
+	}
+
+	parseOpts, err := tokenOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse enrollment token")
+	}
+
+	encPayload, encSignature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, errors.Wrap(ErrTokenTampered, "malformed enrollment token")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encSignature)
+	if err != nil || !hmac.Equal(signature, signEnrollmentToken(encPayload, secretKey)) {
+		return nil, errors.Wrap(ErrTokenTampered, "signature mismatch of enrollment token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, errors.Wrap(ErrTokenTampered, "malformed payload of enrollment token")
+	}
+
+	var decoded enrollmentToken
+
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, errors.Wrap(ErrTokenTampered, "malformed payload of enrollment token")
+	}
+
+	if expires := time.Unix(decoded.Expires, 0); !parseOpts.timeNow().Before(expires) {
+		return nil, errors.Wrapf(ErrTokenExpired, "enrollment token expired at %s", expires.UTC().Format(time.RFC3339))
+	}
+
+	key, err := GenKeyFromURI(decoded.URI, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to import the URI of enrollment token")
+	}
+
+	return key, nil
+}
+
+// tokenOptions applies the opts to verify the tokens, such as WithNow() for the
+// clock to check the expiry.
+func tokenOptions(opts ...Option) (*Options, error) {
+	//nolint:exhaustruct // only the clock is used
+	parseOpts := &Options{}
+
+	for _, fn := range opts {
+		if err := fn(parseOpts); err != nil {
+			return nil, errors.Wrap(err, "failed to apply custom options")
+		}
+	}
+
+	return parseOpts, nil
+}
+
+// signEnrollmentToken returns the HMAC-SHA256 signature of the encoded payload.
+func signEnrollmentToken(encPayload string, secretKey []byte) []byte {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(encPayload))
+
+	return mac.Sum(nil)
+}
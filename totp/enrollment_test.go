@@ -0,0 +1,94 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  Key.EnrollmentToken() and ParseEnrollmentToken()
+// ----------------------------------------------------------------------------
+
+func TestKey_EnrollmentToken(t *testing.T) {
+	t.Parallel()
+
+	serverKey := []byte("server side secret key")
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	token, err := key.EnrollmentToken(serverKey, time.Hour)
+	require.NoError(t, err)
+
+	keyParsed, err := ParseEnrollmentToken(token, serverKey)
+	require.NoError(t, err)
+	require.Equal(t, key.URI(), keyParsed.URI())
+
+	// Wrong server key
+	_, err = ParseEnrollmentToken(token, []byte("other secret key"))
+	require.ErrorIs(t, err, ErrTokenTampered)
+
+	// Tampered payload. The JSON payload always starts with "eyJ" in base64.
+	payload, signature, _ := strings.Cut(token, ".")
+	require.True(t, strings.HasPrefix(payload, "eyJ"))
+
+	tampered := "f" + payload[1:] + "." + signature
+
+	_, err = ParseEnrollmentToken(tampered, serverKey)
+	require.ErrorIs(t, err, ErrTokenTampered)
+
+	// Malformed
+	for _, malformed := range []string{"", "no-dot", payload + ".!!!"} {
+		_, err = ParseEnrollmentToken(malformed, serverKey)
+		require.ErrorIs(t, err, ErrTokenTampered, "token: %q", malformed)
+	}
+}
+
+func TestKey_EnrollmentToken_expired(t *testing.T) {
+	t.Parallel()
+
+	serverKey := []byte("server side secret key")
+
+	issuedAt := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(issuedAt))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	token, err := key.EnrollmentToken(serverKey, time.Hour)
+	require.NoError(t, err)
+
+	// The same clock as the issuer is used
+	keyParsed, err := ParseEnrollmentToken(token, serverKey, WithNow(issuedAt.Add(time.Hour-time.Second)))
+	require.NoError(t, err, "token should be valid just before the expiry")
+	require.Equal(t, key.URI(), keyParsed.URI())
+
+	keyParsed, err = ParseEnrollmentToken(token, serverKey, WithNow(issuedAt.Add(time.Hour)))
+
+	require.ErrorIs(t, err, ErrTokenExpired)
+	require.NotErrorIs(t, err, ErrTokenTampered, "expired and tampered tokens should be distinct")
+	require.Nil(t, keyParsed)
+}
+
+func TestKey_EnrollmentToken_bad_args(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	_, err = key.EnrollmentToken(nil, time.Hour)
+	require.ErrorContains(t, err, "empty secret key")
+
+	for _, ttl := range []time.Duration{0, 500 * time.Millisecond} {
+		_, err = key.EnrollmentToken([]byte("server key"), ttl)
+		require.ErrorContains(t, err, "invalid TTL", "ttl: %s", ttl)
+	}
+
+	_, err = ParseEnrollmentToken("foo.bar", []byte("server key"), WithMinEntropy(0))
+	require.ErrorContains(t, err, "failed to apply custom options")
+
+	_, err = ParseEnrollmentToken("foo.bar", nil)
+	require.ErrorContains(t, err, "empty secret key")
+}
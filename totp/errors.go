@@ -0,0 +1,39 @@
+package totp
+
+import "github.com/pkg/errors"
+
+// Sentinel errors of the package. The errors returned by the functions and
+// methods may wrap them, so use errors.Is() to check them.
+var (
+	// ErrUnsupportedAlgorithm is returned if the algorithm is not supported.
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	// ErrUnsupportedDigits is returned if the number of digits is not supported.
+	ErrUnsupportedDigits = errors.New("unsupported digits")
+	// ErrZeroPeriod is returned if the period is zero.
+	ErrZeroPeriod = errors.New("zero period")
+	// ErrUnsupportedPeriod is returned if the period is out of the range of
+	// StrictPeriodMin and StrictPeriodMax (see GenKeyFromURIStrict()) or not
+	// in the accepted periods (see WithAcceptedPeriods()).
+	ErrUnsupportedPeriod = errors.New("unsupported period")
+	// ErrSecretTooShort is returned if the secret is shorter than SecretSizeMin.
+	ErrSecretTooShort = errors.New("secret is too short")
+	// ErrEmptyPasscode is returned if the passcode to validate is empty.
+	ErrEmptyPasscode = errors.New("empty passcode")
+	// ErrMalformedPasscode is returned if the passcode to validate is not a
+	// number of the expected digits.
+	ErrMalformedPasscode = errors.New("malformed passcode")
+	// ErrLowEntropy is returned if the generated secret does not meet the
+	// minimum entropy set by WithMinEntropy().
+	ErrLowEntropy = errors.New("low entropy secret")
+	// ErrRangeTooLarge is returned if the time range to validate spans more
+	// windows than MaxRangeWindows. See Key.ValidateInRangeE().
+	ErrRangeTooLarge = errors.New("time range too large")
+	// ErrTokenExpired is returned if the enrollment token is expired.
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenTampered is returned if the enrollment token is malformed or its
+	// signature does not match.
+	ErrTokenTampered = errors.New("token tampered")
+	// ErrHOTPKey is returned if a passcode is generated or validated with an
+	// HOTP key, which is for the import and export only. See GenHOTPKeyFromURI().
+	ErrHOTPKey = errors.New("passcodes of HOTP keys are not supported")
+)
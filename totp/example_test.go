@@ -193,6 +193,21 @@ func ExampleDigits() {
 	// Digit 6 OK
 }
 
+func ExampleDigits_IsSupported() {
+	for _, digits := range []totp.Digits{
+		totp.DigitsSix,
+		totp.DigitsEight,
+		totp.Digits(12),
+	} {
+		fmt.Printf("%d digits supported: %v\n", digits, digits.IsSupported())
+	}
+	//
+	// Output:
+	// 6 digits supported: true
+	// 8 digits supported: true
+	// 12 digits supported: false
+}
+
 // ============================================================================
 //  Func: GenKeyFromPEM (fka GenerateKeyPEM)
 // ============================================================================
@@ -242,7 +257,7 @@ gX7ff3VlT4sCakCjQH69ZQxTbzs=
 
 func ExampleGenKeyFromURI() {
 	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-		"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+		"digits=8&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
 
 	key, err := totp.GenKeyFromURI(origin)
 	if err != nil {
@@ -261,12 +276,41 @@ func ExampleGenKeyFromURI() {
 	// Issuer: Example.com
 	// AccountName: alice@example.com
 	// Algorithm: SHA1
-	// Digits: 12
+	// Digits: 8
 	// Period: 60
 	// Secret Size: 20
 	// Secret: QF7N673VMVHYWATKICRUA7V5MUGFG3Z3
 }
 
+// ============================================================================
+//  Func: GenerateURI
+// ============================================================================
+
+func ExampleGenerateURI() {
+	// Generate a new secret key and get it as a TOTP URI.
+	uriRaw, err := totp.GenerateURI("Example.com", "alice@example.com",
+		totp.WithDigits(totp.DigitsEight),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	uri := totp.NewURI(uriRaw)
+
+	if err := uri.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Issuer:", uri.Issuer())
+	fmt.Println("Account Name:", uri.AccountName())
+	fmt.Println("Digits:", uri.Digits())
+	//
+	// Output:
+	// Issuer: Example.com
+	// Account Name: alice@example.com
+	// Digits: 8
+}
+
 // ============================================================================
 //  Type: Key
 // ============================================================================
@@ -315,7 +359,10 @@ func ExampleKey_regenerate1() {
 	Issuer := "Example.com"
 	AccountName := "alice@example.com"
 
-	key, err := totp.GenerateKey(Issuer, AccountName)
+	// The creation time is fixed for the reproducible output of this example
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	key, err := totp.GenerateKey(Issuer, AccountName, totp.WithNow(created))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -326,12 +373,12 @@ func ExampleKey_regenerate1() {
 		log.Fatal(err)
 	}
 
-	// Step3: Ensure the secret key size is the same as the new key object
-	key.Options.SecretSize = uint(len(newSecret.Bytes()))
-
-	// Step4: Overwrite the secret key value with the backed-up value
+	// Step3: Overwrite the secret key value with the backed-up value
 	key.Secret = newSecret
 
+	// Step4: Ensure the secret key size is the same as the backed-up secret
+	key.NormalizeSecretSize()
+
 	// Step5: Backup the TOTP key object in PEM format this time
 	keyPEM, err := key.PEM()
 	if err != nil {
@@ -344,6 +391,7 @@ func ExampleKey_regenerate1() {
 	// -----BEGIN TOTP SECRET KEY-----
 	// Account Name: alice@example.com
 	// Algorithm: SHA1
+	// Created: 2024-01-02T03:04:05Z
 	// Digits: 6
 	// Issuer: Example.com
 	// Period: 30
@@ -368,21 +416,21 @@ func ExampleKey_regenerate2() {
 		log.Fatal(err)
 	}
 
-	// Step2: Generate a new totp.Options object with default values but with the
-	// secret key size set to the same as the backed-up secret key value.
+	// Step2: Generate a new totp.Options object with default values.
 	options, err := totp.NewOptions("Example.com", "alice@example.com")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	options.SecretSize = uint(len(secret.Bytes()))
-
-	// Step3: Generate a new totp.Key object.
+	// Step3: Generate a new totp.Key object with the secret key size set to the
+	// same as the backed-up secret key value.
 	key := totp.Key{
 		Secret:  secret,
 		Options: *options,
 	}
 
+	key.NormalizeSecretSize()
+
 	// Step4: Backup the TOTP key object in PEM format this time
 	keyPEM, err := key.PEM()
 	if err != nil {
@@ -609,7 +657,7 @@ gX7ff3VlT4sCakCjQH69ZQxTbzs=
 
 func ExampleKey_URI() {
 	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-		"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+		"digits=8&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
 
 	key, err := totp.GenKeyFromURI(origin)
 	if err != nil {
@@ -860,7 +908,7 @@ func ExampleStrToUint() {
 
 func ExampleURI() {
 	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-		"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+		"digits=8&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
 
 	uri := totp.URI(origin)
 
@@ -891,7 +939,7 @@ func ExampleURI() {
 	// Algorithm: SHA1
 	// Secret: QF7N673VMVHYWATKICRUA7V5MUGFG3Z3
 	// Period: 60
-	// Digits: 12
+	// Digits: 8
 }
 
 func ExampleURI_IssuerFromPath() {
@@ -912,7 +960,7 @@ func ExampleURI_IssuerFromPath() {
 func ExampleValidate() {
 	// Create a new Key object via URI to obtain the current passcode.
 	uri := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-		"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+		"digits=8&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
 
 	key, err := totp.GenKeyFromURI(uri)
 	if err != nil {
@@ -0,0 +1,47 @@
+package totp
+
+import (
+	"github.com/pkg/errors"
+)
+
+// GenHOTPKeyFromURI creates a new Key object from an HOTP uri/url, such as
+// "otpauth://hotp/Example.com:alice@example.com?secret=...&counter=1". It is
+// similar to GenKeyFromURI() but the "counter" parameter is required instead of
+// the "period".
+//
+// The HOTP keys are for the import and export only, such as migrating the keys
+// between the apps. Key.URI() and MarshalBinary() keep the counter so that they
+// round-trip, but PEM does not.
+//
+// The passcodes are NOT supported. The methods to generate or validate the
+// passcodes, such as PassCode(), Validate() and Verify(), return an error
+// wrapping ErrHOTPKey or false for the HOTP keys, rather than the TOTP ones
+// ignoring the counter.
+func GenHOTPKeyFromURI(uri string, opts ...Option) (*Key, error) {
+	counter, ok := URI(uri).Counter()
+	if !ok {
+		return nil, errors.New("failed to create key from URI: missing or malformed counter")
+	}
+
+	return genKeyFromURI(uri, &counter, opts...)
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// Counter returns the counter of the HOTP key and true. It returns false if the
+// key is TOTP.
+func (k *Key) Counter() (uint64, bool) {
+	if k.Options.counter == nil {
+		return 0, false
+	}
+
+	return *k.Options.counter, true
+}
+
+// Type returns the type of the OTP of the key. OTPTypeHOTP if the key is created
+// by GenHOTPKeyFromURI(), otherwise OTPTypeTOTP.
+func (k *Key) Type() OTPType {
+	return k.Options.otpType()
+}
@@ -0,0 +1,145 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  GenHOTPKeyFromURI()
+// ----------------------------------------------------------------------------
+
+func TestGenHOTPKeyFromURI_round_trip(t *testing.T) {
+	t.Parallel()
+
+	const uri = "otpauth://hotp/Example.com:alice@example.com?algorithm=SHA256&counter=42&" +
+		"digits=8&issuer=Example.com&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	key, err := GenHOTPKeyFromURI(uri)
+	require.NoError(t, err)
+	require.Equal(t, OTPTypeHOTP, key.Type())
+
+	counter, ok := key.Counter()
+	require.True(t, ok)
+	require.Equal(t, uint64(42), counter)
+
+	// The URI should have the counter instead of the period
+	objURI := URI(key.URI())
+	require.Equal(t, "hotp", objURI.Host())
+	require.NotContains(t, key.URI(), "period=")
+	require.Equal(t, uri, key.URI())
+
+	counter, ok = objURI.Counter()
+	require.True(t, ok)
+	require.Equal(t, uint64(42), counter)
+
+	// Round trip
+	keyRecovered, err := GenHOTPKeyFromURI(key.URI())
+	require.NoError(t, err)
+
+	counter, ok = keyRecovered.Counter()
+	require.True(t, ok)
+	require.Equal(t, uint64(42), counter)
+	require.Equal(t, key.Secret, keyRecovered.Secret)
+	require.Equal(t, key.Options.Algorithm, keyRecovered.Options.Algorithm)
+	require.Equal(t, key.Options.Digits, keyRecovered.Options.Digits)
+	require.Equal(t, key.URI(), keyRecovered.URI())
+}
+
+func TestGenHOTPKeyFromURI_bad_uri(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		uri    string
+		errMsg string
+	}{
+		{
+			uri:    "otpauth://hotp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			errMsg: "missing or malformed counter",
+		},
+		{
+			uri:    "otpauth://hotp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=-1",
+			errMsg: "missing or malformed counter",
+		},
+		{
+			uri:    "otpauth://totp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=1",
+			errMsg: "it always should be `hotp`",
+		},
+	} {
+		key, err := GenHOTPKeyFromURI(test.uri)
+
+		require.ErrorContains(t, err, test.errMsg, "URI: %s", test.uri)
+		require.Nil(t, key)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Key.Counter() and Key.Type()
+// ----------------------------------------------------------------------------
+
+func TestKey_Counter_totp(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	counter, ok := key.Counter()
+	require.False(t, ok, "TOTP keys should not have the counter")
+	require.Zero(t, counter)
+	require.Equal(t, OTPTypeTOTP, key.Type())
+	require.Contains(t, key.URI(), "period=")
+	require.NotContains(t, key.URI(), "counter=")
+
+	// TOTP import should reject the HOTP URI
+	_, err = GenKeyFromURI("otpauth://hotp/Example.com:alice@example.com?" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=1")
+	require.ErrorContains(t, err, "it always should be `totp`")
+}
+
+func TestGenHOTPKeyFromURI_passcodes_not_supported(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenHOTPKeyFromURI("otpauth://hotp/Example.com:alice@example.com?" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=1")
+	require.NoError(t, err)
+
+	// Generation
+	_, err = key.PassCode()
+	require.ErrorIs(t, err, ErrHOTPKey)
+
+	_, err = key.PassCodeValue()
+	require.ErrorIs(t, err, ErrHOTPKey)
+
+	_, err = key.PassCodeChallenge("nonce")
+	require.ErrorIs(t, err, ErrHOTPKey)
+
+	_, err = key.Compile()
+	require.ErrorIs(t, err, ErrHOTPKey)
+
+	// Validation. The passcode of the TOTP key with the same secret must not
+	// validate silently.
+	keyTOTP := *key
+	keyTOTP.Options.counter = nil
+
+	passcode, err := keyTOTP.PassCode()
+	require.NoError(t, err)
+	require.True(t, keyTOTP.Validate(passcode))
+
+	require.False(t, key.Validate(passcode))
+	require.ErrorIs(t, key.Verify(passcode, time.Now()).Err, ErrHOTPKey)
+
+	_, err = key.ValidateE(passcode)
+	require.ErrorIs(t, err, ErrHOTPKey)
+
+	_, err = key.ValidateInRangeE(passcode, time.Now().Add(-time.Minute), time.Now())
+	require.ErrorIs(t, err, ErrHOTPKey)
+
+	isValid, _ := key.ValidateResync(passcode)
+	require.False(t, isValid)
+	require.False(t, Validate(passcode, key.Secret.Base32(), key.Options))
+	require.False(t, key.ValidateChallenge(passcode, "nonce"))
+	require.Nil(t, key.ValidateVerbose(passcode))
+	require.Nil(t, key.ValidWindows(passcode, time.Now().Add(-time.Minute), time.Now()))
+}
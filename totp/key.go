@@ -1,18 +1,31 @@
 package totp
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
 	"encoding/pem"
+	"fmt"
+	"math"
 	"net/url"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 )
 
 // BlockTypeTOTP is the type of a PEM encoded data block.
 const BlockTypeTOTP = "TOTP SECRET KEY"
 
+// redacted is the placeholder of the secret in the redacted outputs.
+const redacted = "REDACTED"
+
 // ----------------------------------------------------------------------------
 //  Type: Key
 // ----------------------------------------------------------------------------
@@ -23,6 +36,64 @@ type Key struct {
 	Options Options // Options to be stored.
 }
 
+// ----------------------------------------------------------------------------
+//  Type: BatchItem
+// ----------------------------------------------------------------------------
+
+// BatchItem is a pair of a passcode and the time to validate it at. See
+// Key.ValidateBatch().
+type BatchItem struct {
+	Code string    // Passcode to validate.
+	At   time.Time // Time to validate the passcode at.
+}
+
+// ----------------------------------------------------------------------------
+//  Type: TimeWindow
+// ----------------------------------------------------------------------------
+
+// TimeWindow is a time range from Start (inclusive) to End (exclusive). See
+// Key.ValidWindows().
+type TimeWindow struct {
+	Start time.Time // Start time of the range in UTC (inclusive).
+	End   time.Time // End time of the range in UTC (exclusive).
+}
+
+// ----------------------------------------------------------------------------
+//  Type: TimedPassCode
+// ----------------------------------------------------------------------------
+
+// TimedPassCode is a pair of a passcode and the start time of the window
+// (period) it belongs to. See Key.PassCodeSeries().
+type TimedPassCode struct {
+	Time time.Time // Start time of the window in UTC.
+	Code string    // Passcode of the window.
+}
+
+// ----------------------------------------------------------------------------
+//  Type: ValidationResult
+// ----------------------------------------------------------------------------
+
+// ValidationResult is the result of a passcode validation with the metadata.
+// See Key.Verify().
+type ValidationResult struct {
+	MatchedAt time.Time // Start time of the matched window in UTC. Zero if not valid.
+	Err       error     // Error if the validation could not be performed.
+	Offset    int       // Offset of the matched window in periods from the validation time.
+	Valid     bool      // True if the passcode is valid.
+}
+
+// ----------------------------------------------------------------------------
+//  Type: WindowResult
+// ----------------------------------------------------------------------------
+
+// WindowResult is the evaluation result of a passcode against a window (period).
+// See Key.ValidateVerbose().
+type WindowResult struct {
+	Window time.Time // Start time of the window in UTC.
+	Offset int       // Offset of the window in periods from the validation time.
+	Match  bool      // True if the passcode matched the window.
+}
+
 // ----------------------------------------------------------------------------
 //  Constructor
 // ----------------------------------------------------------------------------
@@ -35,17 +106,9 @@ type Key struct {
 // To customize the options, use the With* functions from the options.go file.
 // For advanced customization, use GenerateKeyCustom() instead.
 func GenerateKey(issuer string, accountName string, opts ...Option) (*Key, error) {
-	// Create options with default values.
-	optsCustom, err := NewOptions(issuer, accountName)
+	optsCustom, err := resolveOptions(issuer, accountName, opts...)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create options during key generation")
-	}
-
-	// Apply custom options.
-	for _, fn := range opts {
-		if err := fn(optsCustom); err != nil {
-			return nil, errors.Wrap(err, "failed to apply custom options")
-		}
+		return nil, err
 	}
 
 	return GenerateKeyCustom(*optsCustom)
@@ -58,6 +121,10 @@ var totpGenerate = totp.Generate
 //
 // Usually, `GenerateKey` with options is enough for most cases. But if you need
 // more control over the options, use this function.
+//
+// Digits other than DigitsSix or DigitsEight are rejected with an error wrapping
+// ErrUnsupportedDigits. Zero digits are treated as unset and fall back to
+// DigitsSix.
 func GenerateKeyCustom(options Options) (*Key, error) {
 	internalSec := []byte{} // random by default (if len = 0)
 
@@ -78,6 +145,20 @@ func GenerateKeyCustom(options Options) (*Key, error) {
 		}
 	}
 
+	return generateKey(options, internalSec)
+}
+
+// generateKey is the implementation of GenerateKeyCustom() with the secret to
+// use. If the secret is empty, a random secret of SecretSize is generated.
+func generateKey(options Options, internalSec []byte) (*Key, error) {
+	if options.Digits != 0 && !options.Digits.IsSupported() {
+		return nil, errors.Wrapf(ErrUnsupportedDigits, "failed to generate key: %d. it should be 6 or 8", options.Digits)
+	}
+
+	if options.Created.IsZero() {
+		options.Created = options.timeNow().UTC().Truncate(time.Second)
+	}
+
 	tmpOpt := totp.GenerateOpts{
 		Issuer:      options.Issuer,
 		AccountName: options.AccountName,
@@ -89,14 +170,38 @@ func GenerateKeyCustom(options Options) (*Key, error) {
 		Rand:        nil,
 	}
 
-	keyOrig, err := totpGenerate(tmpOpt)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate key")
+	// Regenerate the random secret if it does not meet the minimum entropy. The
+	// secret derived from ECDH is deterministic, so it is checked only once.
+	attempts := 1
+	if options.minEntropy > 0 && len(internalSec) == 0 {
+		attempts = minEntropyAttempts
 	}
 
-	secret, err := NewSecretBase32(keyOrig.Secret())
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create secret")
+	var secret Secret
+
+	for range attempts {
+		keyOrig, err := totpGenerate(tmpOpt)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate key")
+		}
+
+		secret, err = NewSecretBase32(keyOrig.Secret())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create secret")
+		}
+
+		if secret.Entropy() >= options.minEntropy {
+			break
+		}
+
+		options.log().Warnf("totp: low entropy secret generated: %.2f bits/byte. it should be at least %.2f",
+			secret.Entropy(), options.minEntropy)
+	}
+
+	if entropy := secret.Entropy(); entropy < options.minEntropy {
+		return nil, errors.Wrapf(ErrLowEntropy,
+			"failed to generate key: %.2f bits/byte after %d attempt(s). it should be at least %.2f",
+			entropy, attempts, options.minEntropy)
 	}
 
 	key := &Key{
@@ -104,6 +209,10 @@ func GenerateKeyCustom(options Options) (*Key, error) {
 		Options: options,
 	}
 
+	options.log().Debugf("totp: key generated: algorithm=%s issuer=%q account=%q fingerprint=%s",
+		options.Algorithm, options.Issuer, options.AccountName, fingerprint(secret))
+	logKeyWarnings(key)
+
 	return key, nil
 }
 
@@ -116,29 +225,54 @@ func GenerateKeyPEM(pemKey string) (*Key, error) {
 	return GenKeyFromPEM(pemKey)
 }
 
+// CheckPEM checks the integrity of the TOTP key in the PEM formatted string
+// without building the Key object. Such as for config validation at startup.
+//
+// It decodes the first TOTP block, as GenKeyFromPEM() does, and checks that:
+//
+//   - the required headers (Account Name, Algorithm, Digits, Issuer, Period,
+//     Secret Size and Skew) are present and parseable.
+//   - the algorithm and digits are supported and the period is not zero.
+//   - the optional "Created" header is in RFC3339 format.
+//   - the secret meets the minimum length of SecretSizeMin.
+//
+// The returned error lists all the problems found.
+func CheckPEM(pemData string) error {
+	block, rest := pem.Decode([]byte(pemData))
+
+	for block != nil && block.Type != BlockTypeTOTP {
+		block, rest = pem.Decode(rest)
+	}
+
+	if block == nil {
+		return errors.New("invalid PEM: no TOTP secret key block found")
+	}
+
+	problems := checkPEMHeaders(block.Headers)
+
+	if err := Secret(block.Bytes).Validate(); err != nil {
+		problems = append(problems, "secret: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("invalid PEM: %d problem(s) found: %s", len(problems), strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
 // GenKeyFromPEM creates a new Key object from a PEM formatted string.
+//
+// The creation time is taken from the "Created" header in RFC3339 format. It is
+// left zero if the header is missing or malformed.
+//
+// It returns an error wrapping ErrSecretTooShort if the secret is shorter than
+// SecretSizeMin bytes. See Secret.Validate() for details.
 func GenKeyFromPEM(pemKey string) (*Key, error) {
 	block, rest := pem.Decode([]byte(pemKey))
 
 	if block != nil && block.Type == BlockTypeTOTP {
-		key := &Key{
-			Secret: block.Bytes,
-			Options: Options{
-				AccountName:    block.Headers["Account Name"],
-				Algorithm:      Algorithm(block.Headers["Algorithm"]),
-				Digits:         NewDigitsStr(block.Headers["Digits"]),
-				ecdhCtx:        "",
-				ecdhPublicKey:  nil,
-				ecdhPrivateKey: nil,
-				Issuer:         block.Headers["Issuer"],
-				kdf:            nil,
-				Period:         StrToUint(block.Headers["Period"]),
-				SecretSize:     StrToUint(block.Headers["Secret Size"]),
-				Skew:           StrToUint(block.Headers["Skew"]),
-			},
-		}
-
-		return key, nil
+		return keyFromPEMBlock(block)
 	}
 
 	if block != nil && len(rest) > 0 {
@@ -148,6 +282,55 @@ func GenKeyFromPEM(pemKey string) (*Key, error) {
 	return nil, errors.New("failed to decode PEM block containing TOTP secret key")
 }
 
+// keyFromPEMBlock creates a new Key object from the decoded PEM block of the
+// BlockTypeTOTP type. See GenKeyFromPEM().
+func keyFromPEMBlock(block *pem.Block) (*Key, error) {
+	created, _ := time.Parse(time.RFC3339, block.Headers["Created"])
+
+	key := &Key{
+		Secret: block.Bytes,
+		Options: Options{
+			acceptedPeriods:    nil,
+			AccountName:        block.Headers["Account Name"],
+			Algorithm:          Algorithm(block.Headers["Algorithm"]),
+			checksum:           false,
+			counter:            nil,
+			Created:            created.UTC(),
+			Digits:             NewDigitsStr(block.Headers["Digits"]),
+			ecdhCtx:            "",
+			ecdhPublicKey:      nil,
+			ecdhPrivateKey:     nil,
+			encoder:            otp.EncoderDefault,
+			Issuer:             block.Headers["Issuer"],
+			issuerNotInPath:    false,
+			kdf:                nil,
+			labelSeparator:     "",
+			lenientIssuer:      false,
+			lenientPassCode:    false,
+			logger:             nil,
+			lowercaseAlgorithm: false,
+			metrics:            nil,
+			minEntropy:         0,
+			minimalURI:         false,
+			now:                time.Time{},
+			passCodeNotStrict:  false,
+			pepper:             nil,
+			Period:             StrToUint(block.Headers["Period"]),
+			redactedStringer:   false,
+			SecretSize:         StrToUint(block.Headers["Secret Size"]),
+			Skew:               StrToUint(block.Headers["Skew"]),
+			skewCenter:         0,
+			skewDuration:       nil,
+		},
+	}
+
+	if err := key.Secret.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid secret in the PEM block. it may be truncated or corrupted")
+	}
+
+	return key, nil
+}
+
 // GenerateKeyURI creates a new Key object from an TOTP uri/url.
 //
 // Deprecated: Use GenKeyFromURI() instead. This function will be removed in
@@ -161,33 +344,188 @@ func GenerateKeyURI(uri string) (*Key, error) {
 // The URL format is documented here:
 //
 //	https://github.com/google/google-authenticator/wiki/Key-Uri-Format
-func GenKeyFromURI(uri string) (*Key, error) {
-	objURI := URI(uri)
+//
+// The opts are applied to the Key object before the values of the URI are set.
+// The creation time (Options.Created) is taken from the "created" parameter and
+// left zero if missing, such as the URIs of other apps.
+// Some options, such as WithLenientIssuer, also affect the parsing of the URI.
+func GenKeyFromURI(uri string, opts ...Option) (*Key, error) {
+	return genKeyFromURI(uri, nil, opts...)
+}
+
+// genKeyFromURI is the implementation of GenKeyFromURI() and GenHOTPKeyFromURI().
+// The key is HOTP if the counter is not nil.
+func genKeyFromURI(uri string, counter *uint64, opts ...Option) (*Key, error) {
+	// Missing algorithm, digits and period are implied as default. Such as URIs
+	// generated with the WithMinimalURI() option.
+	objURI := URI(uri).withDefaults()
 
-	if err := objURI.Check(); err != nil {
+	// Apply custom options for parsing.
+	//nolint:exhaustruct // only the parsing related fields are used
+	parseOpts := &Options{counter: counter}
+
+	for _, fn := range opts {
+		if err := fn(parseOpts); err != nil {
+			return nil, errors.Wrap(err, "failed to apply custom options")
+		}
+	}
+
+	if err := objURI.check(*parseOpts); err != nil {
 		return nil, errors.Wrap(err, "failed to create URI object from the given URI")
 	}
 
-	// Create KEY object with default options.
-	key, err := GenerateKey(objURI.Issuer(), objURI.AccountName())
+	issuer := objURI.issuerWith(parseOpts.labelSep(), parseOpts.lenientIssuer)
+	_, accountName := objURI.splitLabel(parseOpts.labelSep())
+
+	// Create KEY object with default options. The logging is suppressed since
+	// the generated secret is replaced below.
+	key, err := GenerateKey(issuer, accountName, append(slices.Clip(opts), WithLogger(nil))...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate key")
 	}
 
 	// Update options
-	secret := objURI.Secret()
-	lenSecret := len(secret.Bytes())
+	key.Secret = objURI.Secret()
+	key.NormalizeSecretSize()
 
-	key.Secret = secret
-	key.Options.SecretSize = uint(lenSecret)
+	// Zero if the creation time of the secret is unknown
+	key.Options.Created = objURI.Created()
 
 	key.Options.Period = objURI.Period()
-	key.Options.Algorithm = Algorithm(objURI.Algorithm())
+	key.Options.resolveSkewDuration()
+	key.Options.Algorithm = Algorithm(strings.ToUpper(objURI.Algorithm()))
 	key.Options.Digits = Digits(objURI.Digits())
+	key.Options.counter = counter
+
+	key.Options.logger = parseOpts.logger
+	key.Options.log().Debugf("totp: key imported from URI: algorithm=%s issuer=%q account=%q fingerprint=%s",
+		key.Options.Algorithm, key.Options.Issuer, key.Options.AccountName, fingerprint(key.Secret))
+	logKeyWarnings(key)
+
+	return key, nil
+}
+
+// Range of the period in seconds accepted by GenKeyFromURIStrict().
+const (
+	StrictPeriodMin = uint(15)  // 15 seconds.
+	StrictPeriodMax = uint(300) // 5 minutes.
+)
+
+// GenKeyFromURIStrict is similar to GenKeyFromURI() but rejects the URIs with
+// the parameters that are not conforming. Use it to reject such URIs at import
+// rather than to discover the problems at validation.
+//
+// In addition to the checks of GenKeyFromURI(), it returns an error if:
+//
+//   - the algorithm is not supported. (wraps ErrUnsupportedAlgorithm)
+//   - the digits is not 6 nor 8. (wraps ErrUnsupportedDigits)
+//   - the period is not within StrictPeriodMin and StrictPeriodMax seconds.
+//     (wraps ErrUnsupportedPeriod)
+//
+// The missing algorithm, digits and period are implied as default the same as
+// GenKeyFromURI(). Malformed values, such as "digits=6x", are not.
+func GenKeyFromURIStrict(uri string) (*Key, error) {
+	objURI := URI(uri).withDefaults()
+
+	if algo := Algorithm(strings.ToUpper(objURI.Algorithm())); !algo.IsSupported() {
+		return nil, errors.Wrapf(ErrUnsupportedAlgorithm, "failed to create key from URI: %q", objURI.Algorithm())
+	}
+
+	if digits := Digits(objURI.Digits()); !digits.IsSupported() {
+		return nil, errors.Wrapf(ErrUnsupportedDigits, "failed to create key from URI: %d", objURI.Digits())
+	}
+
+	if period := objURI.Period(); period < StrictPeriodMin || period > StrictPeriodMax {
+		return nil, errors.Wrapf(ErrUnsupportedPeriod,
+			"failed to create key from URI: %d. it should be between %d and %d seconds",
+			period, StrictPeriodMin, StrictPeriodMax)
+	}
+
+	return GenKeyFromURI(uri)
+}
+
+// NewKeyFromOTP creates a new Key object from the Key object of the underlying
+// OTP library (github.com/pquerna/otp). It is a bridge for the users migrating
+// from the library. The URL of the key is imported via GenKeyFromURI(), so the
+// opts are applied the same way.
+//
+// HOTP keys are not supported and return an error. Use GenHOTPKeyFromURI() with
+// the URL of the key instead.
+func NewKeyFromOTP(otpKey *otp.Key, opts ...Option) (*Key, error) {
+	if otpKey == nil {
+		return nil, errors.New("failed to create key: nil OTP key")
+	}
+
+	if otpType := otpKey.Type(); otpType != "totp" {
+		return nil, errors.Errorf("failed to create key: unsupported OTP type %q. only TOTP keys are supported", otpType)
+	}
+
+	key, err := GenKeyFromURI(otpKey.URL(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create key from the OTP key")
+	}
 
 	return key, nil
 }
 
+// checkPEMHeaders returns the problems of the PEM headers of a TOTP key. See
+// CheckPEM().
+func checkPEMHeaders(headers map[string]string) []string {
+	const (
+		base10  = 10
+		bitSize = 32
+	)
+
+	problems := []string{}
+
+	// Required headers of unsigned integers
+	parsed := map[string]uint64{}
+
+	for _, name := range []string{"Digits", "Period", "Secret Size", "Skew"} {
+		value, ok := headers[name]
+		if !ok {
+			problems = append(problems, "missing header: "+name)
+
+			continue
+		}
+
+		number, err := strconv.ParseUint(value, base10, bitSize)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("malformed header: %s: %q", name, value))
+
+			continue
+		}
+
+		parsed[name] = number
+	}
+
+	for _, name := range []string{"Account Name", "Algorithm", "Issuer"} {
+		if headers[name] == "" {
+			problems = append(problems, "missing header: "+name)
+		}
+	}
+
+	if algo, ok := headers["Algorithm"]; ok && algo != "" && !Algorithm(algo).IsSupported() {
+		problems = append(problems, fmt.Sprintf("unsupported algorithm: %q", algo))
+	}
+
+	if digits, ok := parsed["Digits"]; ok && !Digits(digits).IsSupported() {
+		problems = append(problems, fmt.Sprintf("unsupported digits: %d", digits))
+	}
+
+	if period, ok := parsed["Period"]; ok && period == 0 {
+		problems = append(problems, "zero period")
+	}
+
+	if created, ok := headers["Created"]; ok {
+		if _, err := time.Parse(time.RFC3339, created); err != nil {
+			problems = append(problems, fmt.Sprintf("malformed header: Created: %q", created))
+		}
+	}
+
+	return problems
+}
+
 // ----------------------------------------------------------------------------
 //  Methods
 // ----------------------------------------------------------------------------
@@ -195,59 +533,357 @@ func GenKeyFromURI(uri string) (*Key, error) {
 //nolint:gochecknoglobals // allow private global variable to mock during tests
 var pemEncodeToMemory = pem.EncodeToMemory
 
+// AdjacentPassCodes returns the passcodes of the previous, current and next
+// windows (periods). Such as for the UIs that show the surrounding passcodes to
+// ease the transition at the window boundaries.
+//
+// All of them are computed from a single read of the current time, so they are
+// always consistent. It returns an error if the period is zero or the previous
+// window is before the Unix epoch.
+//
+//nolint:nonamedreturns // the names document the order of the passcodes
+func (k *Key) AdjacentPassCodes() (prev, cur, next string, err error) {
+	if k.Options.Period == 0 {
+		return "", "", "", errors.New("period is zero")
+	}
+
+	now := k.Options.timeNow().UTC()
+	period := time.Duration(k.Options.Period) * time.Second
+
+	if now.Add(-period).Unix() < 0 {
+		return "", "", "", errors.New("previous window is before the Unix epoch")
+	}
+
+	if prev, err = k.PassCodeCustom(now.Add(-period)); err != nil {
+		return "", "", "", errors.Wrap(err, "failed to generate the previous passcode")
+	}
+
+	if cur, err = k.PassCodeCustom(now); err != nil {
+		return "", "", "", errors.Wrap(err, "failed to generate the current passcode")
+	}
+
+	if next, err = k.PassCodeCustom(now.Add(period)); err != nil {
+		return "", "", "", errors.Wrap(err, "failed to generate the next passcode")
+	}
+
+	return prev, cur, next, nil
+}
+
+// Age returns the elapsed time since the key was generated. Which is useful for
+// age-based rotation policies. It returns zero if the creation time is unknown,
+// such as keys imported from URIs or PEMs without the "Created" header.
+func (k *Key) Age() time.Duration {
+	if k.Options.Created.IsZero() {
+		return 0
+	}
+
+	return k.Options.timeNow().Sub(k.Options.Created)
+}
+
+// CompatibleWith returns true if the passcodes of the key and the other key are
+// interoperable when their secrets match. Such as to check if the keys of one
+// system validate on the other during cross-system migrations.
+//
+// It compares only the parameters of the passcode generation in the URI:
+//
+//   - Options.Algorithm (case-insensitive)
+//   - Options.Digits
+//   - Options.Period (zero is treated as the default of 30 seconds)
+//
+// The issuer, account name and the secret are ignored. Also the options not in
+// the URI, such as WithChecksum() or WithSecretPepper(), and the validation only
+// ones, such as the skew, are not compared. It returns false if either key is
+// nil.
+func (k *Key) CompatibleWith(other *Key) bool {
+	if k == nil || other == nil {
+		return false
+	}
+
+	return strings.EqualFold(k.Options.Algorithm.String(), other.Options.Algorithm.String()) &&
+		k.Options.Digits == other.Options.Digits &&
+		periodOrDefault(k.Options) == periodOrDefault(other.Options)
+}
+
+// IsValid returns an error if the key is not usable. It is a quick sanity check
+// for the keys loaded from storage, such as a headerless PEM.
+//
+// In addition to Options.Validate(), it checks that the secret meets the minimum
+// length of SecretSizeMin. In that case the error wraps ErrSecretTooShort.
+func (k *Key) IsValid() error {
+	if err := k.Options.Validate(); err != nil {
+		return errors.Wrap(err, "invalid key")
+	}
+
+	if err := k.Secret.Validate(); err != nil {
+		return errors.Wrap(err, "invalid key")
+	}
+
+	return nil
+}
+
+// NormalizeSecretSize sets the SecretSize of the options to the actual length
+// of the secret in bytes. Use it after overwriting the secret, such as when
+// recovering a key from a backed-up secret.
+//
+// TOTP itself does not require SecretSize to be equal to the length of the
+// secret. Though, keeping them consistent avoids surprises, such as a mismatch
+// of the "Secret Size" header and the secret in PEM().
+func (k *Key) NormalizeSecretSize() {
+	k.Options.SecretSize = uint(k.Secret.Len())
+}
+
 // PassCode generates a 6 or 8 digits passcode for the current time.
 // The output string will be eg. "123456" or "12345678".
 func (k *Key) PassCode() (string, error) {
-	//nolint:wrapcheck // we won't wrap the error here
-	return totp.GenerateCodeCustom(
-		k.Secret.Base32(),
-		time.Now().UTC(),
-		totp.ValidateOpts{
-			Period:    k.Options.Period,
-			Skew:      k.Options.Skew,
-			Digits:    k.Options.Digits.OTPDigits(),
-			Algorithm: k.Options.Algorithm.OTPAlgorithm(),
-		},
-	)
+	return k.PassCodeCustom(k.Options.timeNow())
 }
 
 // PassCodeCustom is similar to PassCode() but allows you to specify the time
 // to generate the passcode.
 func (k *Key) PassCodeCustom(genTime time.Time) (string, error) {
+	passcode, err := k.passCodeRaw(genTime)
+	if err != nil || !k.Options.checksum {
+		return passcode, err
+	}
+
+	return appendChecksum(passcode), nil
+}
+
+// passCodeRaw is the implementation of PassCodeCustom() without the checksum
+// digit.
+func (k *Key) passCodeRaw(genTime time.Time) (string, error) {
+	if err := k.Options.checkTOTP(); err != nil {
+		return "", errors.Wrap(err, "failed to generate passcode")
+	}
+
 	//nolint:wrapcheck // we won't wrap the error here
-	return totp.GenerateCodeCustom(
-		k.Secret.Base32(),
-		genTime.UTC(),
-		totp.ValidateOpts{
-			Period:    k.Options.Period,
-			Skew:      k.Options.Skew,
-			Digits:    k.Options.Digits.OTPDigits(),
-			Algorithm: k.Options.Algorithm.OTPAlgorithm(),
-		},
-	)
+	return totp.GenerateCodeCustom(k.PepperedSecret().Base32(), genTime.UTC(), k.Options.otpOpts())
 }
 
-// PEM returns the key in PEM formatted string.
+// PassCodeValue is similar to PassCode() but returns the passcode of the
+// current time as an integer. Such as for the binary protocols that send the
+// passcode as a big-endian integer rather than a string.
+//
+// It is the truncated HMAC value modulo 10^Digits (see DynamicTruncate()), the
+// numeric form of PassCode(). Note that the leading zeros are not kept. e.g.
+// "001234" is 1234. The callers must pad it to the digits to display it, such as
+// Digits.OTPDigits().Format(). The checksum digit of WithChecksum() is not
+// included.
+func (k *Key) PassCodeValue() (uint32, error) {
+	const lenCounter = 8
+
+	switch {
+	case !k.Options.Algorithm.IsSupported():
+		return 0, errors.Wrapf(ErrUnsupportedAlgorithm, "failed to generate passcode: %q", k.Options.Algorithm)
+	case !k.Options.Digits.IsSupported():
+		return 0, errors.Wrapf(ErrUnsupportedDigits, "failed to generate passcode: %d", k.Options.Digits)
+	}
+
+	if err := k.Options.checkTOTP(); err != nil {
+		return 0, errors.Wrap(err, "failed to generate passcode")
+	}
+
+	genTime := k.Options.timeNow().UTC()
+	if genTime.Unix() < 0 {
+		return 0, errors.New("failed to generate passcode: time is before the Unix epoch")
+	}
+
+	message := make([]byte, lenCounter)
+	binary.BigEndian.PutUint64(message, TimeToCounter(genTime, k.Options.Period))
+
+	mac := hmac.New(k.Options.Algorithm.OTPAlgorithm().Hash, k.PepperedSecret())
+	mac.Write(message)
+
+	value, err := DynamicTruncate(mac.Sum(nil))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to generate passcode")
+	}
+
+	modulo := uint32(math.Pow10(k.Options.Digits.OTPDigits().Length()))
+
+	return value % modulo, nil
+}
+
+// PassCodeWithTTL is similar to PassCode() but also returns the remaining time
+// until the passcode expires. Such as for a "code + countdown" widget.
+//
+// Both are computed from a single read of the current time. So, unlike calling
+// PassCode() and computing the remaining time separately, they never straddle a
+// window boundary.
+func (k *Key) PassCodeWithTTL() (string, time.Duration, error) {
+	now := k.Options.timeNow().UTC()
+	period := int64(periodOrDefault(k.Options)) //nolint:gosec // period is small enough
+
+	code, err := k.PassCodeCustom(now)
+	if err != nil {
+		return "", 0, err
+	}
+
+	windowEnd := time.Unix((now.Unix()/period+1)*period, 0)
+
+	return code, windowEnd.Sub(now), nil
+}
+
+// PassCodeWithOffset is similar to PassCode() but generates the passcode for
+// the current time shifted by the given offset. Such as a client which knows
+// its clock is off by a measured offset and wants to generate the passcode as
+// the server would see it.
+//
+// It returns an error if the shifted time is before the Unix epoch, where the
+// TOTP counter underflows. The time.Duration itself is bounded to about ±292
+// years, so the shifted time never overflows.
+func (k *Key) PassCodeWithOffset(offset time.Duration) (string, error) {
+	genTime := k.Options.timeNow().UTC().Add(offset)
+
+	if genTime.Unix() < 0 {
+		return "", errors.Errorf("offset out of range: %s. time is before the Unix epoch", offset)
+	}
+
+	return k.PassCodeCustom(genTime)
+}
+
+// PassCodeSeries returns the passcodes of every window (period) that starts
+// within [start, end) in chronological order. Each entry holds the start time
+// of the window and its passcode.
+//
+// Note that the window containing the start time is not included unless the
+// start time is exactly on the window boundary. This is handy for generating a
+// verification table for docs, tests or logs.
+func (k *Key) PassCodeSeries(start, end time.Time) ([]TimedPassCode, error) {
+	switch {
+	case k.Options.Period == 0:
+		return nil, errors.New("period is zero")
+	case !end.After(start):
+		return nil, errors.New("invalid time range. end should be after start")
+	}
+
+	period := int64(k.Options.Period) //nolint:gosec // period is small enough
+
+	// Round up the start time to the next window boundary.
+	boundary := start.Unix() / period * period
+	if time.Unix(boundary, 0).Before(start) {
+		boundary += period
+	}
+
+	var series []TimedPassCode
+
+	for ; time.Unix(boundary, 0).Before(end); boundary += period {
+		timeWindow := time.Unix(boundary, 0).UTC()
+
+		code, err := k.PassCodeCustom(timeWindow)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate passcode at %s", timeWindow)
+		}
+
+		series = append(series, TimedPassCode{
+			Time: timeWindow,
+			Code: code,
+		})
+	}
+
+	return series, nil
+}
+
+// PEM returns the key in PEM formatted string. It ends with a newline. See
+// PEMBytes() for details.
 func (k *Key) PEM() (string, error) {
+	out, err := k.PEMBytes()
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// PEMBytes returns the key in PEM format as raw bytes.
+//
+// Note that the output always ends with a newline as pem.EncodeToMemory does.
+// So printing it with fmt.Println results in an empty line at the end. Use
+// PEMBytesTrimmed() to omit the trailing newline.
+func (k *Key) PEMBytes() ([]byte, error) {
+	headers := map[string]string{
+		"Account Name": k.Options.AccountName,
+		"Algorithm":    k.Options.Algorithm.String(),
+		"Digits":       k.Options.Digits.String(),
+		"Issuer":       k.Options.Issuer,
+		"Period":       strconv.FormatUint(uint64(k.Options.Period), 10),
+		"Secret Size":  strconv.FormatUint(uint64(k.Options.SecretSize), 10),
+		"Skew":         strconv.FormatUint(uint64(k.Options.Skew), 10),
+	}
+
+	if !k.Options.Created.IsZero() {
+		headers["Created"] = k.Options.Created.UTC().Format(time.RFC3339)
+	}
+
 	out := pemEncodeToMemory(&pem.Block{
-		Type: BlockTypeTOTP,
-		Headers: map[string]string{
-			"Account Name": k.Options.AccountName,
-			"Algorithm":    k.Options.Algorithm.String(),
-			"Digits":       k.Options.Digits.String(),
-			"Issuer":       k.Options.Issuer,
-			"Period":       strconv.FormatUint(uint64(k.Options.Period), 10),
-			"Secret Size":  strconv.FormatUint(uint64(k.Options.SecretSize), 10),
-			"Skew":         strconv.FormatUint(uint64(k.Options.Skew), 10),
-		},
-		Bytes: k.Secret.Bytes(),
+		Type:    BlockTypeTOTP,
+		Headers: headers,
+		Bytes:   k.Secret.Bytes(),
 	})
 
 	if out == nil {
-		return "", errors.New("failed to encode key to PEM")
+		return nil, errors.New("failed to encode key to PEM")
 	}
 
-	return string(out), nil
+	return out, nil
+}
+
+// PEMBytesTrimmed is similar to PEMBytes() but omits the trailing newline. Such
+// as for embedding the PEM into fixed-width fields or concatenating PEM blocks
+// with a custom separator.
+func (k *Key) PEMBytesTrimmed() ([]byte, error) {
+	out, err := k.PEMBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(out, []byte("\n")), nil
+}
+
+// PepperedSecret returns the secret that the passcodes are computed with. It is
+// the secret of the key stretched with the pepper if WithSecretPepper() is set,
+// otherwise the secret itself. This is the secret that the authenticator apps
+// need, such as in Key.URI().
+func (k *Key) PepperedSecret() Secret {
+	return k.Options.pepperSecret(k.Secret)
+}
+
+// PrecomputeHashedCodes returns the hashed passcodes of the given number of
+// windows (periods) starting from the window of the given time. The passcodes
+// are hashed with the given hash function and returned in chronological order.
+//
+// This is for the split-trust design where an offline verifier compares the
+// hash of a presented passcode against the precomputed hashes without holding
+// the secret.
+//
+// Security note: a passcode has only 10^Digits possible values, so the hashes
+// can be brute-forced easily unless the hash function is keyed (e.g. HMAC with
+// a key only the verifier knows). Also, all the precomputed passcodes are
+// valid in advance. Therefore, keep the number of windows as small as possible
+// and protect the output as if it were the passcodes themselves.
+func (k *Key) PrecomputeHashedCodes(from time.Time, windows int, hash func([]byte) []byte) ([][]byte, error) {
+	switch {
+	case windows <= 0:
+		return nil, errors.Errorf("invalid number of windows: %d. it should be greater than zero", windows)
+	case hash == nil:
+		return nil, errors.New("hash function is nil")
+	case k.Options.Period == 0:
+		return nil, errors.New("period is zero")
+	}
+
+	period := time.Duration(k.Options.Period) * time.Second
+	hashed := make([][]byte, 0, windows)
+
+	for index := range windows {
+		passcode, err := k.PassCodeCustom(from.Add(time.Duration(index) * period))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate passcode of window %d", index)
+		}
+
+		hashed = append(hashed, hash([]byte(passcode)))
+	}
+
+	return hashed, nil
 }
 
 // QRCode returns a QR code image of a specified width and height, suitable for
@@ -260,58 +896,611 @@ func (k *Key) QRCode(fixLevel FixLevel) (*QRCode, error) {
 	qrCode := &QRCode{
 		URI:   URI(k.URI()),
 		Level: fixLevel,
+		Mode:  QRModeAuto,
+	}
+
+	return qrCode, nil
+}
+
+// QRCodeTerminal returns the QR code of the key's URI as a string ready to be
+// printed in terminals. Such as for headless or SSH enrollment. See the
+// QRCode.Terminal() method for details.
+func (k *Key) QRCodeTerminal(fixLevel FixLevel) (string, error) {
+	qrCode, err := k.QRCode(fixLevel)
+	if err != nil {
+		return "", err
+	}
+
+	return qrCode.Terminal()
+}
+
+// SecretQRCode is similar to QRCode() but the QR code holds only the secret
+// in base32 instead of the full otpauth URI. This produces smaller, less dense
+// QR codes for minimalist apps that prompt for the secret only.
+//
+// Note that such QR codes carry no issuer, account name, algorithm, digits or
+// period. The app must be configured with them separately. Also the URI field
+// of the returned QRCode is NOT a valid otpauth URI.
+func (k *Key) SecretQRCode(fixLevel FixLevel) (*QRCode, error) {
+	if !fixLevel.isValid() {
+		return nil, errors.Errorf("unsupported fix level: %v", fixLevel)
+	}
+
+	qrCode := &QRCode{
+		URI:   URI(k.PepperedSecret().Base32()),
+		Level: fixLevel,
+		Mode:  QRModeAuto,
 	}
 
 	return qrCode, nil
 }
 
+// SavePEM writes the key in PEM format to the given path with the given
+// permission. If perm is zero, 0o600 (owner only) is used since the PEM data
+// contains the secret.
+//
+// The file is written atomically. It writes to a temporary file first and then
+// renames it, so a partially-written file is never left on crash.
+func (k *Key) SavePEM(path string, perm os.FileMode) error {
+	keyPEM, err := k.PEMBytes()
+	if err != nil {
+		return errors.Wrap(err, "failed to encode key to PEM")
+	}
+
+	if err := writeFileAtomic(path, keyPEM, perm); err != nil {
+		return errors.Wrap(err, "failed to write PEM file")
+	}
+
+	return nil
+}
+
 // String returns a string representation of the key in URI format.
 //
-// It is an implementation of the fmt.Stringer interface.
+// It is an implementation of the fmt.Stringer interface. Note that the URI
+// includes the live secret, which leaks if the key is logged via "%v" by
+// accident. Set the WithRedactedStringer() option to replace the secret with
+// "REDACTED". Use URI() for the full value in that case.
 func (k *Key) String() string {
+	if k.Options.redactedStringer {
+		queryVal := k.URIValues()
+		queryVal.Set("secret", redacted)
+
+		return k.URIWithValues(queryVal)
+	}
+
 	return k.URI()
 }
 
+// GoString is an implementation of the fmt.GoStringer interface. It is used by
+// the "%#v" format and redacts the secret. The options are printed as
+// Options.GoString() does. So the key is safe to be printed for debugging.
+func (k Key) GoString() string {
+	return "totp.Key{Secret:" + redactedGoString(len(k.Secret) == 0, k.Secret) +
+		", Options:" + k.Options.GoString() + "}"
+}
+
 // URI returns the key in OTP URI format.
 //
 // It re-generates the URI from the values stored in the Key object and will not
-// use the original URI.
+// use the original URI. It is a shorthand of URIWithValues(k.URIValues()).
 func (k *Key) URI() string {
+	return k.URIWithValues(k.URIValues())
+}
+
+// URIValues returns the query parameters of the URI, such as the secret, issuer
+// and algorithm, as url.Values. Each call returns a new map, so the callers can
+// modify it freely. Such as adding custom parameters and rendering it with
+// URIWithValues().
+//
+// The parameters equal to the defaults are omitted if the key is generated with
+// WithMinimalURI(). HOTP keys have the "counter" parameter instead of "period".
+// The "created" parameter is the creation time in RFC3339 if known. It is
+// specific to this package and ignored by the authenticator apps.
+func (k *Key) URIValues() url.Values {
 	queryVal := url.Values{}
 
 	queryVal.Set("issuer", k.Options.Issuer)
-	queryVal.Set("algorithm", k.Options.Algorithm.String())
+
+	algo := k.Options.Algorithm.String()
+	if k.Options.lowercaseAlgorithm {
+		algo = strings.ToLower(algo)
+	}
+
+	queryVal.Set("algorithm", algo)
 	queryVal.Set("digits", k.Options.Digits.String())
-	queryVal.Set("secret", k.Secret.Base32())
+	queryVal.Set("secret", k.PepperedSecret().Base32())
 	queryVal.Set("period", strconv.FormatUint(uint64(k.Options.Period), 10))
 
+	if !k.Options.Created.IsZero() {
+		queryVal.Set("created", k.Options.Created.UTC().Format(time.RFC3339))
+	}
+
+	// HOTP keys have the counter instead of the period
+	if counter, ok := k.Counter(); ok {
+		queryVal.Set("counter", strconv.FormatUint(counter, 10))
+		queryVal.Del("period")
+	}
+
+	if k.Options.minimalURI {
+		// Omit the parameters that are equal to the defaults. They are implied
+		// on parsing.
+		if k.Options.Algorithm == OptionAlgorithmDefault {
+			queryVal.Del("algorithm")
+		}
+
+		if k.Options.Digits == OptionDigitsDefault {
+			queryVal.Del("digits")
+		}
+
+		if k.Options.Period == OptionPeriodDefault {
+			queryVal.Del("period")
+		}
+	}
+
+	return queryVal
+}
+
+// URIWithValues returns the key in OTP URI format with the given query values.
+// The label (path) is generated from the key as URI() does and the values are
+// encoded as is in sorted order by key. Usually the values are obtained from
+// URIValues() and then modified.
+//
+// Note that the values are not checked. Removing or altering the required
+// parameters, such as the secret, results in a URI that may not be imported.
+func (k *Key) URIWithValues(values url.Values) string {
+	sep := k.Options.labelSep()
+
+	label := k.Options.Issuer + sep + k.Options.AccountName
+	if k.Options.issuerNotInPath {
+		label = k.Options.AccountName
+	}
+
 	//nolint:exhaustruct // other fields are left blank on purpose
 	urlOut := url.URL{
 		Scheme:   "otpauth",
-		Host:     "totp",
-		Path:     "/" + k.Options.Issuer + ":" + k.Options.AccountName,
-		RawQuery: queryVal.Encode(),
+		Host:     k.Options.otpType().String(),
+		Path:     "/" + label,
+		RawQuery: values.Encode(),
+	}
+
+	// "+" in the issuer of the label is read as a space on import. Escape it to
+	// keep it literal.
+	if !k.Options.issuerNotInPath && strings.Contains(k.Options.Issuer, "+") {
+		issuerEsc := strings.ReplaceAll(escapePath(k.Options.Issuer), "+", "%2B")
+
+		urlOut.RawPath = "/" + issuerEsc + escapePath(sep) + escapePath(k.Options.AccountName)
 	}
 
 	return urlOut.String()
 }
 
 // Validate returns true if the given passcode is valid for the current time.
-// For custom time, use ValidateCustom() instead.
+// For custom time, use ValidateCustom() instead. It is a shorthand of Verify().
+//
+// Passcodes that are not a number of the Digits are rejected early without
+// computing any HMAC. See WithLenientPassCode() and WithStrictPassCode() to
+// relax the check.
 func (k *Key) Validate(passcode string) bool {
-	return Validate(
+	return k.Verify(passcode, k.Options.timeNow()).Valid
+}
+
+// ValidateE is similar to Validate() but returns an error if the validation
+// could not be performed. Such as an empty or malformed passcode (non-numeric or
+// not the expected digits) or a misconfigured key. This lets the callers
+// distinguish "enter your code" or "broken key" from "wrong code".
+//
+// A well-formed but wrong passcode returns false with no error. See the
+// ValidateE() function for the wrapped errors.
+func (k *Key) ValidateE(passcode string) (bool, error) {
+	return ValidateE(
 		passcode,
 		k.Secret.Base32(),
 		k.Options,
 	)
 }
 
+// ValidateInt is similar to Validate() but accepts the passcode as an integer.
+// Such as a passcode parsed from a numeric input.
+//
+// The integer is zero-padded to the width of the key's Digits before validation.
+// For example, 12345 is validated as "012345" for 6 digits and as "00012345" for
+// 8 digits. Therefore, a passcode with its leading zeros dropped by the parsing
+// is still valid. Negative values are always invalid.
+func (k *Key) ValidateInt(code int64) bool {
+	if code < 0 {
+		return false
+	}
+
+	width := k.Options.Digits.OTPDigits().Length()
+
+	return k.Validate(fmt.Sprintf("%0*d", width, code))
+}
+
+// ValidateNotInHistory is similar to Validate() but also rejects the passcode
+// if it matches any of the recently accepted passcodes in the history. This is
+// a lightweight reuse guard for the callers that already persist the recent
+// passcodes.
+//
+// All the entries of the history are compared in constant time. Surrounding
+// spaces of the passcode are ignored.
+func (k *Key) ValidateNotInHistory(passcode string, history []string) bool {
+	passcode = k.Options.normalizePassCode(passcode)
+	used := 0
+
+	for _, entry := range history {
+		used |= subtle.ConstantTimeCompare([]byte(passcode), []byte(k.Options.normalizePassCode(entry)))
+	}
+
+	return k.Validate(passcode) && used == 0
+}
+
+// ValidateResync is similar to Validate() but also re-synchronizes the key to
+// the clock drift of the client (RFC 6238, section 6).
+//
+// On success, it returns the offset in periods between the current time and
+// the window where the passcode matched. The offset is also applied to the key
+// so that the subsequent validations are centered on it. For example, if the
+// client clock is one period ahead, it returns 1 and the following validations
+// check [1-Skew, 1+Skew] periods instead of [-Skew, +Skew].
+//
+// On failure, it returns false and the current center without any change.
+func (k *Key) ValidateResync(passcode string) (bool, int) {
+	offset, ok := matchOffset(passcode, k.PepperedSecret().Base32(), k.Options.timeNow().UTC(), k.Options)
+	if !ok {
+		return false, k.Options.skewCenter
+	}
+
+	k.Options.skewCenter = offset
+
+	return true, offset
+}
+
+// ValidateTryAlgorithms validates the passcode with each of the supported
+// algorithms, regardless of the algorithm of the key, and returns the first one
+// that validates. The algorithms are tried in the order of SHA1, SHA256 and
+// SHA512. MD5 is not tried since it is rarely used by the apps.
+//
+// This rescues keys imported with a missing or incorrect algorithm, a common
+// problem in real-world imports. The key is not modified, so the caller should
+// persist the discovered algorithm. For example:
+//
+//	if algo, ok := key.ValidateTryAlgorithms(passcode); ok {
+//		key.Options.Algorithm = algo
+//	}
+//
+// Note that a wrong algorithm may match by chance. Confirm with the passcode of
+// the next period before persisting if possible.
+func (k *Key) ValidateTryAlgorithms(passcode string) (Algorithm, bool) {
+	options := k.Options
+
+	for _, algo := range []Algorithm{"SHA1", "SHA256", "SHA512"} {
+		options.Algorithm = algo
+
+		if Validate(passcode, k.Secret.Base32(), options) {
+			return algo, true
+		}
+	}
+
+	return "", false
+}
+
+// ValidateWith is similar to Validate() but uses the digits, period and
+// algorithm of the override options instead of the key's. The zero values of
+// the override fall back to the key's. The secret, skew and the current time
+// are of the key.
+//
+// This is handy during parameter migrations where the passcodes of the old and
+// new parameters coexist briefly, without cloning and mutating the key.
+func (k *Key) ValidateWith(passcode string, override Options) bool {
+	options := k.Options
+
+	if override.Algorithm != "" {
+		options.Algorithm = override.Algorithm
+	}
+
+	if override.Digits != 0 {
+		options.Digits = override.Digits
+	}
+
+	if override.Period != 0 {
+		options.Period = override.Period
+	}
+
+	return Validate(passcode, k.Secret.Base32(), options)
+}
+
+// ValidateVerbose is a diagnostic version of Validate(). It evaluates the
+// passcode against every window within the skew for the current time and
+// returns the results in chronological order.
+//
+// This is useful to debug flaky validations, such as "it worked a second ago".
+// Note that it always evaluates all the windows, unlike Validate(), which stops
+// at the first match. Use Validate() for the actual validation.
+func (k *Key) ValidateVerbose(passcode string) []WindowResult {
+	return k.validateVerbose(passcode, k.Options.timeNow().UTC())
+}
+
+// validateVerbose is the implementation of ValidateVerbose() with a custom time.
+func (k *Key) validateVerbose(passcode string, validationTime time.Time) []WindowResult {
+	if !k.Options.Algorithm.IsSupported() || k.Options.Skew > math.MaxInt32 || k.Options.checkTOTP() != nil {
+		return nil
+	}
+
+	// Malformed passcodes match no window but the windows are still reported
+	passcode, err := k.Options.preparePassCode(passcode)
+	if err != nil {
+		passcode = ""
+	}
+
+	results := make([]WindowResult, 0, k.Options.Skew*2+1)
+
+	forEachWindow(passcode, k.PepperedSecret().Base32(), validationTime, validationTime, k.Options,
+		func(window time.Time, offset int, match bool) bool {
+			results = append(results, WindowResult{Window: window, Offset: offset, Match: match})
+
+			return true
+		})
+
+	return results
+}
+
+// ValidateAppended validates the input in the "PIN + OTP" form, such as the ones
+// from RADIUS or VPN setups that append the passcode to the PIN.
+//
+// It splits off the trailing passcode of the key's Digits length, validates it
+// and returns the leading portion as the PIN for the caller to check separately.
+// The pinLen is the expected length of the PIN. If negative, any length of PIN,
+// including empty, is accepted.
+//
+// It returns an empty PIN and false if the input is shorter than the passcode,
+// the PIN length does not match or the passcode is invalid.
+func (k *Key) ValidateAppended(input string, pinLen int) (string, bool) {
+	width := k.Options.Digits.OTPDigits().Length()
+	if k.Options.checksum {
+		width++
+	}
+
+	switch {
+	case len(input) < width:
+		return "", false
+	case pinLen >= 0 && len(input) != pinLen+width:
+		return "", false
+	}
+
+	split := len(input) - width
+
+	if !k.Validate(input[split:]) {
+		return "", false
+	}
+
+	return input[:split], true
+}
+
+// ValidateBatch validates the passcodes of the items at their time and returns
+// the results in the same order. Such as for reconciliation jobs that validate
+// many passcodes of the same key offline.
+//
+// It is the equivalent of calling ValidateCustom() for each item but compiles
+// the key only once. See Key.Compile(). If the key can not be compiled, such as
+// unsupported algorithm, all the results are false.
+func (k *Key) ValidateBatch(items []BatchItem) []bool {
+	results := make([]bool, len(items))
+
+	validator, err := k.Compile()
+	if err != nil {
+		return results
+	}
+
+	for index, item := range items {
+		results[index] = validator.ValidateCustom(item.Code, item.At)
+	}
+
+	return results
+}
+
 // ValidateCustom returns true if the given passcode is valid for the custom time.
+// It is a shorthand of Verify().
 func (k *Key) ValidateCustom(passcode string, validationTime time.Time) bool {
-	return ValidateCustom(
-		passcode,
-		k.Secret.Base32(),
-		validationTime.UTC(),
-		k.Options,
-	)
+	return k.Verify(passcode, validationTime).Valid
+}
+
+// MaxRangeWindows is the maximum number of windows (periods) to search by
+// Key.ValidateInRangeE(). About 3.5 days with the 30 seconds period.
+const MaxRangeWindows = 10_000
+
+// ValidateInRange returns true if the passcode is valid at any time within the
+// given range, inclusive. It is a shorthand of ValidateInRangeE() that returns
+// false on errors.
+func (k *Key) ValidateInRange(passcode string, from, to time.Time) bool {
+	isValid, _ := k.ValidateInRangeE(passcode, from, to)
+
+	return isValid
+}
+
+// ValidateInRangeE validates the passcode captured at an uncertain time within
+// the given range, inclusive. Such as the offline and forensic verifications of
+// the passcodes from delayed logs. Use ValidWindows() to get when it was valid.
+//
+// It checks every window overlapping the range, widened by the skew of the key
+// on both sides as Validate() does. The same time for from and to is the same as
+// ValidateCustom().
+//
+// It returns an error if the passcode is malformed, the options can not be used
+// for validation, from is after to, or the range spans more windows than
+// MaxRangeWindows (wraps ErrRangeTooLarge).
+func (k *Key) ValidateInRangeE(passcode string, from, to time.Time) (bool, error) {
+	passcode, err := k.Options.preparePassCode(passcode)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid passcode")
+	}
+
+	switch {
+	case !k.Options.Algorithm.IsSupported():
+		return false, errors.Wrapf(ErrUnsupportedAlgorithm, "invalid options: %q", k.Options.Algorithm)
+	case !k.Options.isPeriodAccepted(k.Options.Period):
+		return false, errors.Wrapf(ErrUnsupportedPeriod, "invalid options: %d", k.Options.Period)
+	case k.Options.checkTOTP() != nil:
+		return false, errors.Wrap(k.Options.checkTOTP(), "invalid options")
+	case k.Options.Skew > MaxRangeWindows:
+		return false, errors.Wrapf(ErrRangeTooLarge, "skew %d exceeds %d windows", k.Options.Skew, MaxRangeWindows)
+	case from.After(to):
+		return false, errors.Errorf("invalid range: from (%s) is after to (%s)", from, to)
+	}
+
+	if first, last := windowRange(from, to, k.Options); last-first >= MaxRangeWindows {
+		return false, errors.Wrapf(ErrRangeTooLarge, "%d windows exceeds %d", last-first+1, MaxRangeWindows)
+	}
+
+	isValid := false
+
+	forEachWindow(passcode, k.PepperedSecret().Base32(), from, to, k.Options,
+		func(_ time.Time, _ int, match bool) bool {
+			isValid = match
+
+			return !match
+		})
+
+	return isValid, nil
+}
+
+// Verify validates the passcode at the given time and returns the result with
+// the metadata, such as the offset and the start time of the matched window, or
+// the error if the validation could not be performed.
+//
+// This is the recommended entry point of the validation. The boolean methods,
+// such as Validate() and ValidateCustom(), are the shorthands of it. The error
+// wraps ErrEmptyPasscode, ErrMalformedPasscode or ErrUnsupportedAlgorithm if
+// it is the cause. A well-formed but wrong passcode is not an error.
+func (k *Key) Verify(passcode string, at time.Time) ValidationResult {
+	//nolint:exhaustruct // the rest of the fields are set below
+	result := ValidationResult{}
+
+	passcode, err := k.Options.preparePassCode(passcode)
+	if err != nil {
+		result.Err = errors.Wrap(err, "invalid passcode")
+		observeFailure(k.Options)
+
+		return result
+	}
+
+	// The underlying OTP library panics on unsupported algorithms
+	if !k.Options.Algorithm.IsSupported() {
+		result.Err = errors.Wrapf(ErrUnsupportedAlgorithm, "invalid options: %q", k.Options.Algorithm)
+		observeFailure(k.Options)
+
+		return result
+	}
+
+	if !k.Options.isPeriodAccepted(k.Options.Period) {
+		result.Err = errors.Wrapf(ErrUnsupportedPeriod, "invalid options: %d", k.Options.Period)
+		observeFailure(k.Options)
+
+		return result
+	}
+
+	if err := k.Options.checkTOTP(); err != nil {
+		result.Err = errors.Wrap(err, "invalid options")
+		observeFailure(k.Options)
+
+		return result
+	}
+
+	secret := k.PepperedSecret().Base32()
+
+	offset, ok := matchOffset(passcode, secret, at.UTC(), k.Options)
+	observeValidation(k.Options, passcode, secret, at.UTC(), offset, ok)
+
+	if !ok {
+		k.Options.log().Debugf(
+			"totp: validation failed within skew: skew=%d period=%d issuer=%q account=%q fingerprint=%s",
+			k.Options.Skew, k.Options.Period, k.Options.Issuer, k.Options.AccountName, fingerprint(k.Secret))
+
+		return result
+	}
+
+	period := periodOrDefault(k.Options)
+	windowTime := at.Add(time.Duration(offset) * time.Duration(period) * time.Second)
+
+	result.Valid = true
+	result.Offset = offset
+	result.MatchedAt = CounterToTime(TimeToCounter(windowTime, period), period)
+
+	return result
+}
+
+// ValidWindows returns every time range within the given range in which the
+// passcode would validate with ValidateCustom(). Such as for forensic analysis
+// of "when could this passcode have worked". The ranges are in chronological
+// order, merged if overlapping and clipped to the given range.
+//
+// Since the passcodes repeat across distant times, the result may contain more
+// than one range. The skew of the key is taken into account, so each range is
+// wider than a period by the skew on both sides.
+//
+// The search is bounded by the given range. It returns nil if the passcode
+// never validates within the range, the range is empty or the key options can
+// not be used for validation.
+func (k *Key) ValidWindows(passcode string, from, to time.Time) []TimeWindow {
+	passcode, err := k.Options.preparePassCode(passcode)
+	if err != nil || !to.After(from) {
+		return nil
+	}
+
+	if !k.Options.Algorithm.IsSupported() || k.Options.Skew > math.MaxInt32 || k.Options.checkTOTP() != nil {
+		return nil
+	}
+
+	periodSec := periodOrDefault(k.Options)
+	period := time.Duration(periodSec) * time.Second
+	skew := int(k.Options.Skew)
+
+	// The passcode of a window validates from (center + skew) periods before the
+	// window to (center - skew) periods after the window.
+	before := time.Duration(k.Options.skewCenter+skew) * period
+	after := time.Duration(skew-k.Options.skewCenter) * period
+
+	var windows []TimeWindow
+
+	// Search the windows of the passcode covering the range with the skew
+	forEachWindow(passcode, k.PepperedSecret().Base32(), from, to, k.Options,
+		func(windowTime time.Time, _ int, match bool) bool {
+			if !match {
+				return true
+			}
+
+			windows = appendWindow(windows, TimeWindow{
+				Start: windowTime.Add(-before),
+				End:   windowTime.Add(period + after),
+			}, from, to)
+
+			return true
+		})
+
+	return windows
+}
+
+// appendWindow appends the window clipped to the range of from and to. It is
+// merged to the last one if overlapping and dropped if empty after clipping.
+func appendWindow(windows []TimeWindow, window TimeWindow, from, to time.Time) []TimeWindow {
+	// Clip to the range
+	if window.Start.Before(from) {
+		window.Start = from.UTC()
+	}
+
+	if window.End.After(to) {
+		window.End = to.UTC()
+	}
+
+	if !window.End.After(window.Start) {
+		return windows
+	}
+
+	// Merge overlapping ranges
+	if index := len(windows) - 1; index >= 0 && !window.Start.After(windows[index].End) {
+		windows[index].End = window.End
+
+		return windows
+	}
+
+	return append(windows, window)
 }
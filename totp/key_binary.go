@@ -0,0 +1,213 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// keyBinaryVersion is the current version of the binary format of the Key. See
+// Key.MarshalBinary().
+const keyBinaryVersion byte = 1
+
+// Types of the OTP in the binary format of the Key.
+const (
+	keyBinaryTOTP byte = 0
+	keyBinaryHOTP byte = 1
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. It encodes
+// the key in a compact and versioned binary format for binary protocols or
+// caches. Which is smaller than PEM.
+//
+// The layout (version 1) is:
+//
+//	version (1 byte) | algorithm ID (1 byte) | digits (1 byte) |
+//	period (uvarint) | skew (uvarint) | created (varint) |
+//	issuer length (uvarint) | issuer | account name length (uvarint) | account name |
+//	secret size (uvarint) | secret | type (1 byte) | [counter (uvarint)]
+//
+// The created is the creation time (Created) in Unix seconds, 0 if unknown. The
+// type is 0 for TOTP and 1 for HOTP, which is followed by the counter of the
+// HOTP key. Only these and the exported fields of the options are stored. Use
+// UnmarshalBinary() to decode.
+func (k *Key) MarshalBinary() ([]byte, error) {
+	if !k.Options.Algorithm.IsSupported() {
+		return nil, errors.Wrapf(ErrUnsupportedAlgorithm, "failed to marshal key: %q", k.Options.Algorithm)
+	}
+
+	if k.Options.Digits > math.MaxUint8 {
+		return nil, errors.Wrapf(ErrUnsupportedDigits, "failed to marshal key: %d", k.Options.Digits)
+	}
+
+	out := []byte{
+		keyBinaryVersion,
+		byte(k.Options.Algorithm.ID()),
+		byte(k.Options.Digits),
+	}
+
+	out = binary.AppendUvarint(out, uint64(k.Options.Period))
+	out = binary.AppendUvarint(out, uint64(k.Options.Skew))
+	out = binary.AppendVarint(out, unixOrZero(k.Options.Created))
+	out = appendLengthPrefixed(out, []byte(k.Options.Issuer))
+	out = appendLengthPrefixed(out, []byte(k.Options.AccountName))
+	out = appendLengthPrefixed(out, k.Secret)
+
+	if counter, ok := k.Counter(); ok {
+		out = append(out, keyBinaryHOTP)
+		out = binary.AppendUvarint(out, counter)
+	} else {
+		out = append(out, keyBinaryTOTP)
+	}
+
+	return out, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. It
+// decodes the binary data encoded by MarshalBinary() and overwrites the key.
+//
+// It returns an error if the data is truncated, has trailing bytes or is in an
+// unsupported version. The key is left untouched on error.
+func (k *Key) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	header := make([]byte, 3) // version, algorithm ID and digits
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return errors.Wrap(err, "failed to unmarshal key: truncated header")
+	}
+
+	if header[0] != keyBinaryVersion {
+		return errors.Errorf("failed to unmarshal key: unsupported version %d", header[0])
+	}
+
+	algo, err := NewAlgorithmID(int(header[1]))
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal key")
+	}
+
+	period, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal key: malformed period")
+	}
+
+	skew, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal key: malformed skew")
+	}
+
+	created, err := binary.ReadVarint(reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal key: malformed creation time")
+	}
+
+	fields := make([][]byte, 3) // issuer, account name and secret
+
+	for index, name := range []string{"issuer", "account name", "secret"} {
+		fields[index], err = readLengthPrefixed(reader)
+		if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal key: malformed %s", name)
+		}
+	}
+
+	counter, isHOTP, err := readCounter(reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal key")
+	}
+
+	if reader.Len() > 0 {
+		return errors.Errorf("failed to unmarshal key: %d bytes of trailing data", reader.Len())
+	}
+
+	if period > math.MaxUint32 || skew > math.MaxUint32 {
+		return errors.New("failed to unmarshal key: period or skew out of range")
+	}
+
+	createdTime := time.Time{}
+	if created != 0 {
+		createdTime = time.Unix(created, 0).UTC()
+	}
+
+	k.Secret = fields[2]
+	//nolint:exhaustruct // only the exported fields are stored
+	k.Options = Options{
+		AccountName: string(fields[1]),
+		Algorithm:   algo,
+		Created:     createdTime,
+		Digits:      Digits(header[2]),
+		Issuer:      string(fields[0]),
+		Period:      uint(period),
+		SecretSize:  uint(len(fields[2])),
+		Skew:        uint(skew),
+	}
+
+	if isHOTP {
+		k.Options.counter = &counter
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// appendLengthPrefixed appends the data prefixed with its length in uvarint.
+func appendLengthPrefixed(out, data []byte) []byte {
+	out = binary.AppendUvarint(out, uint64(len(data)))
+
+	return append(out, data...)
+}
+
+// readCounter reads the type of the OTP and the counter. It returns false as
+// the second value for TOTP.
+func readCounter(reader *bytes.Reader) (uint64, bool, error) {
+	otpType, err := reader.ReadByte()
+	if err != nil {
+		return 0, false, errors.Wrap(err, "truncated type")
+	}
+
+	switch otpType {
+	case keyBinaryTOTP:
+		return 0, false, nil
+	case keyBinaryHOTP:
+		counter, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return 0, false, errors.Wrap(err, "malformed counter")
+		}
+
+		return counter, true, nil
+	default:
+		return 0, false, errors.Errorf("unknown type %d", otpType)
+	}
+}
+
+// readLengthPrefixed reads the data prefixed with its length in uvarint.
+func readLengthPrefixed(reader *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read length")
+	}
+
+	if length > uint64(reader.Len()) {
+		return nil, errors.Errorf("truncated data. length %d exceeds the remaining %d bytes", length, reader.Len())
+	}
+
+	data := make([]byte, length)
+
+	_, err = io.ReadFull(reader, data)
+
+	return data, errors.Wrap(err, "failed to read data")
+}
+
+// unixOrZero returns the time in Unix seconds or 0 if the time is zero.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -0,0 +1,125 @@
+package totp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  Key.MarshalBinary() and Key.UnmarshalBinary()
+// ----------------------------------------------------------------------------
+
+func TestKey_MarshalBinary_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, algo := range []Algorithm{"MD5", "SHA1", "SHA256", "SHA512"} {
+		keyOrig, err := GenerateKey("Example.com", "alice@example.com",
+			WithAlgorithm(algo), WithDigits(DigitsEight), WithPeriod(300), WithSkew(2))
+		require.NoError(t, err, "failed to create key during test setup")
+
+		data, err := keyOrig.MarshalBinary()
+		require.NoError(t, err)
+
+		pemKey, err := keyOrig.PEM()
+		require.NoError(t, err)
+		require.Less(t, len(data), len(pemKey), "binary format should be smaller than PEM")
+
+		//nolint:exhaustruct // zero value is intentional
+		keyRecovered := Key{}
+
+		require.NoError(t, keyRecovered.UnmarshalBinary(data))
+		require.Equal(t, keyOrig.Secret, keyRecovered.Secret)
+		require.Empty(t, keyOrig.Options.Diff(keyRecovered.Options), "options should be the same")
+		require.Equal(t, keyOrig.Options.Created, keyRecovered.Options.Created, "creation time should be kept")
+		require.Equal(t, OTPTypeTOTP, keyRecovered.Type())
+	}
+}
+
+func TestKey_MarshalBinary_round_trip_created_and_counter(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenHOTPKeyFromURI("otpauth://hotp/Example.com:alice@example.com?" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=42")
+	require.NoError(t, err, "failed to create key during test setup")
+	require.True(t, key.Options.Created.IsZero(), "creation time should be unknown")
+
+	data, err := key.MarshalBinary()
+	require.NoError(t, err)
+
+	//nolint:exhaustruct // zero value is intentional
+	keyRecovered := Key{}
+
+	require.NoError(t, keyRecovered.UnmarshalBinary(data))
+	require.True(t, keyRecovered.Options.Created.IsZero(), "unknown creation time should stay zero")
+
+	counter, ok := keyRecovered.Counter()
+	require.True(t, ok, "HOTP key should be kept")
+	require.Equal(t, uint64(42), counter)
+}
+
+func TestKey_UnmarshalBinary_truncated(t *testing.T) {
+	t.Parallel()
+
+	keyOrig, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	data, err := keyOrig.MarshalBinary()
+	require.NoError(t, err)
+
+	for length := range len(data) {
+		//nolint:exhaustruct // zero value is intentional
+		key := Key{}
+
+		err := key.UnmarshalBinary(data[:length])
+
+		require.Error(t, err, "truncated data of %d bytes should return error", length)
+		require.Contains(t, err.Error(), "failed to unmarshal key")
+		require.Nil(t, key.Secret, "key should be untouched on error")
+	}
+}
+
+func TestKey_UnmarshalBinary_malformed(t *testing.T) {
+	t.Parallel()
+
+	keyOrig, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	data, err := keyOrig.MarshalBinary()
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		modify func([]byte) []byte
+		expect string
+	}{
+		{func(d []byte) []byte { return append(d, 0x00) }, "1 bytes of trailing data"},
+		{func(d []byte) []byte { d[0] = 99; return d }, "unsupported version 99"},
+		{func(d []byte) []byte { d[1] = 99; return d }, "invalid algorithm ID"},
+		{func(d []byte) []byte { d[len(d)-1] = 9; return d }, "unknown type 9"},
+	} {
+		//nolint:exhaustruct // zero value is intentional
+		key := Key{}
+
+		err := key.UnmarshalBinary(test.modify(append([]byte{}, data...)))
+		require.ErrorContains(t, err, test.expect)
+	}
+}
+
+func TestKey_MarshalBinary_bad_options(t *testing.T) {
+	t.Parallel()
+
+	keyOrig, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	keyBad := *keyOrig
+	keyBad.Options.Algorithm = "BAD"
+
+	_, err = keyBad.MarshalBinary()
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	keyBad = *keyOrig
+	keyBad.Options.Digits = 256
+
+	_, err = keyBad.MarshalBinary()
+	require.ErrorIs(t, err, ErrUnsupportedDigits)
+}
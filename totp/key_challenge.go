@@ -0,0 +1,153 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
+)
+
+// Challenge-response passcodes bind the passcode to a challenge given by the
+// server in addition to the time. A relayed passcode of a phishing site is then
+// useless for other challenges.
+//
+// The passcode is computed as RFC-4226 HOTP with the challenge appended to the
+// moving factor. Both parties must compute it as follows:
+//
+//	counter  = floor(unix time / period)  // see TimeToCounter()
+//	message  = counter (8 bytes, big-endian) || challenge (UTF-8 bytes as is)
+//	hash     = HMAC-<algorithm>(secret, message)
+//	offset   = hash[len(hash)-1] & 0x0f
+//	binary   = (hash[offset] & 0x7f) << 24 | hash[offset+1] << 16 | hash[offset+2] << 8 | hash[offset+3]
+//	passcode = binary mod 10^digits, zero-padded to the digits
+//
+// The offset and binary are the dynamic truncation of RFC-4226. See
+// DynamicTruncate(). Note that the hash of MD5 may be too short for the offset,
+// which is an error. The period, digits and algorithm are the ones of the key
+// options.
+//
+// If the key is set to the Steam encoding by WithValidationEncoder(), the binary
+// is encoded in the Steam alphabet instead of the decimal digits, the same as the
+// regular passcodes of the key. See encodeSteam().
+
+// steamAlphabet is the alphabet of the Steam Guard codes (otp.EncoderSteam).
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+// PassCodeChallenge generates a challenge-response passcode for the given
+// challenge at the current time. See ValidateChallenge() for validation.
+//
+// The challenge must not be empty. Otherwise the passcode would be a plain TOTP
+// passcode.
+func (k *Key) PassCodeChallenge(challenge string) (string, error) {
+	if challenge == "" {
+		return "", errors.New("empty challenge")
+	}
+
+	if !k.Options.Algorithm.IsSupported() {
+		return "", errors.Wrapf(ErrUnsupportedAlgorithm, "failed to generate passcode: %q", k.Options.Algorithm)
+	}
+
+	if err := k.Options.checkTOTP(); err != nil {
+		return "", errors.Wrap(err, "failed to generate passcode")
+	}
+
+	counter := TimeToCounter(k.Options.timeNow(), k.Options.Period)
+
+	passcode, err := k.challengeCode(counter, challenge)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate passcode")
+	}
+
+	if k.Options.checksum {
+		passcode = appendChecksum(passcode)
+	}
+
+	return passcode, nil
+}
+
+// ValidateChallenge returns true if the given passcode is valid for the given
+// challenge at the current time. The skew of the key options is allowed.
+//
+// It returns false if the challenge is empty. See PassCodeChallenge() for the
+// computation of the passcode.
+func (k *Key) ValidateChallenge(passcode, challenge string) bool {
+	if challenge == "" || !k.Options.Algorithm.IsSupported() || k.Options.Skew > math.MaxInt32 ||
+		k.Options.checkTOTP() != nil {
+		return false
+	}
+
+	passcode, err := k.Options.preparePassCode(passcode)
+	if err != nil {
+		return false
+	}
+
+	validationTime := shiftSkewCenter(k.Options.timeNow(), k.Options)
+	period := time.Duration(periodOrDefault(k.Options)) * time.Second
+	skew := int(k.Options.Skew)
+
+	for offset := -skew; offset <= skew; offset++ {
+		windowTime := validationTime.Add(time.Duration(offset) * period)
+		if windowTime.Unix() < 0 {
+			continue
+		}
+
+		expect, err := k.challengeCode(TimeToCounter(windowTime, k.Options.Period), challenge)
+		if err != nil {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expect), []byte(passcode)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// challengeCode computes the challenge-response passcode of the given counter
+// and challenge. The algorithm must be supported.
+func (k *Key) challengeCode(counter uint64, challenge string) (string, error) {
+	const lenCounter = 8
+
+	message := make([]byte, lenCounter, lenCounter+len(challenge))
+	binary.BigEndian.PutUint64(message, counter)
+	message = append(message, challenge...)
+
+	mac := hmac.New(k.Options.Algorithm.OTPAlgorithm().Hash, k.PepperedSecret())
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	value, err := DynamicTruncate(sum)
+	if err != nil {
+		return "", err
+	}
+
+	digits := k.Options.Digits.OTPDigits()
+
+	if k.Options.encoder == otp.EncoderSteam {
+		return encodeSteam(value, digits.Length()), nil
+	}
+
+	modulo := uint32(math.Pow10(digits.Length()))
+
+	return digits.Format(int32(value % modulo)), nil //nolint:gosec // less than 10^8, no overflow
+}
+
+// encodeSteam encodes the truncated value in the Steam alphabet of the given
+// length, the least significant character first. It is the same as the Steam
+// encoder of the underlying OTP library.
+func encodeSteam(value uint32, length int) string {
+	radix := uint32(len(steamAlphabet))
+	encoded := make([]byte, length)
+
+	for index := range encoded {
+		encoded[index] = steamAlphabet[value%radix]
+		value /= radix
+	}
+
+	return string(encoded)
+}
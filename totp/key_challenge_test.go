@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // SHA1 is used in the RFC test vector
+	"strings"
+	"testing"
+	"time"
+
+	origOtp "github.com/pquerna/otp"
+	origTotp "github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  Key.PassCodeChallenge() and Key.ValidateChallenge()
+// ----------------------------------------------------------------------------
+
+func TestKey_PassCodeChallenge_golden(t *testing.T) {
+	t.Parallel()
+
+	// RFC-6238 test secret for SHA1
+	key := &Key{
+		Secret: Secret("12345678901234567890"),
+		//nolint:exhaustruct // allow missing fields
+		Options: Options{
+			Algorithm: Algorithm("SHA1"),
+			Digits:    DigitsEight,
+			Period:    30,
+			now:       time.Unix(59, 0),
+		},
+	}
+
+	// Plain TOTP of RFC-6238 at T=59 is "94287082". The challenge must change it.
+	passcode, err := key.PassCodeChallenge("transfer 100 USD to bob")
+	require.NoError(t, err)
+	require.Len(t, passcode, 8)
+	require.NotEqual(t, "94287082", passcode)
+	require.Equal(t, "81429799", passcode, "the challenge-mixing scheme should not change")
+}
+
+func TestKey_ValidateChallenge(t *testing.T) {
+	t.Parallel()
+
+	for _, algo := range []Algorithm{"MD5", "SHA1", "SHA256", "SHA512"} {
+		key, err := GenerateKey("Example.com", "alice@example.com",
+			WithAlgorithm(algo), WithNow(time.Unix(1700000000, 0)))
+		require.NoError(t, err, "failed to create key during test setup")
+
+		// Fixed secret so that the HMAC of MD5 is in range. See DynamicTruncate().
+		key.Secret = Secret("12345678901234567890")
+
+		passcode, err := key.PassCodeChallenge("nonce-1")
+		require.NoError(t, err)
+
+		require.True(t, key.ValidateChallenge(passcode, "nonce-1"), "algorithm: %s", algo)
+		require.False(t, key.ValidateChallenge(passcode, "nonce-2"),
+			"passcode should be bound to the challenge. algorithm: %s", algo)
+		require.False(t, key.ValidateChallenge(passcode, ""), "empty challenge should be rejected")
+		require.False(t, key.ValidateChallenge("abcdef", "nonce-1"))
+
+		// Skew
+		keyNext := *key
+		keyNext.Options.now = key.Options.now.Add(30 * time.Second)
+		require.True(t, keyNext.ValidateChallenge(passcode, "nonce-1"), "skew should be allowed")
+
+		keyNext.Options.Skew = 0
+		require.False(t, keyNext.ValidateChallenge(passcode, "nonce-1"))
+	}
+}
+
+func TestKey_PassCodeChallenge_encoder(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithValidationEncoder(origOtp.EncoderSteam), WithNow(time.Unix(1700000000, 0)))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	passcode, err := key.PassCodeChallenge("nonce-1")
+	require.NoError(t, err)
+	require.Len(t, passcode, 6)
+	require.Empty(t, strings.Trim(passcode, steamAlphabet),
+		"challenge passcode should be in the same alphabet as the regular passcodes")
+
+	require.True(t, key.ValidateChallenge(passcode, "nonce-1"))
+	require.False(t, key.ValidateChallenge(passcode, "nonce-2"))
+}
+
+func TestEncodeSteam(t *testing.T) {
+	t.Parallel()
+
+	// Should be the same as the Steam encoder of the underlying OTP library
+	secret := Secret("12345678901234567890")
+	now := time.Unix(59, 0)
+
+	expect, err := origTotp.GenerateCodeCustom(secret.Base32(), now, origTotp.ValidateOpts{
+		Period:    30,
+		Digits:    origOtp.DigitsSix,
+		Algorithm: origOtp.AlgorithmSHA1,
+		Encoder:   origOtp.EncoderSteam,
+	})
+	require.NoError(t, err)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1}) // counter of T=59
+	value, err := DynamicTruncate(mac.Sum(nil))
+	require.NoError(t, err)
+
+	require.Equal(t, expect, encodeSteam(value, 6))
+}
+
+func TestKey_PassCodeChallenge_errors(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	_, err = key.PassCodeChallenge("")
+	require.ErrorContains(t, err, "empty challenge")
+
+	key.Options.Algorithm = Algorithm("UNKNOWN")
+
+	_, err = key.PassCodeChallenge("nonce")
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	require.False(t, key.ValidateChallenge("123456", "nonce"))
+}
@@ -1,10 +1,14 @@
 package totp
 
 import (
+	"bytes"
 	"crypto/ecdh"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/pem"
 	"math"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -38,6 +42,32 @@ func TestGenerateKey_bad_algorithm(t *testing.T) {
 		"error message should contain the underlying error reason")
 }
 
+func TestGenerateKey_md5_default_secret_size(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithAlgorithm("MD5"))
+	require.NoError(t, err)
+
+	require.Equal(t, OptionSecretSizeDefaultMD5, key.Options.SecretSize)
+	require.Equal(t, SecretSizeMin, key.Secret.Len(),
+		"MD5 key should have exactly the minimum secret size by default")
+
+	// The URI should pass the check and be importable
+	uri := URI(key.URI())
+	require.NoError(t, uri.Check())
+
+	keyImported, err := GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+	require.Equal(t, Algorithm("MD5"), keyImported.Options.Algorithm)
+	require.True(t, key.Secret.Equal(keyImported.Secret))
+
+	// Custom secret size after the algorithm option takes precedence
+	key, err = GenerateKey("Example.com", "alice@example.com",
+		WithAlgorithm("MD5"), WithSecretSize(32))
+	require.NoError(t, err)
+	require.Equal(t, 32, key.Secret.Len())
+}
+
 func TestGenerateKey_missing_issuer(t *testing.T) {
 	t.Parallel()
 
@@ -146,6 +176,26 @@ func TestGenerateKeyCustom_wrong_digits(t *testing.T) {
 	require.Contains(t, err.Error(), "failed to decode base32 string")
 }
 
+func TestGenerateKeyCustom_unsupported_digits(t *testing.T) {
+	t.Parallel()
+
+	opts, err := NewOptions("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	opts.Digits = 7
+
+	key, err := GenerateKeyCustom(*opts)
+
+	require.ErrorIs(t, err, ErrUnsupportedDigits, "7 digits should not silently fall back to 6")
+	require.Contains(t, err.Error(), "7. it should be 6 or 8")
+	require.Nil(t, key)
+
+	key, err = GenerateKey("Example.com", "alice@example.com", WithDigits(7))
+
+	require.ErrorIs(t, err, ErrUnsupportedDigits)
+	require.Nil(t, key)
+}
+
 // ----------------------------------------------------------------------------
 //  GenerateKeyPEM() : Deprecated function. This test will be removed in v1.0.0
 // ----------------------------------------------------------------------------
@@ -174,6 +224,66 @@ gX7ff3VlT4sCakCjQH69ZQxTbzs=
 	assert.Equal(t, "8", key.Options.Digits.String())
 }
 
+// ----------------------------------------------------------------------------
+//  CheckPEM()
+// ----------------------------------------------------------------------------
+
+func TestCheckPEM(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	pemKey, err := key.PEM()
+	require.NoError(t, err)
+
+	require.NoError(t, CheckPEM(pemKey))
+	require.NoError(t, CheckPEM("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"+pemKey),
+		"non-TOTP blocks should be skipped")
+
+	err = CheckPEM("not a PEM")
+	require.ErrorContains(t, err, "no TOTP secret key block found")
+}
+
+func TestCheckPEM_problems(t *testing.T) {
+	t.Parallel()
+
+	pemData := `
+-----BEGIN TOTP SECRET KEY-----
+Algorithm: SHA3
+Created: yesterday
+Digits: 7
+Issuer: Example.com
+Period: 0
+Secret Size: twenty
+
+gX7ff3VlT4s=
+-----END TOTP SECRET KEY-----`
+
+	err := CheckPEM(pemData)
+	require.Error(t, err)
+
+	for _, problem := range []string{
+		"invalid PEM: 8 problem(s) found",
+		`malformed header: Secret Size: "twenty"`,
+		"missing header: Skew",
+		"missing header: Account Name",
+		`unsupported algorithm: "SHA3"`,
+		"unsupported digits: 7",
+		"zero period",
+		`malformed header: Created: "yesterday"`,
+		"secret: 8 bytes. it should be at least 16 bytes",
+	} {
+		require.Contains(t, err.Error(), problem)
+	}
+
+	// Headerless PEM is loadable but not sound
+	err = CheckPEM("-----BEGIN TOTP SECRET KEY-----\n" +
+		"gwlv1KkBd3UgVs+EVbHhT80QfVPMAowPR0wBKLqcHyY=\n" +
+		"-----END TOTP SECRET KEY-----\n")
+	require.ErrorContains(t, err, "invalid PEM: 7 problem(s) found")
+}
+
 // ----------------------------------------------------------------------------
 //  GenKeyFromPEM()
 // ----------------------------------------------------------------------------
@@ -205,6 +315,29 @@ AIU+2GKjyT3iMuzZxxFxPFMCAwEAAQ==
 	require.Nil(t, key)
 }
 
+func TestGenKeyFromPEM_short_secret(t *testing.T) {
+	t.Parallel()
+
+	pemData := `
+-----BEGIN TOTP SECRET KEY-----
+Account Name: alice@example.com
+Algorithm: SHA1
+Digits: 6
+Issuer: Example.com
+Period: 30
+Secret Size: 20
+Skew: 0
+
+gX7ff3VlT4s=
+-----END TOTP SECRET KEY-----`
+
+	key, err := GenKeyFromPEM(pemData)
+
+	require.ErrorIs(t, err, ErrSecretTooShort, "truncated secret should be caught on import")
+	require.Contains(t, err.Error(), "invalid secret in the PEM block")
+	require.Nil(t, key)
+}
+
 func TestGenKeyFromPEM_multiple_pem_keys(t *testing.T) {
 	t.Parallel()
 
@@ -274,13 +407,153 @@ func TestGenerateKeyURI_error_msg(t *testing.T) {
 	}
 
 	key2, err := GenerateKeyURI("otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-		"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+		"digits=8&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
 
 	require.Error(t, err, "missing issuer and account name should return error")
 	require.Nil(t, key2)
 	require.Contains(t, err.Error(), "failed to generate key")
 }
 
+// ----------------------------------------------------------------------------
+//  GenKeyFromURI()
+// ----------------------------------------------------------------------------
+
+func TestGenKeyFromURI_lenient_issuer(t *testing.T) {
+	t.Parallel()
+
+	// Example.org vs Example.com
+	origin := "otpauth://totp/Example.org:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	key, err := GenKeyFromURI(origin)
+
+	require.Error(t, err, "unmatched issuer should return error by default")
+	require.Nil(t, key)
+
+	key, err = GenKeyFromURI(origin, WithLenientIssuer())
+
+	require.NoError(t, err, "unmatched issuer should be accepted with WithLenientIssuer")
+	require.Equal(t, "Example.org", key.Options.Issuer)
+	require.Equal(t, "alice@example.com", key.Options.AccountName)
+}
+
+func TestGenKeyFromURI_bad_option(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	key, err := GenKeyFromURI(origin, WithAlgorithm(Algorithm("BADALGO")))
+
+	require.Error(t, err, "invalid option should return error")
+	require.Contains(t, err.Error(), "failed to apply custom options")
+	require.Nil(t, key)
+}
+
+// ----------------------------------------------------------------------------
+//  GenKeyFromURIStrict()
+// ----------------------------------------------------------------------------
+
+func TestGenKeyFromURIStrict(t *testing.T) {
+	t.Parallel()
+
+	const base = "otpauth://totp/Example.com:alice@example.com?issuer=Example.com&" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	for _, query := range []string{"", "&algorithm=sha256&digits=8&period=60", "&period=15", "&period=300"} {
+		key, err := GenKeyFromURIStrict(base + query)
+
+		require.NoError(t, err, "query: %q", query)
+		require.Equal(t, "QF7N673VMVHYWATKICRUA7V5MUGFG3Z3", key.Secret.Base32())
+	}
+
+	for _, test := range []struct {
+		query  string
+		expect error
+	}{
+		{"&algorithm=BADALGO", ErrUnsupportedAlgorithm},
+		{"&digits=12", ErrUnsupportedDigits},
+		{"&digits=6x", ErrUnsupportedDigits},
+		{"&period=0", ErrUnsupportedPeriod},
+		{"&period=14", ErrUnsupportedPeriod},
+		{"&period=301", ErrUnsupportedPeriod},
+		{"&period=86400", ErrUnsupportedPeriod},
+	} {
+		key, err := GenKeyFromURIStrict(base + test.query)
+
+		require.ErrorIs(t, err, test.expect, "query: %q", test.query)
+		require.Nil(t, key)
+	}
+
+	// The lenient one stays for compatibility
+	key, err := GenKeyFromURI(base + "&period=86400")
+	require.NoError(t, err)
+	require.Equal(t, uint(86400), key.Options.Period)
+
+	// Other problems are checked the same as GenKeyFromURI()
+	key, err = GenKeyFromURIStrict("otpauth://hotp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+	require.Error(t, err)
+	require.Nil(t, key)
+}
+
+// ----------------------------------------------------------------------------
+//  NewKeyFromOTP()
+// ----------------------------------------------------------------------------
+
+func TestNewKeyFromOTP(t *testing.T) {
+	t.Parallel()
+
+	otpKey, err := origTotp.Generate(origTotp.GenerateOpts{
+		Issuer:      "Example.com",
+		AccountName: "alice@example.com",
+		Period:      60,
+		SecretSize:  20,
+		Secret:      nil,
+		Digits:      origOtp.DigitsEight,
+		Algorithm:   origOtp.AlgorithmSHA256,
+		Rand:        nil,
+	})
+	require.NoError(t, err)
+
+	key, err := NewKeyFromOTP(otpKey)
+	require.NoError(t, err)
+
+	require.Equal(t, "Example.com", key.Options.Issuer)
+	require.Equal(t, "alice@example.com", key.Options.AccountName)
+	require.Equal(t, uint(60), key.Options.Period)
+	require.Equal(t, DigitsEight, key.Options.Digits)
+	require.Equal(t, Algorithm("SHA256"), key.Options.Algorithm)
+	require.Equal(t, otpKey.Secret(), key.Secret.Base32())
+
+	// Both should agree on the passcode
+	now := time.Now()
+
+	expect, err := origTotp.GenerateCodeCustom(otpKey.Secret(), now, origTotp.ValidateOpts{
+		Period:    60,
+		Skew:      0,
+		Digits:    origOtp.DigitsEight,
+		Algorithm: origOtp.AlgorithmSHA256,
+	})
+	require.NoError(t, err)
+	require.True(t, key.ValidateCustom(expect, now))
+}
+
+func TestNewKeyFromOTP_unsupported(t *testing.T) {
+	t.Parallel()
+
+	key, err := NewKeyFromOTP(nil)
+	require.ErrorContains(t, err, "nil OTP key")
+	require.Nil(t, key)
+
+	hotpKey, err := origOtp.NewKeyFromURL(
+		"otpauth://hotp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=1")
+	require.NoError(t, err)
+
+	key, err = NewKeyFromOTP(hotpKey)
+	require.ErrorContains(t, err, `unsupported OTP type "hotp"`)
+	require.Nil(t, key)
+}
+
 // ----------------------------------------------------------------------------
 //  Key.QRCode()
 // ----------------------------------------------------------------------------
@@ -323,46 +596,1282 @@ func TestKey_PEM(t *testing.T) {
 	require.Error(t, err, "missing key should return error")
 	require.Contains(t, err.Error(), "failed to encode key to PEM")
 	require.Empty(t, pemOut)
+
+	pemBytes, err := key.PEMBytesTrimmed()
+
+	require.Error(t, err, "missing key should return error")
+	require.Contains(t, err.Error(), "failed to encode key to PEM")
+	require.Nil(t, pemBytes)
 }
 
-// ============================================================================
-//  Tests for fixed issues
-// ============================================================================
-//  These tests reproduce the issues and should pass if the issue is fixed.
+// ----------------------------------------------------------------------------
+//  Key.PEMBytes() and Key.PEMBytesTrimmed()
+// ----------------------------------------------------------------------------
 
-// Issue #42.
-// If `Period` is set short with `Skew=0`, the passcode validation often fails.
-func TestKey_skew_as_one(t *testing.T) {
+func TestKey_PEMBytes_trailing_newline(t *testing.T) {
 	t.Parallel()
 
-	key, err := GenerateKey("dummy issuer", "dummy account")
-	require.NoError(t, err, "failed to generate TOTP key during test setup")
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
 
-	key.Options.Period = 3              // set short to 3 seconds
-	timeSleep := key.Options.Period - 1 // sleep almost last-minute
-	numValid := 0
-	numIterations := 10
+	pemStr, err := key.PEM()
+	require.NoError(t, err)
 
-	if timeSleep > uint(math.MaxInt) {
-		t.Fatalf("output length too large: %d", timeSleep)
+	pemBytes, err := key.PEMBytes()
+	require.NoError(t, err)
+	require.Equal(t, pemStr, string(pemBytes))
+	require.True(t, bytes.HasSuffix(pemBytes, []byte("-----END TOTP SECRET KEY-----\n")))
+
+	pemTrimmed, err := key.PEMBytesTrimmed()
+	require.NoError(t, err)
+	require.True(t, bytes.HasSuffix(pemTrimmed, []byte("-----END TOTP SECRET KEY-----")))
+	require.Equal(t, pemBytes[:len(pemBytes)-1], pemTrimmed)
+
+	// Still decodable
+	keyImported, err := GenKeyFromPEM(string(pemTrimmed))
+	require.NoError(t, err)
+	require.Equal(t, key.Secret, keyImported.Secret)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.URI()
+// ----------------------------------------------------------------------------
+
+func TestKey_URI_issuer_not_in_path(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithIssuerInPath(false))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	uri := URI(key.URI())
+
+	require.Equal(t, "/alice@example.com", uri.Path(), "label should contain the account name only")
+	require.Empty(t, uri.IssuerFromPath())
+	require.NoError(t, uri.Check())
+
+	// Round-trip
+	keyImported, err := GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+
+	require.Equal(t, "Example.com", keyImported.Options.Issuer, "issuer should be recovered from the query")
+	require.Equal(t, "alice@example.com", keyImported.Options.AccountName)
+	require.Equal(t, key.Secret, keyImported.Secret)
+}
+
+func TestKey_URI_minimal(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithMinimalURI())
+	require.NoError(t, err)
+
+	uri := URI(key.URI())
+
+	require.Empty(t, uri.Algorithm(), "default algorithm should be omitted")
+	require.Zero(t, uri.Digits(), "default digits should be omitted")
+	require.Zero(t, uri.Period(), "default period should be omitted")
+
+	keyFull := *key
+	keyFull.Options.minimalURI = false
+
+	require.Less(t, len(key.URI()), len(keyFull.URI()), "minimal URI should be shorter")
+
+	// Round-trip
+	keyImported, err := GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+	require.Equal(t, OptionAlgorithmDefault, keyImported.Options.Algorithm)
+	require.Equal(t, OptionDigitsDefault, keyImported.Options.Digits)
+	require.Equal(t, OptionPeriodDefault, keyImported.Options.Period)
+	require.True(t, key.Secret.Equal(keyImported.Secret))
+
+	// Non-default values are kept
+	key, err = GenerateKey("Example.com", "alice@example.com",
+		WithMinimalURI(), WithDigits(DigitsEight), WithPeriod(60))
+	require.NoError(t, err)
+
+	uri = URI(key.URI())
+
+	require.Empty(t, uri.Algorithm())
+	require.Equal(t, uint(8), uri.Digits())
+	require.Equal(t, uint(60), uri.Period())
+
+	keyImported, err = GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+	require.Equal(t, DigitsEight, keyImported.Options.Digits)
+	require.Equal(t, uint(60), keyImported.Options.Period)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.URIValues()
+// ----------------------------------------------------------------------------
+
+func TestKey_URIValues(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	values := key.URIValues()
+
+	require.Equal(t, key.Secret.Base32(), values.Get("secret"))
+	require.Equal(t, "Example.com", values.Get("issuer"))
+	require.Equal(t, "SHA1", values.Get("algorithm"))
+	require.Equal(t, "6", values.Get("digits"))
+	require.Equal(t, "30", values.Get("period"))
+
+	require.Equal(t, key.URI(), key.URIWithValues(values), "URI should be built on the values")
+
+	// Custom parameter
+	values.Set("image", "https://example.com/logo.png")
+
+	uri := key.URIWithValues(values)
+	require.Contains(t, uri, "image=https%3A%2F%2Fexample.com%2Flogo.png")
+	require.NotContains(t, key.URI(), "image=", "values should be a copy")
+
+	keyImported, err := GenKeyFromURI(uri)
+	require.NoError(t, err, "custom parameters should not break the import")
+	require.True(t, key.Secret.Equal(keyImported.Secret))
+
+	// Minimal
+	key.Options.minimalURI = true
+
+	require.Len(t, key.URIValues(), 3, "only the issuer, secret and creation time should remain")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.SavePEM()
+// ----------------------------------------------------------------------------
+
+func TestKey_SavePEM_golden(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	pathFile := filepath.Join(t.TempDir(), "secret.pem")
+
+	require.NoError(t, key.SavePEM(pathFile, 0))
+
+	info, err := os.Stat(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, filePermDefault, info.Mode().Perm(), "zero permission should default to 0o600")
+
+	pemData, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+
+	keyLoaded, err := GenKeyFromPEM(string(pemData))
+	require.NoError(t, err)
+	require.Equal(t, key.Secret, keyLoaded.Secret)
+	require.Equal(t, key.Options.Issuer, keyLoaded.Options.Issuer)
+}
+
+func TestKey_SavePEM_write_error(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	err = key.SavePEM(filepath.Join(t.TempDir(), "missing", "secret.pem"), 0o600)
+
+	require.Error(t, err, "missing directory should return error")
+	require.Contains(t, err.Error(), "failed to write PEM file")
+}
+
+//nolint:paralleltest // disable parallel test due to monkey patching during test
+func TestKey_SavePEM_encode_error(t *testing.T) {
+	// Backup and defer restore
+	oldPemEncodeToMemory := pemEncodeToMemory
+	defer func() {
+		pemEncodeToMemory = oldPemEncodeToMemory
+	}()
+
+	// Mock pemEncodeToMemory to force return nil as an error
+	pemEncodeToMemory = func(_ *pem.Block) []byte {
+		return nil
 	}
 
-	// If skew is set to 0, the validation fails 60-70% of the time.
-	for range numIterations {
-		passCode, err := key.PassCode()
-		require.NoError(t, err, "failed to generate passcode")
+	//nolint:exhaustruct // disable exhaust struct linter due to test
+	key := Key{}
+	pathFile := filepath.Join(t.TempDir(), "secret.pem")
 
-		// Sleep to validate passcode with an almost last-minute deadline.
-		time.Sleep(time.Second * time.Duration(timeSleep))
+	err := key.SavePEM(pathFile, 0o600)
 
-		if ok := key.Validate(passCode); ok {
-			numValid++
-		}
+	require.Error(t, err, "encode error should return error")
+	require.Contains(t, err.Error(), "failed to encode key to PEM")
+	require.NoFileExists(t, pathFile)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.SecretQRCode()
+// ----------------------------------------------------------------------------
+
+func TestKey_SecretQRCode(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	qrSecret, err := key.SecretQRCode(FixLevelDefault)
+	require.NoError(t, err)
+	require.Equal(t, key.Secret.Base32(), qrSecret.URI.String(), "it should hold the secret only")
+
+	qrURI, err := key.QRCode(FixLevelDefault)
+	require.NoError(t, err)
+
+	matrixSecret, err := qrSecret.Matrix()
+	require.NoError(t, err)
+
+	matrixURI, err := qrURI.Matrix()
+	require.NoError(t, err)
+
+	require.Less(t, len(matrixSecret), len(matrixURI), "secret only QR code should be smaller")
+
+	_, err = key.SecretQRCode(FixLevel(99))
+	require.ErrorContains(t, err, "unsupported fix level")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.AdjacentPassCodes()
+// ----------------------------------------------------------------------------
+
+func TestKey_AdjacentPassCodes(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(fixedTime))
+	require.NoError(t, err)
+
+	prev, cur, next, err := key.AdjacentPassCodes()
+	require.NoError(t, err)
+
+	period := time.Duration(key.Options.Period) * time.Second
+
+	for index, test := range []struct {
+		actual  string
+		genTime time.Time
+	}{
+		{prev, fixedTime.Add(-period)},
+		{cur, fixedTime},
+		{next, fixedTime.Add(period)},
+	} {
+		expect, err := key.PassCodeCustom(test.genTime)
+		require.NoError(t, err)
+		require.Equal(t, expect, test.actual, "test #%d failed", index+1)
 	}
+}
 
-	expect := numIterations
-	actual := numValid
+func TestKey_AdjacentPassCodes_error(t *testing.T) {
+	t.Parallel()
 
-	require.Equal(t, expect, actual,
-		"not all generated passcodes are valid")
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(time.Unix(10, 0)))
+	require.NoError(t, err)
+
+	_, _, _, err = key.AdjacentPassCodes()
+	require.ErrorContains(t, err, "before the Unix epoch")
+
+	key.Options.Period = 0
+
+	prev, cur, next, err := key.AdjacentPassCodes()
+	require.ErrorContains(t, err, "period is zero")
+	require.Empty(t, prev+cur+next)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.Age()
+// ----------------------------------------------------------------------------
+
+func TestKey_Age(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(created))
+	require.NoError(t, err, "failed to create key during test setup")
+	require.Equal(t, created, key.Options.Created, "creation time should be set on generation")
+
+	key.Options.now = created.Add(48 * time.Hour)
+	require.Equal(t, 48*time.Hour, key.Age())
+
+	// PEM round-trip
+	pemKey, err := key.PEM()
+	require.NoError(t, err)
+	require.Contains(t, pemKey, "Created: 2024-01-02T03:04:05Z")
+
+	keyImported, err := GenKeyFromPEM(pemKey)
+	require.NoError(t, err)
+	require.Equal(t, created, keyImported.Options.Created)
+	require.Positive(t, keyImported.Age())
+
+	// URI round-trip
+	require.Contains(t, key.URI(), "created=2024-01-02T03%3A04%3A05Z")
+
+	keyImported, err = GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+	require.Equal(t, created, keyImported.Options.Created)
+
+	// Compact URI round-trip
+	keyImported, err = GenKeyFromCompactURI(key.CompactURI())
+	require.NoError(t, err)
+	require.Equal(t, created, keyImported.Options.Created)
+
+	// Unknown creation time, such as the URIs of other apps
+	values := key.URIValues()
+	values.Del("created")
+
+	keyImported, err = GenKeyFromURI(key.URIWithValues(values))
+	require.NoError(t, err)
+	require.True(t, keyImported.Options.Created.IsZero(), "creation time from URI should be unknown")
+	require.Zero(t, keyImported.Age())
+
+	pemKey, err = keyImported.PEM()
+	require.NoError(t, err)
+	require.NotContains(t, pemKey, "Created:", "zero creation time should not be emitted")
+
+	keyImported, err = GenKeyFromPEM(pemKey)
+	require.NoError(t, err)
+	require.True(t, keyImported.Options.Created.IsZero())
+}
+
+// ----------------------------------------------------------------------------
+//  Key.CompatibleWith()
+// ----------------------------------------------------------------------------
+
+func TestKey_CompatibleWith(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	// Different issuer, account and secret
+	other, err := GenerateKey("Example.org", "bob@example.org", WithSkew(3))
+	require.NoError(t, err)
+	require.True(t, key.CompatibleWith(other))
+	require.True(t, other.CompatibleWith(key))
+
+	other.Options.Period = 0
+	require.True(t, key.CompatibleWith(other), "zero period should be the default")
+
+	other.Options.Algorithm = Algorithm("sha1")
+	require.True(t, key.CompatibleWith(other), "algorithm should be case-insensitive")
+
+	for _, mutate := range []func(opts *Options){
+		func(opts *Options) { opts.Algorithm = Algorithm("SHA256") },
+		func(opts *Options) { opts.Digits = DigitsEight },
+		func(opts *Options) { opts.Period = 60 },
+	} {
+		other := *key
+		mutate(&other.Options)
+
+		require.False(t, key.CompatibleWith(&other), "options: %+v", other.Options)
+	}
+
+	require.False(t, key.CompatibleWith(nil))
+	require.False(t, (*Key)(nil).CompatibleWith(key))
+}
+
+// ----------------------------------------------------------------------------
+//  Key.IsValid()
+// ----------------------------------------------------------------------------
+
+func TestKey_IsValid(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+	require.NoError(t, key.IsValid())
+
+	// Zero-value key
+	keyZero := new(Key)
+	require.ErrorIs(t, keyZero.IsValid(), ErrUnsupportedAlgorithm)
+
+	// Headerless PEM
+	keyPEM, err := GenKeyFromPEM("-----BEGIN TOTP SECRET KEY-----\n" +
+		"gwlv1KkBd3UgVs+EVbHhT80QfVPMAowPR0wBKLqcHyY=\n" +
+		"-----END TOTP SECRET KEY-----\n")
+	require.NoError(t, err)
+	require.ErrorIs(t, keyPEM.IsValid(), ErrUnsupportedAlgorithm)
+
+	// Short secret
+	keyShort := *key
+	keyShort.Secret = Secret("too short")
+
+	err = keyShort.IsValid()
+	require.ErrorIs(t, err, ErrSecretTooShort)
+	require.Contains(t, err.Error(), "invalid key: 9 bytes. it should be at least 16 bytes")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.NormalizeSecretSize()
+// ----------------------------------------------------------------------------
+
+func TestKey_NormalizeSecretSize(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	key.Secret, err = NewSecretBase32("QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+	require.NoError(t, err)
+	require.NotEqual(t, uint(20), key.Options.SecretSize, "sizes should drift before normalization")
+
+	key.NormalizeSecretSize()
+
+	require.Equal(t, uint(20), key.Options.SecretSize)
+
+	pemKey, err := key.PEM()
+	require.NoError(t, err)
+	require.Contains(t, pemKey, "Secret Size: 20")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.PassCodeSeries()
+// ----------------------------------------------------------------------------
+
+func TestKey_PassCodeSeries_golden(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenKeyFromURI("otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	start := time.Unix(1700000020, 0) // 10 seconds after the boundary
+	end := time.Unix(1700000100, 0)   // exactly on the boundary (excluded)
+
+	series, err := key.PassCodeSeries(start, end)
+	require.NoError(t, err)
+	require.Len(t, series, 2, "windows starting at 1700000040 and 1700000070 are expected")
+
+	for index, entry := range series {
+		expectTime := time.Unix(1700000040+int64(index*30), 0).UTC()
+		require.Equal(t, expectTime, entry.Time)
+
+		expectCode, err := key.PassCodeCustom(expectTime)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, entry.Code)
+	}
+
+	// Start on the boundary is included.
+	series, err = key.PassCodeSeries(time.Unix(1700000010, 0), time.Unix(1700000011, 0))
+	require.NoError(t, err)
+	require.Len(t, series, 1)
+}
+
+func TestKey_PassCodeSeries_bad_args(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	now := time.Now()
+
+	_, err = key.PassCodeSeries(now, now)
+	require.ErrorContains(t, err, "invalid time range")
+
+	key.Options.Period = 0
+
+	_, err = key.PassCodeSeries(now, now.Add(time.Minute))
+	require.ErrorContains(t, err, "period is zero")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.PassCodeWithOffset()
+// ----------------------------------------------------------------------------
+
+func TestKey_PassCodeWithOffset(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSkew(0))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	offset := 5 * time.Minute
+
+	passcode, err := key.PassCodeWithOffset(offset)
+	require.NoError(t, err)
+	require.True(t, key.ValidateCustom(passcode, time.Now().Add(offset)) ||
+		key.ValidateCustom(passcode, time.Now().Add(offset-time.Duration(key.Options.Period)*time.Second)),
+		"passcode should be valid for the shifted time")
+
+	for _, offset := range []time.Duration{
+		math.MinInt64, // before the Unix epoch
+		-time.Duration(time.Now().Unix()+60) * time.Second,
+	} {
+		passcode, err := key.PassCodeWithOffset(offset)
+
+		require.Error(t, err, "offset: %s", offset)
+		require.Contains(t, err.Error(), "offset out of range")
+		require.Empty(t, passcode)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Key.PassCodeValue()
+// ----------------------------------------------------------------------------
+
+func TestKey_PassCodeValue_golden(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors of RFC 6238, Appendix B
+	for _, test := range []struct {
+		unixTime int64
+		expect   uint32
+	}{
+		{59, 94287082},
+		{1111111109, 7081804}, // "07081804", leading zero is not kept
+		{1234567890, 89005924},
+	} {
+		//nolint:exhaustruct // allow missing fields
+		key := &Key{
+			Secret: Secret("12345678901234567890"),
+			Options: Options{
+				Algorithm: Algorithm("SHA1"),
+				Digits:    DigitsEight,
+				Period:    30,
+				now:       time.Unix(test.unixTime, 0),
+			},
+		}
+
+		value, err := key.PassCodeValue()
+		require.NoError(t, err)
+		require.Equal(t, test.expect, value, "time: %d", test.unixTime)
+	}
+}
+
+func TestKey_PassCodeValue_match_passcode(t *testing.T) {
+	t.Parallel()
+
+	for _, algo := range []string{"SHA1", "SHA256", "SHA512"} {
+		for _, digits := range []Digits{DigitsSix, DigitsEight} {
+			key, err := GenerateKey("Example.com", "alice@example.com",
+				WithAlgorithm(Algorithm(algo)), WithDigits(digits), WithNow(time.Unix(1700000000, 0)))
+			require.NoError(t, err)
+
+			value, err := key.PassCodeValue()
+			require.NoError(t, err)
+
+			passcode, err := key.PassCode()
+			require.NoError(t, err)
+
+			require.Equal(t, passcode, digits.OTPDigits().Format(int32(value)), //nolint:gosec // less than 10^8
+				"algorithm: %s, digits: %d", algo, digits)
+		}
+	}
+}
+
+func TestKey_PassCodeValue_md5(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	key := &Key{
+		Secret: Secret("12345678901234567890"),
+		Options: Options{
+			Algorithm: Algorithm("MD5"),
+			Digits:    DigitsSix,
+			Period:    30,
+			now:       time.Unix(59, 0), // the offset of the HMAC is 0
+		},
+	}
+
+	value, err := key.PassCodeValue()
+	require.NoError(t, err)
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+	require.Equal(t, passcode, DigitsSix.OTPDigits().Format(int32(value)), //nolint:gosec // less than 10^6
+		"should be the same as the passcode of the underlying OTP library")
+
+	// The offset of the HMAC is 14, out of range of the 16 bytes of MD5. Not
+	// compared with PassCode() since the underlying OTP library panics.
+	key.Options.now = time.Unix(89, 0)
+
+	value, err = key.PassCodeValue()
+	require.ErrorContains(t, err, "HMAC result too short")
+	require.Zero(t, value)
+}
+
+func TestKey_PassCodeValue_bad_options(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		opts   Options
+		expect string
+	}{
+		//nolint:exhaustruct // allow missing fields
+		{Options{Algorithm: "BADALGO", Digits: DigitsSix}, "unsupported algorithm"},
+		//nolint:exhaustruct // allow missing fields
+		{Options{Algorithm: "SHA1", Digits: Digits(7)}, "unsupported digits"},
+		//nolint:exhaustruct // allow missing fields
+		{Options{Algorithm: "SHA1", Digits: DigitsSix, now: time.Unix(-1, 0)}, "before the Unix epoch"},
+	} {
+		key := &Key{Secret: Secret("12345678901234567890"), Options: test.opts}
+
+		value, err := key.PassCodeValue()
+		require.ErrorContains(t, err, test.expect)
+		require.Zero(t, value)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Key.PassCodeWithTTL()
+// ----------------------------------------------------------------------------
+
+func TestKey_PassCodeWithTTL(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000020, 500_000_000) // 10.5 seconds after the boundary
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(fixedTime))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	code, ttl, err := key.PassCodeWithTTL()
+	require.NoError(t, err)
+	require.Equal(t, 19500*time.Millisecond, ttl)
+
+	expect, err := key.PassCodeCustom(fixedTime)
+	require.NoError(t, err)
+	require.Equal(t, expect, code)
+
+	// Current time
+	key.Options.now = time.Time{}
+
+	_, ttl, err = key.PassCodeWithTTL()
+	require.NoError(t, err)
+	require.Positive(t, ttl)
+	require.LessOrEqual(t, ttl, 30*time.Second)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.PrecomputeHashedCodes()
+// ----------------------------------------------------------------------------
+
+func TestKey_PrecomputeHashedCodes_golden(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenKeyFromURI("otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	hashFn := func(in []byte) []byte {
+		sum := sha256.Sum256(in)
+
+		return sum[:]
+	}
+
+	timeFrom := time.Unix(1700000000, 0)
+
+	hashed, err := key.PrecomputeHashedCodes(timeFrom, 3, hashFn)
+	require.NoError(t, err)
+	require.Len(t, hashed, 3)
+
+	for index, actual := range hashed {
+		passcode, err := key.PassCodeCustom(timeFrom.Add(time.Duration(index*30) * time.Second))
+		require.NoError(t, err)
+
+		require.Equal(t, hashFn([]byte(passcode)), actual, "window %d hash mismatch", index)
+	}
+}
+
+func TestKey_PrecomputeHashedCodes_bad_args(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	hashFn := func(in []byte) []byte { return in }
+
+	_, err = key.PrecomputeHashedCodes(time.Now(), 0, hashFn)
+	require.ErrorContains(t, err, "invalid number of windows: 0")
+
+	_, err = key.PrecomputeHashedCodes(time.Now(), 1, nil)
+	require.ErrorContains(t, err, "hash function is nil")
+
+	key.Options.Period = 0
+
+	_, err = key.PrecomputeHashedCodes(time.Now(), 1, hashFn)
+	require.ErrorContains(t, err, "period is zero")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateAppended()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateAppended(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(time.Unix(1700000000, 0)))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	pin, ok := key.ValidateAppended("1234"+passcode, 4)
+	require.True(t, ok)
+	require.Equal(t, "1234", pin)
+
+	pin, ok = key.ValidateAppended("secret-pin"+passcode, -1)
+	require.True(t, ok, "any length of PIN should be accepted if pinLen is negative")
+	require.Equal(t, "secret-pin", pin)
+
+	pin, ok = key.ValidateAppended(passcode, -1)
+	require.True(t, ok, "empty PIN should be accepted if pinLen is negative")
+	require.Empty(t, pin)
+
+	for _, test := range []struct {
+		input  string
+		pinLen int
+	}{
+		{passcode[1:], -1},     // shorter than the passcode
+		{"123" + passcode, 4},  // PIN length mismatch
+		{"1234" + "abcdef", 4}, // invalid passcode
+		{passcode + "1234", 4}, // PIN after the passcode
+		{"", 0},                // empty
+	} {
+		pin, ok := key.ValidateAppended(test.input, test.pinLen)
+
+		require.False(t, ok, "input: %q, pinLen: %d", test.input, test.pinLen)
+		require.Empty(t, pin)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateBatch()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateBatch(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSkew(0))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	time1 := time.Unix(1700000000, 0)
+	time2 := time1.Add(time.Hour)
+
+	code1, err := key.PassCodeCustom(time1)
+	require.NoError(t, err)
+
+	code2, err := key.PassCodeCustom(time2)
+	require.NoError(t, err)
+
+	items := []BatchItem{
+		{Code: code1, At: time1},
+		{Code: code2, At: time2},
+		{Code: code1, At: time2},
+		{Code: "abcdef", At: time1},
+		{Code: code2, At: time2.Add(29 * time.Second)},
+	}
+
+	results := key.ValidateBatch(items)
+
+	require.Len(t, results, len(items))
+
+	for index, item := range items {
+		require.Equal(t, key.ValidateCustom(item.Code, item.At), results[index],
+			"result should be the same as ValidateCustom. index: %d", index)
+	}
+
+	require.True(t, results[0])
+	require.True(t, results[1])
+	require.Empty(t, key.ValidateBatch(nil))
+
+	// Unsupported algorithm
+	key.Options.Algorithm = Algorithm("UNKNOWN")
+	require.Equal(t, []bool{false, false}, key.ValidateBatch(items[:2]))
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateWith()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateWith(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	// Old parameters
+	keyOld, err := GenerateKey("Example.com", "alice@example.com",
+		WithNow(fixedTime), WithAlgorithm(Algorithm("SHA1")), WithDigits(DigitsSix), WithPeriod(30))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	// New parameters with the same secret
+	keyNew := *keyOld
+	keyNew.Options.Algorithm = Algorithm("SHA256")
+	keyNew.Options.Digits = DigitsEight
+	keyNew.Options.Period = 60
+
+	codeOld, err := keyOld.PassCode()
+	require.NoError(t, err)
+
+	codeNew, err := keyNew.PassCode()
+	require.NoError(t, err)
+
+	require.False(t, keyNew.Validate(codeOld), "old passcode should not be valid with new parameters")
+
+	//nolint:exhaustruct // only the parameters to override
+	override := Options{
+		Algorithm: Algorithm("SHA1"),
+		Digits:    DigitsSix,
+		Period:    30,
+	}
+
+	require.True(t, keyNew.ValidateWith(codeOld, override))
+	require.False(t, keyNew.ValidateWith(codeNew, override))
+	require.Equal(t, Algorithm("SHA256"), keyNew.Options.Algorithm, "key should not be mutated")
+
+	// Zero values fall back to the key's
+	//nolint:exhaustruct // empty override
+	require.True(t, keyNew.ValidateWith(codeNew, Options{}))
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateE()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateE(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	isValid, err := key.ValidateE(passcode)
+	require.NoError(t, err)
+	require.True(t, isValid)
+
+	isValid, err = key.ValidateE(" " + passcode + "\n")
+	require.NoError(t, err, "surrounding spaces should be ignored")
+	require.True(t, isValid)
+
+	for _, test := range []struct {
+		passcode string
+		expect   error
+	}{
+		{"", ErrEmptyPasscode},
+		{"   ", ErrEmptyPasscode},
+		{"12345", ErrMalformedPasscode},
+		{"1234567", ErrMalformedPasscode},
+		{"12345a", ErrMalformedPasscode},
+		{"-12345", ErrMalformedPasscode},
+	} {
+		isValid, err := key.ValidateE(test.passcode)
+
+		require.ErrorIs(t, err, test.expect, "passcode: %q", test.passcode)
+		require.False(t, isValid)
+		require.False(t, key.Validate(test.passcode), "boolean API should return false as well")
+	}
+
+	// Misconfigured key
+	key.Options.Algorithm = "BAD"
+
+	isValid, err = key.ValidateE(passcode)
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	require.False(t, isValid)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateInt()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateInt(t *testing.T) {
+	t.Parallel()
+
+	// Test vector of RFC 6238, Appendix B. The passcode at the time is
+	// "07081804" in 8 digits and "081804" in 6 digits, both with a leading zero.
+	for _, test := range []struct {
+		digits Digits
+		code   int64
+	}{
+		{DigitsSix, 81804},
+		{DigitsEight, 7081804},
+	} {
+		//nolint:exhaustruct // allow missing fields
+		key := &Key{
+			Secret: Secret("12345678901234567890"),
+			Options: Options{
+				Algorithm: Algorithm("SHA1"),
+				Digits:    test.digits,
+				Period:    30,
+				now:       time.Unix(1111111109, 0),
+			},
+		}
+
+		require.True(t, key.ValidateInt(test.code),
+			"%d digits: code %d should be valid with the leading zero", test.digits, test.code)
+		require.False(t, key.ValidateInt(test.code+1), "wrong code should be invalid")
+		require.False(t, key.ValidateInt(-1), "negative value should be invalid")
+		require.False(t, key.ValidateInt(1234567890), "too long value should be invalid")
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateNotInHistory()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateNotInHistory(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(time.Unix(1700000000, 0)))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	require.True(t, key.ValidateNotInHistory(passcode, nil))
+	require.True(t, key.ValidateNotInHistory(passcode, []string{"123", "", "00000000"}))
+	require.False(t, key.ValidateNotInHistory(passcode, []string{"123", passcode}),
+		"passcode in the history should be rejected")
+	require.False(t, key.ValidateNotInHistory(" "+passcode, []string{passcode + "\n"}),
+		"surrounding spaces should be ignored")
+	require.False(t, key.ValidateNotInHistory("abc", nil), "invalid passcode should be rejected")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateTryAlgorithms()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateTryAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	for _, algo := range []Algorithm{"SHA1", "SHA256", "SHA512"} {
+		keyApp, err := GenerateKey("Example.com", "alice@example.com", WithAlgorithm(algo), WithSkew(0))
+		require.NoError(t, err, "failed to create key during test setup")
+
+		fixedTime := time.Unix(1700000000, 0)
+
+		passcode, err := keyApp.PassCodeCustom(fixedTime)
+		require.NoError(t, err)
+
+		// Imported key with the algorithm lost
+		keyImported := *keyApp
+		keyImported.Options.Algorithm = ""
+		keyImported.Options.now = fixedTime
+
+		actual, ok := keyImported.ValidateTryAlgorithms(passcode)
+		require.True(t, ok, "algorithm %s should be found", algo)
+		require.Empty(t, keyImported.Options.Algorithm, "key should not be modified")
+
+		// Another algorithm may match by chance. Either way the found one must
+		// generate the same passcode.
+		keyFound := *keyApp
+		keyFound.Options.Algorithm = actual
+
+		expect, err := keyFound.PassCodeCustom(fixedTime)
+		require.NoError(t, err)
+		require.Equal(t, expect, passcode, "found algorithm: %s, expected: %s", actual, algo)
+	}
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	algo, ok := key.ValidateTryAlgorithms("abcdef")
+	require.False(t, ok)
+	require.Empty(t, algo)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateVerbose()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateVerbose(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSkew(2))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	validationTime := time.Unix(1700000015, 0).UTC() // 5 seconds after the boundary
+
+	// Passcode of the previous window
+	passcode, err := key.PassCodeCustom(validationTime.Add(-30 * time.Second))
+	require.NoError(t, err)
+
+	results := key.validateVerbose(passcode, validationTime)
+	require.Len(t, results, 5, "it should evaluate all the windows within the skew")
+
+	for index, result := range results {
+		offset := index - 2
+
+		require.Equal(t, offset, result.Offset)
+		require.Equal(t, time.Unix(1700000010+int64(offset*30), 0).UTC(), result.Window)
+		require.Equal(t, offset == -1, result.Match, "only the previous window should match")
+	}
+
+	// Lenient input is normalized as Validate() does
+	keyLenient := *key
+	keyLenient.Options.lenientPassCode = true
+
+	results = keyLenient.validateVerbose(passcode[:3]+"-"+passcode[3:], validationTime)
+	require.Len(t, results, 5)
+	require.True(t, results[1].Match, "lenient passcode should match the previous window")
+
+	// Public method
+	results = key.ValidateVerbose("000000x")
+	require.Len(t, results, 5)
+
+	for _, result := range results {
+		require.False(t, result.Match)
+	}
+
+	// Unsupported algorithm
+	key.Options.Algorithm = "BAD"
+	require.Nil(t, key.ValidateVerbose(passcode))
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateResync()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateResync(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithPeriod(300), // long enough to not cross the window during the test
+		WithSkew(1),
+	)
+	require.NoError(t, err, "failed to create key during test setup")
+
+	period := 300 * time.Second
+
+	// Client clock is two periods ahead. Out of the skew.
+	passcodeAhead2, err := key.PassCodeCustom(time.Now().Add(2 * period))
+	require.NoError(t, err)
+	require.False(t, key.Validate(passcodeAhead2), "passcode out of skew should be invalid")
+
+	// Client clock is one period ahead. Within the skew.
+	passcodeAhead1, err := key.PassCodeCustom(time.Now().Add(period))
+	require.NoError(t, err)
+
+	ok, center := key.ValidateResync(passcodeAhead1)
+	require.True(t, ok, "passcode within the skew should be valid")
+	require.Equal(t, 1, center, "new skew center should be the matched offset")
+
+	// Now the window is centered on +1 period.
+	require.True(t, key.Validate(passcodeAhead2),
+		"passcode two periods ahead should be valid after resync")
+
+	// Failure keeps the center.
+	ok, center = key.ValidateResync("000000x")
+	require.False(t, ok)
+	require.Equal(t, 1, center, "center should not change on failure")
+}
+
+// ============================================================================
+//  Tests for fixed issues
+// ============================================================================
+//  These tests reproduce the issues and should pass if the issue is fixed.
+
+// Issue #42.
+// If `Period` is set short with `Skew=0`, the passcode validation often fails.
+func TestKey_skew_as_one(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("dummy issuer", "dummy account")
+	require.NoError(t, err, "failed to generate TOTP key during test setup")
+
+	key.Options.Period = 3              // set short to 3 seconds
+	timeSleep := key.Options.Period - 1 // sleep almost last-minute
+	numValid := 0
+	numIterations := 10
+
+	if timeSleep > uint(math.MaxInt) {
+		t.Fatalf("output length too large: %d", timeSleep)
+	}
+
+	// If skew is set to 0, the validation fails 60-70% of the time.
+	for range numIterations {
+		passCode, err := key.PassCode()
+		require.NoError(t, err, "failed to generate passcode")
+
+		// Sleep to validate passcode with an almost last-minute deadline.
+		time.Sleep(time.Second * time.Duration(timeSleep))
+
+		if ok := key.Validate(passCode); ok {
+			numValid++
+		}
+	}
+
+	expect := numIterations
+	actual := numValid
+
+	require.Equal(t, expect, actual,
+		"not all generated passcodes are valid")
+}
+
+// ----------------------------------------------------------------------------
+//  Key.Verify()
+// ----------------------------------------------------------------------------
+
+func TestKey_Verify(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSkew(1))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	// 1700000010 is the start of a window
+	windowStart := time.Unix(1700000010, 0).UTC()
+
+	passcode, err := key.PassCodeCustom(windowStart)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		at     time.Time
+		offset int
+	}{
+		{windowStart, 0},
+		{windowStart.Add(29 * time.Second), 0},
+		{windowStart.Add(30 * time.Second), -1},
+		{windowStart.Add(-1 * time.Second), 1},
+	} {
+		result := key.Verify(passcode, test.at)
+
+		require.NoError(t, result.Err)
+		require.True(t, result.Valid, "at: %v", test.at)
+		require.Equal(t, test.offset, result.Offset, "at: %v", test.at)
+		require.Equal(t, windowStart, result.MatchedAt, "at: %v", test.at)
+		require.Equal(t, result.Valid, key.ValidateCustom(passcode, test.at), "shorthand should agree")
+	}
+
+	// Out of skew
+	result := key.Verify(passcode, windowStart.Add(time.Hour))
+	require.NoError(t, result.Err)
+	require.False(t, result.Valid)
+	require.True(t, result.MatchedAt.IsZero())
+}
+
+func TestKey_Verify_errors(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	result := key.Verify("", time.Now())
+	require.ErrorIs(t, result.Err, ErrEmptyPasscode)
+	require.False(t, result.Valid)
+
+	result = key.Verify("12a456", time.Now())
+	require.ErrorIs(t, result.Err, ErrMalformedPasscode)
+	require.False(t, result.Valid)
+
+	key.Options.Algorithm = Algorithm("UNKNOWN")
+
+	result = key.Verify("123456", time.Now())
+	require.ErrorIs(t, result.Err, ErrUnsupportedAlgorithm)
+	require.False(t, result.Valid)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidateInRange() and Key.ValidateInRangeE()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidateInRange(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSkew(1))
+	require.NoError(t, err)
+
+	window := time.Unix(1700000000/30*30, 0)
+	period := 30 * time.Second
+
+	passcode, err := key.PassCodeCustom(window)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		from, to time.Time
+		expect   bool
+	}{
+		{window.Add(-time.Hour), window.Add(time.Hour), true},
+		{window, window, true},
+		// With the skew of 1, valid from 1 period before to 2 periods after
+		{window.Add(2*period - time.Second), window.Add(time.Hour), true},
+		{window.Add(-time.Hour), window.Add(-period), true},
+		{window.Add(2 * period), window.Add(time.Hour), false},
+		{window.Add(-time.Hour), window.Add(-period - time.Second), false},
+	} {
+		require.Equal(t, test.expect, key.ValidateInRange(passcode, test.from, test.to),
+			"range: %s - %s", test.from, test.to)
+		require.Equal(t, key.ValidateCustom(passcode, test.from), key.ValidateInRange(passcode, test.from, test.from),
+			"single time should be the same as ValidateCustom()")
+	}
+}
+
+func TestKey_ValidateInRangeE_errors(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	isValid, err := key.ValidateInRangeE("123456", now, now.Add(MaxRangeWindows*30*time.Second))
+	require.ErrorIs(t, err, ErrRangeTooLarge)
+	require.False(t, isValid)
+
+	_, err = key.ValidateInRangeE("123456", now, now.Add(time.Hour))
+	require.NoError(t, err, "range within the limit should be searched")
+
+	_, err = key.ValidateInRangeE("123456", now, now.Add(-time.Second))
+	require.ErrorContains(t, err, "invalid range")
+
+	_, err = key.ValidateInRangeE("12a456", now, now)
+	require.ErrorIs(t, err, ErrMalformedPasscode)
+
+	keyBad := *key
+	keyBad.Options.Algorithm = Algorithm("BADALGO")
+	_, err = keyBad.ValidateInRangeE("123456", now, now)
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	keyBad = *key
+	keyBad.Options.Skew = MaxRangeWindows + 1
+	_, err = keyBad.ValidateInRangeE("123456", now, now)
+	require.ErrorIs(t, err, ErrRangeTooLarge)
+}
+
+// ----------------------------------------------------------------------------
+//  Key.ValidWindows()
+// ----------------------------------------------------------------------------
+
+func TestKey_ValidWindows(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithDigits(DigitsEight), WithSkew(1))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	// 1700000010 is the start of a window
+	windowStart := time.Unix(1700000010, 0).UTC()
+
+	passcode, err := key.PassCodeCustom(windowStart)
+	require.NoError(t, err)
+
+	from := windowStart.Add(-time.Hour)
+	to := windowStart.Add(time.Hour)
+
+	windows := key.ValidWindows(passcode, from, to)
+	require.Equal(t, []TimeWindow{
+		{Start: windowStart.Add(-30 * time.Second), End: windowStart.Add(60 * time.Second)},
+	}, windows, "the window should be widened by the skew")
+
+	// Consistent with ValidateCustom()
+	require.True(t, key.ValidateCustom(passcode, windows[0].Start))
+	require.True(t, key.ValidateCustom(passcode, windows[0].End.Add(-time.Second)))
+	require.False(t, key.ValidateCustom(passcode, windows[0].Start.Add(-time.Second)))
+	require.False(t, key.ValidateCustom(passcode, windows[0].End))
+
+	// Clipped to the range
+	windows = key.ValidWindows(passcode, windowStart.Add(10*time.Second), to)
+	require.Equal(t, []TimeWindow{
+		{Start: windowStart.Add(10 * time.Second), End: windowStart.Add(60 * time.Second)},
+	}, windows)
+
+	// No skew
+	keyNoSkew := *key
+	keyNoSkew.Options.Skew = 0
+
+	windows = keyNoSkew.ValidWindows(passcode, from, to)
+	require.Equal(t, []TimeWindow{
+		{Start: windowStart, End: windowStart.Add(30 * time.Second)},
+	}, windows)
+
+	// Not found, empty range and malformed passcode
+	require.Nil(t, key.ValidWindows(passcode, windowStart.Add(time.Hour), windowStart.Add(2*time.Hour)))
+	require.Nil(t, key.ValidWindows(passcode, to, from))
+	require.Nil(t, key.ValidWindows("abc", from, to))
 }
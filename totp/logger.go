@@ -0,0 +1,71 @@
+package totp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Logger
+// ----------------------------------------------------------------------------
+
+// Logger is the interface to log the events of the package, such as failed
+// validations, deprecated algorithms and weak secrets. Set it with WithLogger().
+//
+// The package never logs the secret nor the passcodes. The secrets are logged
+// as a fingerprint only, which is the first 8 bytes of the SHA-256 hash of the
+// secret in hex.
+type Logger interface {
+	// Debugf logs the events for debugging, such as failed validations.
+	Debugf(format string, args ...any)
+	// Warnf logs the events that may need attention, such as weak secrets.
+	Warnf(format string, args ...any)
+}
+
+// nopLogger is the default Logger which logs nothing.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...any) {}
+
+func (nopLogger) Warnf(string, ...any) {}
+
+// ----------------------------------------------------------------------------
+//  Functions
+// ----------------------------------------------------------------------------
+
+// fingerprint returns a short fingerprint of the secret to be logged instead of
+// the secret itself.
+func fingerprint(secret Secret) string {
+	const lenFingerprint = 8
+
+	hash := sha256.Sum256(secret)
+
+	return hex.EncodeToString(hash[:lenFingerprint])
+}
+
+// fingerprintBase32 is similar to fingerprint but for the base32 encoded secret.
+// Malformed secrets return "unknown".
+func fingerprintBase32(secret string) string {
+	decoded, err := decodeBase32Tolerant(secret)
+	if err != nil {
+		return "unknown"
+	}
+
+	return fingerprint(decoded)
+}
+
+// logKeyWarnings logs the warnings of the key, such as deprecated algorithm and
+// weak secret.
+func logKeyWarnings(key *Key) {
+	logger := key.Options.log()
+
+	if key.Options.Algorithm == "MD5" {
+		logger.Warnf("totp: deprecated algorithm used: algorithm=%s issuer=%q account=%q fingerprint=%s",
+			key.Options.Algorithm, key.Options.Issuer, key.Options.AccountName, fingerprint(key.Secret))
+	}
+
+	if !key.Secret.MeetsRFC4226Minimum() {
+		logger.Warnf("totp: weak secret detected: size=%d min=%d issuer=%q account=%q fingerprint=%s",
+			key.Secret.Len(), SecretSizeMin, key.Options.Issuer, key.Options.AccountName, fingerprint(key.Secret))
+	}
+}
@@ -0,0 +1,111 @@
+package totp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordLogger is a Logger that records the logs for testing.
+type recordLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordLogger) Debugf(format string, args ...any) {
+	l.record("DEBUG " + fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) Warnf(format string, args ...any) {
+	l.record("WARN " + fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) record(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.logs = append(l.logs, msg)
+}
+
+func (l *recordLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return strings.Join(l.logs, "\n")
+}
+
+// ----------------------------------------------------------------------------
+//  WithLogger()
+// ----------------------------------------------------------------------------
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	logger := &recordLogger{}
+
+	keyWeak, err := GenerateKey("Example.com", "alice@example.com",
+		WithLogger(logger), WithAlgorithm(Algorithm("MD5")), WithSecretSize(10))
+	require.NoError(t, err)
+
+	key, err := GenerateKey("Example.com", "bob@example.com", WithLogger(logger), WithSkew(0))
+	require.NoError(t, err)
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	wrongCode := strconv.Itoa((int(passcode[0]-'0')+1)%10) + passcode[1:]
+	require.False(t, key.Validate(wrongCode))
+
+	logs := logger.String()
+
+	require.Contains(t, logs, "DEBUG totp: key generated: algorithm=MD5")
+	require.Contains(t, logs, "WARN totp: deprecated algorithm used: algorithm=MD5")
+	require.Contains(t, logs, "WARN totp: weak secret detected: size=10 min=16")
+	require.Contains(t, logs, "fingerprint="+fingerprint(keyWeak.Secret))
+	require.Contains(t, logs, "DEBUG totp: validation failed within skew: skew=0 period=30 "+
+		"issuer=\"Example.com\" account=\"bob@example.com\" fingerprint="+fingerprint(key.Secret))
+
+	// No secret material nor passcodes
+	for _, secret := range []Secret{keyWeak.Secret, key.Secret} {
+		require.NotContains(t, logs, secret.Base32())
+		require.NotContains(t, logs, secret.Base64())
+	}
+
+	require.NotContains(t, logs, passcode)
+	require.NotContains(t, logs, wrongCode)
+}
+
+func TestWithLogger_import(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	logger := &recordLogger{}
+
+	key, err := GenKeyFromURI(testRendererURI, WithLogger(logger))
+	require.NoError(t, err)
+
+	logs := logger.String()
+
+	require.Equal(t, "DEBUG totp: key imported from URI: algorithm=SHA1 "+
+		"issuer=\"Example.com\" account=\"alice@example.com\" fingerprint="+fingerprint(key.Secret), logs,
+		"the secret generated on the way should not be logged")
+	require.NotContains(t, logs, key.Secret.Base32())
+}
+
+func TestWithLogger_default(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	opts := Options{}
+
+	require.Equal(t, nopLogger{}, opts.log(), "default should be the no-op logger")
+
+	// The no-op logger should not panic
+	opts.log().Debugf("debug %d", 1)
+	opts.log().Warnf("warn %d", 1)
+}
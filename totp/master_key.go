@@ -0,0 +1,117 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+)
+
+// GenKeyFromMasterKey creates a new Key object with the secret derived from
+// the given master key, such as an existing per-user symmetric key, with the
+// options applied.
+//
+// The secret is derived with HKDF-SHA256 (RFC-5869) with no salt, the master
+// key as the input keying material and the info for domain separation. The
+// output length is the SecretSize of the options. So the same master key, info
+// and options always yield the same secret, which enables stateless
+// regeneration of the key.
+//
+// Unlike WithECDH, it is a single-party derivation. If the ECDH options are set
+// as well, they are ignored. The info must not be empty. The recommended format
+// is:
+//
+//	"[issuer] [account name] [purpose] [version]"
+//
+//	e.g.) "example.com alice@example.com TOTP secret v1"
+func GenKeyFromMasterKey(master []byte, info, issuer, accountName string, opts ...Option) (*Key, error) {
+	if len(master) == 0 {
+		return nil, errors.New("empty master key")
+	}
+
+	if info == "" {
+		return nil, errors.New("empty info for master key derivation. it is required for domain separation")
+	}
+
+	options, err := resolveOptions(issuer, accountName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := hkdfSHA256(master, nil, []byte(info), options.SecretSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive key from master key")
+	}
+
+	return generateKey(*options, secret)
+}
+
+// deviceKeyInfoPrefix is the prefix of the HKDF info of the device keys. See
+// Key.DeriveDeviceKey().
+const deviceKeyInfoPrefix = "go-totp device key v1:"
+
+// DeriveDeviceKey returns a new Key object with a per-device secret derived from
+// the secret of the key and the given device ID. The other options are kept.
+//
+// Each device of the same account gets a different secret, so the compromise of
+// a device is isolated. The server can validate the passcodes of any device by
+// re-deriving the device key from the root key.
+//
+// The secret is derived as follows for reproducibility:
+//
+//	secret = HKDF-SHA256(IKM: root secret, salt: none,
+//	                     info: "go-totp device key v1:" || device ID,
+//	                     length: length of the root secret)
+//
+// The device ID must not be empty.
+func (k *Key) DeriveDeviceKey(deviceID string) (*Key, error) {
+	if deviceID == "" {
+		return nil, errors.New("empty device ID")
+	}
+
+	//nolint:gosec // the length of a secret never overflows
+	secret, err := hkdfSHA256(k.Secret, nil, []byte(deviceKeyInfoPrefix+deviceID), uint(k.Secret.Len()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive device key")
+	}
+
+	return &Key{
+		Secret:  secret,
+		Options: k.Options,
+	}, nil
+}
+
+// hkdfSHA256 derives a key of outLen bytes from the secret, salt and info with
+// HKDF-SHA256 (RFC-5869). An empty salt means no salt.
+func hkdfSHA256(secret, salt, info []byte, outLen uint) ([]byte, error) {
+	const maxBlocks = 255
+
+	if outLen == 0 || outLen > maxBlocks*sha256.Size {
+		return nil, errors.Errorf("invalid output length: %d. it should be 1 to %d", outLen, maxBlocks*sha256.Size)
+	}
+
+	// Extract. No salt is the same as a salt of zeros in the hash size.
+	if len(salt) == 0 {
+		salt = make([]byte, sha256.Size)
+	}
+
+	extractor := hmac.New(sha256.New, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	// Expand
+	out := make([]byte, 0, outLen+sha256.Size)
+	block := []byte{}
+
+	for counter := byte(1); uint(len(out)) < outLen; counter++ {
+		expander := hmac.New(sha256.New, prk)
+		expander.Write(block)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		block = expander.Sum(nil)
+
+		out = append(out, block...)
+	}
+
+	return out[:outLen], nil
+}
@@ -0,0 +1,156 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  GenKeyFromMasterKey()
+// ----------------------------------------------------------------------------
+
+func TestGenKeyFromMasterKey(t *testing.T) {
+	t.Parallel()
+
+	master := []byte("per-user symmetric key of alice")
+	info := "example.com alice@example.com TOTP secret v1"
+
+	key1, err := GenKeyFromMasterKey(master, info, "Example.com", "alice@example.com", WithSecretSize(32))
+	require.NoError(t, err)
+	require.Equal(t, 32, key1.Secret.Len())
+	require.Equal(t, "Example.com", key1.Options.Issuer)
+
+	// Deterministic
+	key2, err := GenKeyFromMasterKey(master, info, "Example.com", "alice@example.com", WithSecretSize(32))
+	require.NoError(t, err)
+	require.Equal(t, key1.Secret, key2.Secret, "same master and info should yield the same secret")
+
+	// Domain separation
+	key3, err := GenKeyFromMasterKey(master, info+" other", "Example.com", "alice@example.com", WithSecretSize(32))
+	require.NoError(t, err)
+	require.NotEqual(t, key1.Secret, key3.Secret, "different info should yield a different secret")
+
+	// Default secret size
+	keyDefault, err := GenKeyFromMasterKey(master, info, "Example.com", "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, int(OptionSecretSizeDefault), keyDefault.Secret.Len())
+	require.True(t, bytes.HasPrefix(keyDefault.Secret, key1.Secret), "HKDF output should be a prefix of the longer one")
+
+	passcode, err := key1.PassCode()
+	require.NoError(t, err)
+	require.True(t, key2.Validate(passcode))
+}
+
+func TestGenKeyFromMasterKey_skew_duration(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenKeyFromMasterKey([]byte("master key"), "info", "Example.com", "alice@example.com",
+		WithSkewDuration(2*time.Minute), WithPeriod(60))
+	require.NoError(t, err)
+	require.Equal(t, uint(60), key.Options.Period)
+	require.Equal(t, uint(2), key.Options.Skew, "the skew duration should be resolved with the final period")
+}
+
+func TestGenKeyFromMasterKey_errors(t *testing.T) {
+	t.Parallel()
+
+	master := []byte("master key")
+
+	for _, test := range []struct {
+		master []byte
+		info   string
+		issuer string
+		opts   []Option
+		errMsg string
+	}{
+		{nil, "info", "Example.com", nil, "empty master key"},
+		{master, "", "Example.com", nil, "empty info"},
+		{master, "info", "", nil, "issuer and accountName are required"},
+		{master, "info", "Example.com", []Option{WithDigits(Digits(7))}, "failed to apply custom options"},
+		{master, "info", "Example.com", []Option{WithSecretSize(255*32 + 1)}, "invalid output length"},
+	} {
+		key, err := GenKeyFromMasterKey(test.master, test.info, test.issuer, "alice@example.com", test.opts...)
+
+		require.ErrorContains(t, err, test.errMsg)
+		require.Nil(t, key)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Key.DeriveDeviceKey()
+// ----------------------------------------------------------------------------
+
+func TestKey_DeriveDeviceKey(t *testing.T) {
+	t.Parallel()
+
+	root, err := GenerateKey("Example.com", "alice@example.com", WithNow(time.Unix(1700000000, 0)))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	phone, err := root.DeriveDeviceKey("phone")
+	require.NoError(t, err)
+
+	tablet, err := root.DeriveDeviceKey("tablet")
+	require.NoError(t, err)
+
+	require.Equal(t, root.Secret.Len(), phone.Secret.Len())
+	require.NotEqual(t, root.Secret, phone.Secret)
+	require.NotEqual(t, phone.Secret, tablet.Secret, "devices should have different secrets")
+	require.Equal(t, root.Options.Issuer, phone.Options.Issuer, "options should be kept")
+
+	// The server re-derives the device key to validate
+	passcode, err := phone.PassCode()
+	require.NoError(t, err)
+
+	phoneAgain, err := root.DeriveDeviceKey("phone")
+	require.NoError(t, err)
+	require.True(t, phoneAgain.Validate(passcode))
+
+	// Reproducible derivation
+	expect, err := hkdfSHA256(root.Secret, nil, []byte("go-totp device key v1:phone"), uint(root.Secret.Len()))
+	require.NoError(t, err)
+	require.Equal(t, Secret(expect), phone.Secret)
+
+	// Errors
+	_, err = root.DeriveDeviceKey("")
+	require.ErrorContains(t, err, "empty device ID")
+
+	//nolint:exhaustruct // zero-value key
+	_, err = (&Key{}).DeriveDeviceKey("phone")
+	require.ErrorContains(t, err, "failed to derive device key")
+}
+
+// ----------------------------------------------------------------------------
+//  hkdfSHA256()
+// ----------------------------------------------------------------------------
+
+func TestHKDFSHA256_rfc5869(t *testing.T) {
+	t.Parallel()
+
+	// Test Case 3 of RFC-5869 (zero-length salt and info)
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	expect := "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"
+
+	okm, err := hkdfSHA256(ikm, nil, nil, 42)
+	require.NoError(t, err)
+	require.Equal(t, expect, hex.EncodeToString(okm))
+
+	// Test Case 1 of RFC-5869 (with salt and info)
+	salt, err := hex.DecodeString("000102030405060708090a0b0c")
+	require.NoError(t, err)
+
+	info, err := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	require.NoError(t, err)
+
+	expect = "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+
+	okm, err = hkdfSHA256(ikm, salt, info, 42)
+	require.NoError(t, err)
+	require.Equal(t, expect, hex.EncodeToString(okm))
+
+	_, err = hkdfSHA256(ikm, nil, nil, 0)
+	require.ErrorContains(t, err, "invalid output length")
+}
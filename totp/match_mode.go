@@ -0,0 +1,60 @@
+package totp
+
+import "strings"
+
+// MatchMode is the mode to compare the issuers in the path (label) and the
+// query of a URI. Use `Match*` constants to set the mode. See URI.IssuerMatch().
+type MatchMode int
+
+// Modes to compare the issuers.
+const (
+	// MatchExact compares the issuers byte by byte. This is the default and the
+	// same as URI.Issuer().
+	MatchExact MatchMode = iota
+	// MatchCaseInsensitive compares the issuers ignoring the case of ASCII
+	// letters. Such as "Example.com" and "EXAMPLE.COM".
+	MatchCaseInsensitive
+	// MatchFold compares the issuers under Unicode simple case folding. Such as
+	// "Straße" and "STRASSE" do not match but "Ωmega" and "ωMEGA" do.
+	//
+	// Note that Unicode normalization forms, such as NFC, are not applied.
+	MatchFold
+)
+
+// equal returns true if a and b are equal under the mode. Unknown modes fall
+// back to MatchExact.
+func (m MatchMode) equal(a, b string) bool {
+	switch m {
+	case MatchCaseInsensitive:
+		return equalFoldASCII(a, b)
+	case MatchFold:
+		return strings.EqualFold(a, b)
+	case MatchExact:
+		return a == b
+	default:
+		return a == b
+	}
+}
+
+// equalFoldASCII is similar to strings.EqualFold but folds ASCII letters only.
+func equalFoldASCII(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range len(a) {
+		if toLowerASCII(a[i]) != toLowerASCII(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toLowerASCII(char byte) byte {
+	if 'A' <= char && char <= 'Z' {
+		return char + ('a' - 'A')
+	}
+
+	return char
+}
@@ -0,0 +1,115 @@
+package totp
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Metrics
+// ----------------------------------------------------------------------------
+
+// Metrics is the interface to count the validation events, such as for the
+// dashboards of the TOTP health. Set it with WithMetrics().
+//
+// The events carry no secret-derived values, such as the passcodes or the
+// fingerprints of the secrets. So they are safe to be used as the labels of
+// the counters.
+type Metrics interface {
+	// Inc increments the counter of the event. It must be safe for concurrent
+	// use.
+	Inc(event MetricEvent)
+}
+
+// MetricEvent is the kind of the validation event counted by Metrics.
+type MetricEvent string
+
+// Validation events counted by Metrics.
+const (
+	// MetricSuccess is counted when a passcode is valid.
+	MetricSuccess = MetricEvent("success")
+	// MetricFailure is counted when a passcode is invalid, including malformed
+	// passcodes.
+	MetricFailure = MetricEvent("failure")
+	// MetricSkewMatched is counted in addition to MetricSuccess when a passcode
+	// matched a window other than the center. Such as the clients with drifted
+	// clocks.
+	MetricSkewMatched = MetricEvent("skew_matched")
+	// MetricExpired is counted in addition to MetricFailure when a passcode is
+	// of the window just before the validation window range. Such as the users
+	// who typed the passcode too slowly.
+	MetricExpired = MetricEvent("expired")
+)
+
+// maxSkewExpired bounds the skew to compute the expired window. Larger skews are
+// meaningless and would overflow the time calculation.
+const maxSkewExpired = 1 << 16
+
+// ----------------------------------------------------------------------------
+//  Functions
+// ----------------------------------------------------------------------------
+
+// observeFailure counts the failure of a validation that could not be
+// performed, such as a malformed passcode.
+func observeFailure(options Options) {
+	if options.metrics == nil {
+		return
+	}
+
+	options.metrics.Inc(MetricFailure)
+}
+
+// observeValidation counts the result of the validation. The offset is the one
+// of the matched window from matchOffset(), ignored if not valid. The extra HMAC
+// to detect the expired passcodes is computed only if the metrics are set.
+func observeValidation(options Options, passcode, secret string, validationTime time.Time, offset int, valid bool) {
+	if options.metrics == nil {
+		return
+	}
+
+	if valid {
+		options.metrics.Inc(MetricSuccess)
+
+		if offset != options.skewCenter {
+			options.metrics.Inc(MetricSkewMatched)
+		}
+
+		return
+	}
+
+	options.metrics.Inc(MetricFailure)
+
+	if isExpiredPassCode(passcode, secret, validationTime, options) {
+		options.metrics.Inc(MetricExpired)
+	}
+}
+
+// isExpiredPassCode returns true if the passcode is of the window just before
+// the validation window range. Which is (center - skew - 1) periods from the
+// validation time.
+func isExpiredPassCode(passcode, secret string, validationTime time.Time, options Options) bool {
+	if !options.Algorithm.IsSupported() {
+		return false
+	}
+
+	period := periodOrDefault(options)
+	offset := options.skewCenter - int(min(options.Skew, maxSkewExpired)) - 1
+	windowTime := validationTime.Add(time.Duration(offset) * time.Duration(period) * time.Second)
+
+	if windowTime.Unix() < 0 {
+		return false
+	}
+
+	otpOpts := options.otpOpts()
+	otpOpts.Period = period
+	otpOpts.Skew = 0
+
+	expect, err := totp.GenerateCodeCustom(secret, windowTime.UTC(), otpOpts)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expect), []byte(options.normalizePassCode(passcode))) == 1
+}
@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordMetrics is a Metrics that records the counts for testing.
+type recordMetrics struct {
+	mu     sync.Mutex
+	counts map[MetricEvent]int
+}
+
+func (m *recordMetrics) Inc(event MetricEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.counts == nil {
+		m.counts = make(map[MetricEvent]int)
+	}
+
+	m.counts[event]++
+}
+
+func (m *recordMetrics) snapshot() map[MetricEvent]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[MetricEvent]int, len(m.counts))
+	for event, count := range m.counts {
+		out[event] = count
+	}
+
+	return out
+}
+
+// ----------------------------------------------------------------------------
+//  WithMetrics()
+// ----------------------------------------------------------------------------
+
+func TestWithMetrics(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	metrics := &recordMetrics{}
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithMetrics(metrics), WithNow(fixedTime), WithSkew(1))
+	require.NoError(t, err)
+
+	period := time.Duration(key.Options.Period) * time.Second
+
+	passcodeAt := func(offset int) string {
+		passcode, err := key.PassCodeCustom(fixedTime.Add(time.Duration(offset) * period))
+		require.NoError(t, err)
+
+		return passcode
+	}
+
+	current, previous, expired := passcodeAt(0), passcodeAt(-1), passcodeAt(-2)
+	if current == previous || current == expired || previous == expired {
+		t.Skip("passcodes collided by chance")
+	}
+
+	require.True(t, key.Validate(current))
+	require.Equal(t, map[MetricEvent]int{MetricSuccess: 1}, metrics.snapshot())
+
+	require.True(t, key.Validate(previous))
+	require.Equal(t, map[MetricEvent]int{MetricSuccess: 2, MetricSkewMatched: 1}, metrics.snapshot())
+
+	require.False(t, key.Validate(expired))
+	require.Equal(t, map[MetricEvent]int{
+		MetricSuccess: 2, MetricSkewMatched: 1, MetricFailure: 1, MetricExpired: 1,
+	}, metrics.snapshot())
+
+	require.False(t, key.Validate("abc"))
+	require.Equal(t, 2, metrics.snapshot()[MetricFailure], "malformed passcode should be a failure")
+
+	// Package level functions and the compiled validator
+	require.True(t, Validate(previous, key.Secret.Base32(), key.Options))
+
+	validator, err := key.Compile()
+	require.NoError(t, err)
+	require.False(t, validator.Validate(expired))
+
+	require.Equal(t, map[MetricEvent]int{
+		MetricSuccess: 3, MetricSkewMatched: 2, MetricFailure: 3, MetricExpired: 2,
+	}, metrics.snapshot())
+}
+
+func TestWithMetrics_default(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+	require.Nil(t, key.Options.metrics, "metrics should be disabled by default")
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+	require.True(t, key.Validate(passcode), "validation should work without metrics")
+}
@@ -1,9 +1,13 @@
 package totp
 
 import (
+	"bytes"
 	"crypto/ecdh"
+	"slices"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
 )
 
 // ============================================================================
@@ -16,11 +20,49 @@ type Option func(*Options) error
 
 const errNilOptions = "options is nil"
 
+// ECDHContextLenRecommended is the recommended minimum length in bytes of the
+// context string used in WithECDH. Shorter contexts are accepted by WithECDH but
+// rejected by WithECDHStrict.
+const ECDHContextLenRecommended = 16
+
 // ----------------------------------------------------------------------------
 //  Option Patterns
 // ----------------------------------------------------------------------------
 
+// WithAcceptedPeriods restricts the periods in seconds of the keys to the given
+// ones. Such as to enforce the uniform period across the 2FA deployment of an
+// organization. By default, any period is accepted.
+//
+// The URIs with other periods are rejected on import (GenKeyFromURI()). The keys
+// with other periods fail Key.IsValid() and the validations, such as
+// Key.Validate() and Key.Verify().
+//
+// It returns an error if no period or a zero period is given.
+func WithAcceptedPeriods(periods ...uint) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if len(periods) == 0 {
+			return errors.New("no accepted period given")
+		}
+
+		if slices.Contains(periods, 0) {
+			return errors.Wrap(ErrZeroPeriod, "invalid accepted periods")
+		}
+
+		opts.acceptedPeriods = slices.Clone(periods)
+
+		return nil
+	}
+}
+
 // WithAlgorithm sets the Algorithm to use for HMAC (Default: Algorithm("SHA512")).
+//
+// If the secret size is still the default of the current algorithm, it will be
+// changed to the default of the new algorithm as well. Such as 16 bytes for MD5.
+// To use a custom secret size, set WithSecretSize() after this option.
 func WithAlgorithm(algo Algorithm) Option {
 	return func(opts *Options) error {
 		if opts == nil {
@@ -31,20 +73,54 @@ func WithAlgorithm(algo Algorithm) Option {
 			return errors.New("unsupported algorithm: " + algo.String())
 		}
 
+		if opts.SecretSize == secretSizeDefault(opts.Algorithm) {
+			opts.SecretSize = secretSizeDefault(algo)
+		}
+
 		opts.Algorithm = algo
 
 		return nil
 	}
 }
 
+// WithChecksum appends a checksum digit to the passcodes if enable is true. Such
+// as to interoperate with the legacy systems that expect checksummed passcodes.
+// By default, it is disabled as the standard TOTP.
+//
+// The checksum is the Luhn (mod 10) check digit of the passcode as in the
+// reference implementation of RFC-4226, Appendix C. e.g. "123456" → "1234566".
+// The passcodes generated, such as Key.PassCode(), have the digit appended and
+// the validations, such as Key.Validate(), require and verify it.
+//
+// The option is not stored in the PEM nor the URI of the key and the
+// authenticator apps do not support it. So set it on both sides.
+func WithChecksum(enable bool) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.checksum = enable
+
+		return nil
+	}
+}
+
 // WithDigits sets the Digits to request TOTP code. Choices are DigitsSix or
 // DigitsEight (Default: DigitsSix).
+//
+// Other values are rejected with an error wrapping ErrUnsupportedDigits rather
+// than silently generating 6 digit passcodes.
 func WithDigits(digits Digits) Option {
 	return func(opts *Options) error {
 		if opts == nil {
 			return errors.New(errNilOptions)
 		}
 
+		if !digits.IsSupported() {
+			return errors.Wrapf(ErrUnsupportedDigits, "%d. it should be 6 or 8", digits)
+		}
+
 		opts.Digits = digits
 
 		return nil
@@ -67,12 +143,30 @@ func WithDigits(digits Digits) Option {
 //     "[issuer] [sorted account names] [purpose] [version]"
 //
 //     e.g.) "example.com alice@example.com bob@example.com TOTP secret v1"
+//
+//     An empty context is rejected since it gives no domain separation. Also it
+//     is recommended to be at least ECDHContextLenRecommended bytes long. Use
+//     WithECDHStrict to enforce the recommended length.
+//
+//   - The keys of the curves not in SupportedECDHCurves() are rejected.
 func WithECDH(localKey *ecdh.PrivateKey, remoteKey *ecdh.PublicKey, context string) Option {
 	return func(opts *Options) error {
 		if opts == nil {
 			return errors.New(errNilOptions)
 		}
 
+		if context == "" {
+			return errors.New("empty context for ECDH. it should be a consistent string between the two parties")
+		}
+
+		if localKey != nil && !IsSupportedCurve(localKey.Curve()) {
+			return errors.Errorf("unsupported curve of the local ECDH key: %v", localKey.Curve())
+		}
+
+		if remoteKey != nil && !IsSupportedCurve(remoteKey.Curve()) {
+			return errors.Errorf("unsupported curve of the remote ECDH key: %v", remoteKey.Curve())
+		}
+
 		// Set ECDH keys as option info. The actual secret generation is done
 		// when the Key is created. See GenerateKeyCustom().
 		opts.ecdhPrivateKey = localKey
@@ -83,6 +177,25 @@ func WithECDH(localKey *ecdh.PrivateKey, remoteKey *ecdh.PublicKey, context stri
 	}
 }
 
+// WithECDHStrict is similar to WithECDH but also rejects the context shorter
+// than ECDHContextLenRecommended bytes.
+func WithECDHStrict(localKey *ecdh.PrivateKey, remoteKey *ecdh.PublicKey, context string) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if len(context) < ECDHContextLenRecommended {
+			return errors.Errorf(
+				"context for ECDH is too short. it should be at least %d bytes long, got %d",
+				ECDHContextLenRecommended, len(context),
+			)
+		}
+
+		return WithECDH(localKey, remoteKey, context)(opts)
+	}
+}
+
 // WithECDHKDF sets the userKDF, user definded key derivation function, to derive
 // a TOTP secret key from a ECDH shared secret.
 //
@@ -100,6 +213,202 @@ func WithECDHKDF(userKDF func(secret, ctx []byte, outLen uint) ([]byte, error))
 	}
 }
 
+// WithIssuerInPath sets whether to include the issuer in the path (label) of
+// the URI generated by Key.URI() (Default: true).
+//
+// If false, the label contains only the account name and the issuer is set in
+// the query only. e.g.:
+//
+//	otpauth://totp/alice@example.com?issuer=Example.com&...
+//
+// Some authenticator apps prefer this format. The issuer is still recovered
+// from the query on import.
+func WithIssuerInPath(inPath bool) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.issuerNotInPath = !inPath
+
+		return nil
+	}
+}
+
+// WithLabelSeparator sets the separator between the issuer and the account name
+// in the label of the URI, such as Key.URI(). It also applies to parsing URIs,
+// such as in GenKeyFromURI. (Default: ":")
+//
+// WARNING: The Key URI Format specifies the colon as the separator. This is ONLY
+// for the nonstandard apps, such as legacy internal authenticators. The standard
+// apps will show the whole label as the account name with the issuer missing.
+// Do not use it unless the target app requires it.
+func WithLabelSeparator(sep string) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if sep == "" {
+			return errors.New("empty label separator")
+		}
+
+		opts.labelSeparator = sep
+
+		return nil
+	}
+}
+
+// WithLenientIssuer relaxes the issuer check on parsing URIs, such as in
+// GenKeyFromURI. If set, the issuers in the path (label) and the query do not
+// need to match and the issuer is taken from either of them. See the
+// URI.IssuerLenient() method for details.
+func WithLenientIssuer() Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.lenientIssuer = true
+
+		return nil
+	}
+}
+
+// WithLenientPassCode removes all the spaces and hyphens in the passcode before
+// the validation. Such as "123 456" or "123-456" which users often type as the
+// authenticator apps display. The normalized passcode is then checked and
+// validated as usual. (Default: only the surrounding spaces are removed)
+func WithLenientPassCode() Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.lenientPassCode = true
+
+		return nil
+	}
+}
+
+// WithLogger sets the logger to log the events of the key, such as failed
+// validations, deprecated algorithms and weak secrets. (Default: no logging)
+//
+// No secret material is logged. Only the fingerprints and metadata are. If nil
+// is given, the logging is disabled.
+func WithLogger(logger Logger) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.logger = logger
+
+		return nil
+	}
+}
+
+// WithLowercaseAlgorithmURI emits the algorithm in lowercase in the URI of the
+// key, such as "algorithm=sha1" in Key.URI(). (Default: uppercase)
+//
+// The Key URI Format specifies the uppercase. This is ONLY for the apps that
+// reject it. Parsing the URIs, such as in GenKeyFromURI and URI.Check(), is
+// case-insensitive regardless of this option.
+func WithLowercaseAlgorithmURI() Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.lowercaseAlgorithm = true
+
+		return nil
+	}
+}
+
+// WithMetrics sets the metrics to count the validation events, such as the
+// successes, failures, skew-matched and expired passcodes. (Default: no
+// counting)
+//
+// The events are counted by the validations of Key.Validate(), Key.Verify()
+// and their variants, CompiledValidator and the package level Validate
+// functions. Note that detecting the skew-matched and expired passcodes costs
+// an extra HMAC computation per validation. If nil is given, the counting is
+// disabled.
+func WithMetrics(metrics Metrics) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.metrics = metrics
+
+		return nil
+	}
+}
+
+// WithMinEntropy requires the randomly generated secret to have at least the
+// given Shannon entropy in bits per byte. If not, the secret is regenerated up
+// to a few times and GenerateKey returns an error wrapping ErrLowEntropy if it
+// still does not meet the threshold. This guards against a broken or weak
+// random source. Disabled by default.
+//
+// Note that the entropy of a secret is limited by its size, which is at most
+// log2(size) bits per byte. For example, 4.32 for a 20 byte secret and 7.0 for
+// the default 128 byte secret. The value must be in the range of (0, 8].
+func WithMinEntropy(bitsPerByte float64) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if !(bitsPerByte > 0 && bitsPerByte <= maxEntropy) {
+			return errors.Errorf("invalid minimum entropy: %v. it should be in the range of (0, %d]",
+				bitsPerByte, maxEntropy)
+		}
+
+		opts.minEntropy = bitsPerByte
+
+		return nil
+	}
+}
+
+// WithMinimalURI omits the algorithm, digits and period parameters from the URI
+// of the key, such as Key.URI(), if they are equal to the default values.
+//
+// The shorter URI yields a less dense QR code which scans better on low-res
+// cameras. The omitted parameters are implied as default on import, such as in
+// GenKeyFromURI. Without this option, all the parameters are always set.
+func WithMinimalURI() Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.minimalURI = true
+
+		return nil
+	}
+}
+
+// WithNow pins the time used by the methods of the key that use the current
+// time, such as Key.PassCode() and Key.Validate(), to the given time.
+//
+// This is handy for scripts and tests that need a frozen time. The fixed time
+// takes precedence over the system clock. The methods with a custom time, such
+// as Key.PassCodeCustom(), are not affected.
+func WithNow(now time.Time) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.now = now
+
+		return nil
+	}
+}
+
 // WithPeriod sets the number of seconds a TOTP hash is valid for (Default: 30 seconds).
 func WithPeriod(period uint) Option {
 	return func(opts *Options) error {
@@ -113,7 +422,61 @@ func WithPeriod(period uint) Option {
 	}
 }
 
-// WithSecretSize sets the size of the generated Secret (Default: 128 bytes).
+// WithRedactedStringer makes Key.String() replace the secret in the URI with
+// "REDACTED". This prevents the accidental leakage of the secret through logging
+// the key via "%v" or "%s". Key.URI() still returns the full URI.
+//
+// It is opt-in since it changes the output of Key.String(). The option is not
+// stored in the PEM nor the URI of the key.
+func WithRedactedStringer() Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.redactedStringer = true
+
+		return nil
+	}
+}
+
+// WithSecretPepper stretches the secret with the given site-wide pepper before
+// computing the HMAC of the passcodes. Such as the pepper kept in the app config
+// and the secrets in the database, so that a database leak alone can not
+// generate the passcodes.
+//
+// The passcodes are computed with the derived secret below instead of the
+// secret itself, for both the generation and validation:
+//
+//	derived = HKDF-SHA256(IKM: secret, salt: pepper, info: "go-totp pepper v1",
+//	                      length: length of the secret)
+//
+// The pepper must be identical for the generation and validation. Otherwise the
+// passcodes never match. Since the authenticator apps need the derived secret,
+// the URI and QR code of the key, such as Key.URI() and Key.QRCode(), carry the
+// derived secret. The PEM and binary formats keep the secret itself and never
+// the pepper. Do not apply this option to the keys imported from the URIs since
+// they already carry the derived secret. See Key.PepperedSecret().
+//
+// The pepper is copied and must not be empty.
+func WithSecretPepper(pepper []byte) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if len(pepper) == 0 {
+			return errors.New("empty secret pepper")
+		}
+
+		opts.pepper = bytes.Clone(pepper)
+
+		return nil
+	}
+}
+
+// WithSecretSize sets the size of the generated Secret (Default: 128 bytes,
+// 16 bytes for MD5).
 func WithSecretSize(size uint) Option {
 	return func(opts *Options) error {
 		if opts == nil {
@@ -126,6 +489,41 @@ func WithSecretSize(size uint) Option {
 	}
 }
 
+// WithSecretBase32Length sets the size of the generated Secret so that its base32
+// encoding is exactly the given number of characters. Such as to store the
+// secrets in fixed-width columns. If padded is true, the length is of the padded
+// encoding (Secret.Base32Padded()), otherwise of the unpadded one
+// (Secret.Base32()).
+//
+// Base32 encodes each 5 bytes to 8 characters. So n bytes are encoded to
+// ceil(8n/5) characters without padding, and to ceil(n/5)*8 characters with
+// padding. The size is the largest n that fits, which is floor(5*chars/8).
+//
+//   - Without padding, chars modulo 8 must be 0, 2, 4, 5 or 7. e.g. 26 chars
+//     for 16 bytes and 32 chars for 20 bytes.
+//   - With padding, chars must be a multiple of 8. e.g. 32 chars for 20 bytes,
+//     which has no padding characters since the size is the largest.
+//
+// It returns an error if the length is not achievable or the size is smaller
+// than SecretSizeMin (16 bytes). It overrides WithSecretSize and vice versa,
+// the latter wins.
+func WithSecretBase32Length(chars int, padded bool) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		size, err := secretSizeFromBase32Length(chars, padded)
+		if err != nil {
+			return err
+		}
+
+		opts.SecretSize = size
+
+		return nil
+	}
+}
+
 // WithSkew sets the periods before or after the current time to allow.
 //
 // Value of 1 allows up to Period of either side of the specified time.
@@ -137,6 +535,97 @@ func WithSkew(skew uint) Option {
 		}
 
 		opts.Skew = skew
+		opts.skewDuration = nil
+
+		return nil
+	}
+}
+
+// WithSkewCenter shifts the center of the validation window by the given number
+// of periods (Default: 0). The validation checks [center-Skew, center+Skew]
+// periods around the validation time instead of [-Skew, +Skew].
+//
+// Positive values accept the passcodes of the clients whose clock runs ahead,
+// such as a hardware token known to be fast by a fixed number of periods, and
+// negative values the ones running behind. Unlike increasing the Skew, it does
+// not widen the window. Key.ValidateResync() sets the center automatically.
+func WithSkewCenter(periods int) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.skewCenter = periods
+
+		return nil
+	}
+}
+
+// WithSkewDuration sets the skew as the tolerance in wall-clock time, such as
+// "±60 seconds", rather than in periods as WithSkew().
+//
+// The duration is converted to the nearest number of periods of the Period, with
+// halves rounded up. e.g. with the 30 seconds period, 60s is 2 periods, 45s is 2
+// and 44s is 1. The conversion is done after all the options are applied, so
+// the order of WithPeriod() does not matter. Also the period of the URI is used
+// in GenKeyFromURI(). A later WithSkew() overrides it.
+//
+// It returns an error if the duration is negative.
+func WithSkewDuration(tolerance time.Duration) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if tolerance < 0 {
+			return errors.Errorf("invalid skew duration: %s. it should not be negative", tolerance)
+		}
+
+		opts.skewDuration = &tolerance
+		opts.resolveSkewDuration()
+
+		return nil
+	}
+}
+
+// WithStrictPassCode sets whether to check the passcode to be a number of the
+// Digits before the validation (Default: true).
+//
+// The check rejects the obviously invalid input, such as junk or wrong length
+// passcodes, early without computing any HMAC. For the encodings other than the
+// default set by WithValidationEncoder(), only the length is checked. Empty
+// passcodes are rejected regardless.
+func WithStrictPassCode(strict bool) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		opts.passCodeNotStrict = !strict
+
+		return nil
+	}
+}
+
+// WithValidationEncoder sets the encoding of the passcodes to generate and
+// validate, such as otp.EncoderSteam for the Steam Guard codes. The default is
+// otp.EncoderDefault, the decimal digits.
+//
+// The passcodes are the Digits long in the characters of the encoding. Note that
+// PassCodeValue() and WithChecksum() are for the decimal digits only.
+//
+// It returns an error if the encoder is unknown.
+func WithValidationEncoder(encoder otp.Encoder) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		if encoder != otp.EncoderDefault && encoder != otp.EncoderSteam {
+			return errors.Errorf("unsupported encoder: %q", encoder)
+		}
+
+		opts.encoder = encoder
 
 		return nil
 	}
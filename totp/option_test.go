@@ -1,8 +1,13 @@
 package totp
 
 import (
+	"fmt"
+	"math"
 	"testing"
+	"time"
 
+	origOtp "github.com/pquerna/otp"
+	origTotp "github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,12 +19,32 @@ func TestOption_nil_input(t *testing.T) {
 	t.Parallel()
 
 	for index, fnOpt := range []Option{
+		WithAcceptedPeriods(30),
 		WithAlgorithm(Algorithm("SHA1")),
+		WithChecksum(true),
 		WithECDH(nil, nil, ""),
 		WithECDHKDF(nil),
+		WithECDHStrict(nil, nil, ""),
+		WithIssuerInPath(false),
+		WithLabelSeparator(":"),
+		WithLenientIssuer(),
+		WithLenientPassCode(),
+		WithLogger(nil),
+		WithLowercaseAlgorithmURI(),
+		WithMetrics(nil),
+		WithMinEntropy(4),
+		WithMinimalURI(),
+		WithNow(time.Time{}),
 		WithPeriod(30),
+		WithRedactedStringer(),
+		WithSecretBase32Length(32, false),
+		WithSecretPepper([]byte("pepper")),
 		WithSecretSize(128),
 		WithSkew(0),
+		WithSkewCenter(0),
+		WithSkewDuration(time.Minute),
+		WithStrictPassCode(true),
+		WithValidationEncoder(origOtp.EncoderSteam),
 		WithDigits(DigitsSix),
 	} {
 		// functions shuold return error when nil input is given.
@@ -31,3 +56,692 @@ func TestOption_nil_input(t *testing.T) {
 			"Test %d: unexpected error message", index+1)
 	}
 }
+
+// ----------------------------------------------------------------------------
+//  WithAcceptedPeriods()
+// ----------------------------------------------------------------------------
+
+func TestWithAcceptedPeriods(t *testing.T) {
+	t.Parallel()
+
+	const base = "otpauth://totp/Example.com:alice@example.com?issuer=Example.com&" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	// Import
+	key, err := GenKeyFromURI(base+"&period=30", WithAcceptedPeriods(30))
+	require.NoError(t, err)
+
+	_, err = GenKeyFromURI(base, WithAcceptedPeriods(30))
+	require.NoError(t, err, "missing period should be treated as default")
+
+	_, err = GenKeyFromURI(base+"&period=15", WithAcceptedPeriods(30))
+	require.ErrorIs(t, err, ErrUnsupportedPeriod, "period out of the policy should be rejected")
+
+	_, err = GenKeyFromURI(base+"&period=15", WithAcceptedPeriods(15, 30))
+	require.NoError(t, err)
+
+	_, err = GenKeyFromURI(base + "&period=15")
+	require.NoError(t, err, "any period should be accepted by default")
+
+	// Validation
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+	require.True(t, key.Validate(passcode))
+	require.NoError(t, key.IsValid())
+
+	key.Options.Period = 60
+
+	require.ErrorIs(t, key.IsValid(), ErrUnsupportedPeriod)
+	require.ErrorIs(t, key.Verify(passcode, time.Now()).Err, ErrUnsupportedPeriod)
+
+	ok, err := ValidateE(passcode, key.Secret.Base32(), key.Options)
+	require.ErrorIs(t, err, ErrUnsupportedPeriod)
+	require.False(t, ok)
+
+	validator, err := key.Compile()
+	require.ErrorIs(t, err, ErrUnsupportedPeriod)
+	require.Nil(t, validator)
+
+	// Bad input
+	//nolint:exhaustruct // allow missing fields
+	opts := &Options{}
+
+	require.ErrorContains(t, WithAcceptedPeriods()(opts), "no accepted period given")
+	require.ErrorIs(t, WithAcceptedPeriods(30, 0)(opts), ErrZeroPeriod)
+}
+
+// ----------------------------------------------------------------------------
+//  WithChecksum()
+// ----------------------------------------------------------------------------
+
+func TestWithChecksum(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithChecksum(true), WithNow(fixedTime), WithSkew(0))
+	require.NoError(t, err)
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+	require.Len(t, passcode, 7, "checksum digit should be appended")
+
+	raw, err := key.passCodeRaw(fixedTime)
+	require.NoError(t, err)
+	require.Equal(t, appendChecksum(raw), passcode)
+
+	require.True(t, key.Validate(passcode))
+	require.False(t, key.Validate(raw), "passcode without checksum should be invalid")
+
+	tampered := passcode[:6] + string('0'+(passcode[6]-'0'+1)%10)
+	require.ErrorIs(t, key.Verify(tampered, fixedTime).Err, ErrMalformedPasscode)
+
+	pin, ok := key.ValidateAppended("1234"+passcode, 4)
+	require.True(t, ok)
+	require.Equal(t, "1234", pin)
+
+	require.NotEmpty(t, key.ValidWindows(passcode, fixedTime.Add(-time.Hour), fixedTime.Add(time.Hour)))
+
+	challenged, err := key.PassCodeChallenge("tx-1234")
+	require.NoError(t, err)
+	require.Len(t, challenged, 7)
+	require.True(t, key.ValidateChallenge(challenged, "tx-1234"))
+
+	// Disabled by default
+	key.Options.checksum = false
+
+	require.True(t, key.Validate(raw))
+	require.False(t, key.Validate(passcode))
+}
+
+func TestLuhnDigit_golden(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		number string
+		expect byte
+	}{
+		{"123456", '6'},
+		{"000000", '0'},
+		{"7992739871", '3'},
+	} {
+		digit, ok := luhnDigit(test.number)
+
+		require.True(t, ok)
+		require.Equal(t, string(test.expect), string(digit), "number: %s", test.number)
+	}
+
+	_, ok := luhnDigit("12a456")
+	require.False(t, ok)
+
+	for _, passcode := range []string{"", "6", "12a4566", "1234567"} {
+		code, err := stripChecksum(passcode)
+
+		require.Error(t, err, "passcode: %q", passcode)
+		require.Empty(t, code)
+	}
+
+	require.Equal(t, "12a456", appendChecksum("12a456"), "non-numeric passcode should be as is")
+}
+
+// ----------------------------------------------------------------------------
+//  WithECDH()
+// ----------------------------------------------------------------------------
+
+func TestWithECDH_empty_context(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	opts := &Options{}
+
+	err := WithECDH(nil, nil, "")(opts)
+
+	require.Error(t, err, "empty context should return error")
+	require.Contains(t, err.Error(), "empty context for ECDH")
+}
+
+// ----------------------------------------------------------------------------
+//  WithECDHStrict()
+// ----------------------------------------------------------------------------
+
+func TestWithECDHStrict_short_context(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // allow missing fields
+	opts := &Options{}
+
+	err := WithECDHStrict(nil, nil, "short")(opts)
+
+	require.Error(t, err, "context shorter than the recommended length should return error")
+	require.Contains(t, err.Error(), "context for ECDH is too short")
+	require.Empty(t, opts.ecdhCtx, "context should not be set on error")
+
+	ctx := "example.com alice@example.com bob@example.com TOTP secret v1"
+
+	require.NoError(t, WithECDHStrict(nil, nil, ctx)(opts))
+	require.Equal(t, ctx, opts.ecdhCtx)
+}
+
+// ----------------------------------------------------------------------------
+//  WithLabelSeparator()
+// ----------------------------------------------------------------------------
+
+func TestWithLabelSeparator(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example Co", "alice@example.com", WithLabelSeparator("|"))
+	require.NoError(t, err)
+
+	uri := key.URI()
+	require.Contains(t, uri, "otpauth://totp/Example%20Co%7Calice@example.com?")
+
+	// Standard parsing does not recognize the separator
+	keyImported, err := GenKeyFromURI(uri)
+	require.NoError(t, err)
+	require.Equal(t, "Example Co|alice@example.com", keyImported.Options.AccountName,
+		"the whole label should be the account name")
+
+	// Parser counterpart
+	keyImported, err = GenKeyFromURI(uri, WithLabelSeparator("|"))
+	require.NoError(t, err)
+	require.Equal(t, "Example Co", keyImported.Options.Issuer)
+	require.Equal(t, "alice@example.com", keyImported.Options.AccountName)
+
+	// Default is unchanged
+	key, err = GenerateKey("Example Co", "alice@example.com")
+	require.NoError(t, err)
+	require.Contains(t, key.URI(), "otpauth://totp/Example%20Co:alice@example.com?")
+
+	//nolint:exhaustruct // allow missing fields
+	opts := &Options{}
+	require.ErrorContains(t, WithLabelSeparator("")(opts), "empty label separator")
+}
+
+// ----------------------------------------------------------------------------
+//  WithLenientPassCode()
+// ----------------------------------------------------------------------------
+
+func TestWithLenientPassCode(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(fixedTime))
+	require.NoError(t, err)
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	spaced := passcode[:3] + " " + passcode[3:]
+	hyphened := passcode[:3] + "-" + passcode[3:]
+
+	require.False(t, key.Validate(spaced), "spaces should be rejected by default")
+	require.False(t, key.Validate(hyphened), "hyphens should be rejected by default")
+
+	require.NoError(t, WithLenientPassCode()(&key.Options))
+
+	for _, input := range []string{spaced, hyphened, " " + spaced + "\t"} {
+		require.True(t, key.Validate(input), "input %q should be normalized", input)
+		require.True(t, Validate(input, key.Secret.Base32(), key.Options))
+	}
+
+	require.False(t, key.Validate("123-45a"), "normalized passcode should still be checked")
+	require.False(t, key.ValidateNotInHistory(spaced, []string{hyphened}),
+		"history entries should be normalized as well")
+}
+
+// ----------------------------------------------------------------------------
+//  WithLowercaseAlgorithmURI()
+// ----------------------------------------------------------------------------
+
+func TestWithLowercaseAlgorithmURI(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithAlgorithm(Algorithm("SHA256")), WithLowercaseAlgorithmURI())
+	require.NoError(t, err)
+
+	uri := key.URI()
+	require.Contains(t, uri, "algorithm=sha256")
+	require.NoError(t, URI(uri).Check(), "lowercase algorithm should pass the check")
+
+	// Round-trip
+	keyImported, err := GenKeyFromURI(uri)
+	require.NoError(t, err)
+	require.Equal(t, Algorithm("SHA256"), keyImported.Options.Algorithm, "it should be normalized to uppercase")
+	require.True(t, key.Secret.Equal(keyImported.Secret))
+
+	// Default is unchanged
+	key, err = GenerateKey("Example.com", "alice@example.com", WithAlgorithm(Algorithm("SHA256")))
+	require.NoError(t, err)
+	require.Contains(t, key.URI(), "algorithm=SHA256")
+}
+
+// ----------------------------------------------------------------------------
+//  WithMinEntropy()
+// ----------------------------------------------------------------------------
+
+func TestWithMinEntropy(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithMinEntropy(6))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, key.Secret.Entropy(), 6.0)
+
+	// Out of range
+	for _, bits := range []float64{0, -1, 8.1, math.NaN()} {
+		//nolint:exhaustruct // allow missing fields
+		opts := &Options{}
+		require.ErrorContains(t, WithMinEntropy(bits)(opts), "invalid minimum entropy",
+			"bits %v should be an error", bits)
+	}
+}
+
+//nolint:paralleltest // disable parallel test due to monkey patching during test
+func TestWithMinEntropy_weak_rand(t *testing.T) {
+	// Backup and defer restore
+	oldTotpGenerate := totpGenerate
+	defer func() {
+		totpGenerate = oldTotpGenerate
+	}()
+
+	// Mock totpGenerate to return a secret of all zeros
+	calls := 0
+	totpGenerate = func(_ origTotp.GenerateOpts) (*origOtp.Key, error) {
+		calls++
+
+		//nolint:lll // ignore long line length due to URI
+		return origOtp.NewKeyFromURL("otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&digits=6&issuer=Example.com&period=30&secret=AAAAAAAAAAAAAAAAAAAAAAAAAA")
+	}
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithMinEntropy(4))
+
+	require.ErrorIs(t, err, ErrLowEntropy)
+	require.Nil(t, key)
+	require.Equal(t, minEntropyAttempts, calls, "it should retry before giving up")
+
+	// Default is off
+	calls = 0
+
+	key, err = GenerateKey("Example.com", "alice@example.com")
+
+	require.NoError(t, err)
+	require.NotNil(t, key)
+	require.Equal(t, 1, calls)
+}
+
+// ----------------------------------------------------------------------------
+//  WithNow()
+// ----------------------------------------------------------------------------
+
+func TestWithNow(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(fixedTime), WithSkew(0))
+	require.NoError(t, err)
+
+	expect, err := key.PassCodeCustom(fixedTime)
+	require.NoError(t, err)
+
+	actual, err := key.PassCode()
+	require.NoError(t, err)
+
+	require.Equal(t, expect, actual, "PassCode should use the fixed time")
+	require.True(t, key.Validate(expect), "Validate should use the fixed time")
+	require.True(t, Validate(expect, key.Secret.Base32(), key.Options))
+
+	// Other time
+	passcodeNow, err := key.PassCodeCustom(time.Now())
+	require.NoError(t, err)
+
+	if passcodeNow != expect {
+		require.False(t, key.Validate(passcodeNow), "current time should not be used")
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  WithRedactedStringer()
+// ----------------------------------------------------------------------------
+
+func TestWithRedactedStringer(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithRedactedStringer())
+	require.NoError(t, err)
+
+	secret := key.Secret.Base32()
+
+	for _, out := range []string{key.String(), fmt.Sprintf("%v", key), fmt.Sprintf("%s", key)} {
+		require.NotContains(t, out, secret)
+		require.Contains(t, out, "secret=REDACTED")
+		require.Contains(t, out, "otpauth://totp/Example.com:alice@example.com?")
+	}
+
+	require.Contains(t, key.URI(), "secret="+secret, "URI should return the full value")
+
+	// Default is unchanged
+	key, err = GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, key.URI(), key.String())
+}
+
+// ----------------------------------------------------------------------------
+//  WithSecretBase32Length()
+// ----------------------------------------------------------------------------
+
+func TestWithSecretBase32Length(t *testing.T) {
+	t.Parallel()
+
+	for index, test := range []struct {
+		chars  int
+		padded bool
+		size   uint
+	}{
+		{26, false, 16},
+		{29, false, 18},
+		{31, false, 19},
+		{32, false, 20},
+		{103, false, 64},
+		{32, true, 20},
+		{40, true, 25},
+		{208, true, 130},
+	} {
+		key, err := GenerateKey("Example.com", "alice@example.com", WithSecretBase32Length(test.chars, test.padded))
+		require.NoError(t, err, "test #%d: failed to generate key", index+1)
+
+		require.Equal(t, test.size, key.Options.SecretSize, "test #%d: unexpected size", index+1)
+		require.Equal(t, int(test.size), key.Secret.Len(), "test #%d: unexpected length of secret", index+1)
+
+		encoded := key.Secret.Base32()
+		if test.padded {
+			encoded = key.Secret.Base32Padded()
+		}
+
+		require.Len(t, encoded, test.chars, "test #%d: unexpected length of base32", index+1)
+	}
+}
+
+func TestWithSecretBase32Length_not_achievable(t *testing.T) {
+	t.Parallel()
+
+	for index, test := range []struct {
+		chars  int
+		padded bool
+		expect string
+	}{
+		{0, false, "it should be positive"},
+		{-8, true, "it should be positive"},
+		{27, false, "the closest achievable length is 26"},
+		{30, false, "the closest achievable length is 29"},
+		{33, true, "it should be a multiple of 8"},
+		{24, false, "smaller than 16 bytes"},
+		{24, true, "smaller than 16 bytes"},
+	} {
+		//nolint:exhaustruct // allow missing fields
+		opts := &Options{}
+
+		err := WithSecretBase32Length(test.chars, test.padded)(opts)
+
+		require.Error(t, err, "test #%d should fail", index+1)
+		require.Contains(t, err.Error(), test.expect, "test #%d: unexpected error message", index+1)
+		require.Zero(t, opts.SecretSize, "test #%d: size should not be set on error", index+1)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  WithSecretPepper()
+// ----------------------------------------------------------------------------
+
+func TestWithSecretPepper(t *testing.T) {
+	t.Parallel()
+
+	pepper := []byte("site-wide pepper from the app config")
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithSecretPepper(pepper), WithNow(fixedTime), WithSecretSize(20))
+	require.NoError(t, err)
+
+	// Derived secret
+	expect, err := hkdfSHA256(key.Secret, pepper, []byte("go-totp pepper v1"), 20)
+	require.NoError(t, err)
+	require.Equal(t, Secret(expect), key.PepperedSecret())
+	require.False(t, key.Secret.Equal(key.PepperedSecret()))
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	// Generation and validation agree
+	require.True(t, key.Validate(passcode))
+	require.True(t, Validate(passcode, key.Secret.Base32(), key.Options), "package level should be peppered as well")
+
+	validator, err := key.Compile()
+	require.NoError(t, err)
+	require.True(t, validator.Validate(passcode))
+
+	// Same as the plain key with the derived secret. Such as the authenticator
+	// apps with the URI.
+	keyApp, err := GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+	require.True(t, keyApp.Secret.Equal(key.PepperedSecret()), "URI should carry the derived secret")
+
+	passcodeApp, err := keyApp.PassCodeCustom(fixedTime)
+	require.NoError(t, err)
+	require.Equal(t, passcode, passcodeApp)
+
+	// Without or with a different pepper
+	keyNoPepper := *key
+	keyNoPepper.Options.pepper = nil
+
+	passcodeNoPepper, err := keyNoPepper.PassCode()
+	require.NoError(t, err)
+
+	if passcodeNoPepper != passcode {
+		require.False(t, keyNoPepper.Validate(passcode), "passcode should not match without the pepper")
+	}
+
+	// PEM keeps the secret itself
+	pemKey, err := key.PEM()
+	require.NoError(t, err)
+
+	keyPEM, err := GenKeyFromPEM(pemKey)
+	require.NoError(t, err)
+	require.True(t, keyPEM.Secret.Equal(key.Secret))
+	require.NotContains(t, pemKey, string(pepper))
+
+	// Empty pepper
+	//nolint:exhaustruct // allow missing fields
+	opts := &Options{}
+	require.ErrorContains(t, WithSecretPepper(nil)(opts), "empty secret pepper")
+
+	// Copied
+	pepperMutable := []byte("pepper")
+	require.NoError(t, WithSecretPepper(pepperMutable)(opts))
+
+	pepperMutable[0] = 'X'
+	require.Equal(t, []byte("pepper"), opts.pepper)
+}
+
+// ----------------------------------------------------------------------------
+//  WithSkewCenter()
+// ----------------------------------------------------------------------------
+
+func TestWithSkewCenter(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithNow(fixedTime), WithSkew(1), WithSkewCenter(3))
+	require.NoError(t, err)
+
+	period := time.Duration(key.Options.Period) * time.Second
+
+	// Windows within [center-skew, center+skew] = [2, 4] are valid
+	for offset := 2; offset <= 4; offset++ {
+		passcode, err := key.PassCodeCustom(fixedTime.Add(time.Duration(offset) * period))
+		require.NoError(t, err)
+
+		require.True(t, key.Validate(passcode), "offset %d should be valid", offset)
+		require.True(t, Validate(passcode, key.Secret.Base32(), key.Options))
+
+		result := key.Verify(passcode, fixedTime)
+		require.True(t, result.Valid)
+		require.Equal(t, offset, result.Offset)
+	}
+
+	// The window of the current time is out of the shifted range
+	passcodeNow, err := key.PassCodeCustom(fixedTime)
+	require.NoError(t, err)
+
+	if !key.ValidateCustom(passcodeNow, fixedTime.Add(-3*period)) {
+		t.Fatal("passcode should be valid if the validation time is shifted back by the center")
+	}
+
+	for offset := 2; offset <= 4; offset++ {
+		passcode, err := key.PassCodeCustom(fixedTime.Add(time.Duration(offset) * period))
+		require.NoError(t, err)
+
+		if passcode == passcodeNow {
+			t.Skip("passcodes collided by chance")
+		}
+	}
+
+	require.False(t, key.Validate(passcodeNow), "current window should not be valid")
+
+	// Default center is zero
+	key, err = GenerateKey("Example.com", "alice@example.com", WithNow(fixedTime))
+	require.NoError(t, err)
+	require.Zero(t, key.Options.skewCenter)
+}
+
+// ----------------------------------------------------------------------------
+//  WithSkewDuration()
+// ----------------------------------------------------------------------------
+
+func TestWithSkewDuration(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		period    uint
+		tolerance time.Duration
+		expect    uint
+	}{
+		{30, 60 * time.Second, 2},
+		{30, 45 * time.Second, 2}, // halves are rounded up
+		{30, 44 * time.Second, 1},
+		{30, 14 * time.Second, 0},
+		{30, 0, 0},
+		{60, 60 * time.Second, 1},
+		{0, 90 * time.Second, 3}, // zero period is the default
+	} {
+		//nolint:exhaustruct // allow missing fields
+		opts := &Options{Period: test.period}
+
+		require.NoError(t, WithSkewDuration(test.tolerance)(opts))
+		require.Equal(t, test.expect, opts.Skew, "period: %d, tolerance: %s", test.period, test.tolerance)
+	}
+
+	// The order of WithPeriod() does not matter
+	for _, opts := range [][]Option{
+		{WithPeriod(60), WithSkewDuration(2 * time.Minute)},
+		{WithSkewDuration(2 * time.Minute), WithPeriod(60)},
+	} {
+		key, err := GenerateKey("Example.com", "alice@example.com", opts...)
+		require.NoError(t, err)
+		require.Equal(t, uint(2), key.Options.Skew)
+	}
+
+	// The period of the URI is used
+	key, err := GenKeyFromURI("otpauth://totp/Example.com:alice@example.com?issuer=Example.com&"+
+		"period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3", WithSkewDuration(2*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, uint(2), key.Options.Skew)
+
+	// A later WithSkew() overrides it
+	key, err = GenerateKey("Example.com", "alice@example.com", WithSkewDuration(2*time.Minute), WithSkew(1))
+	require.NoError(t, err)
+	require.Equal(t, uint(1), key.Options.Skew)
+
+	//nolint:exhaustruct // allow missing fields
+	err = WithSkewDuration(-time.Second)(&Options{})
+	require.ErrorContains(t, err, "should not be negative")
+}
+
+// ----------------------------------------------------------------------------
+//  WithStrictPassCode()
+// ----------------------------------------------------------------------------
+
+func TestWithStrictPassCode(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	result := key.Verify("12345", time.Now())
+	require.False(t, result.Valid)
+	require.ErrorIs(t, result.Err, ErrMalformedPasscode, "short passcode should be rejected before HMAC")
+
+	require.NoError(t, WithStrictPassCode(false)(&key.Options))
+
+	result = key.Verify("12345", time.Now())
+	require.False(t, result.Valid)
+	require.NoError(t, result.Err, "the check should be disabled")
+
+	result = key.Verify(" ", time.Now())
+	require.ErrorIs(t, result.Err, ErrEmptyPasscode, "empty passcode should be rejected regardless")
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+	require.True(t, key.Validate(passcode), "valid passcode should still validate")
+}
+
+// ----------------------------------------------------------------------------
+//  WithValidationEncoder()
+// ----------------------------------------------------------------------------
+
+func TestWithValidationEncoder(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1111111109, 0).UTC()
+
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithValidationEncoder(origOtp.EncoderSteam), WithNow(now))
+	require.NoError(t, err)
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	expect, err := origTotp.GenerateCodeCustom(key.Secret.Base32(), now, origTotp.ValidateOpts{
+		Digits:    origOtp.DigitsSix,
+		Algorithm: origOtp.AlgorithmSHA1,
+		Period:    30,
+		Encoder:   origOtp.EncoderSteam,
+	})
+	require.NoError(t, err)
+	require.Equal(t, expect, passcode, "passcode should be in the Steam encoding")
+
+	// Round trip with the strict check
+	require.True(t, key.Validate(passcode))
+	require.True(t, key.Verify(passcode, now).Valid)
+	require.True(t, key.ValidateInRange(passcode, now.Add(-time.Hour), now))
+	require.NotEmpty(t, key.ValidWindows(passcode, now.Add(-time.Hour), now.Add(time.Hour)))
+	require.True(t, key.ValidateVerbose(passcode)[1].Match)
+
+	isValid, offset := key.ValidateResync(passcode)
+	require.True(t, isValid)
+	require.Zero(t, offset)
+
+	result := key.Verify(passcode[1:], now)
+	require.ErrorIs(t, result.Err, ErrMalformedPasscode, "wrong length passcode should be rejected")
+
+	// The default encoder should not accept it
+	require.NoError(t, WithValidationEncoder(origOtp.EncoderDefault)(&key.Options))
+	require.False(t, key.Validate(passcode))
+
+	err = WithValidationEncoder(origOtp.Encoder("unknown"))(&key.Options)
+	require.ErrorContains(t, err, "unsupported encoder")
+}
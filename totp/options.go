@@ -2,9 +2,17 @@ package totp
 
 import (
 	"crypto/ecdh"
+	"fmt"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
 	"github.com/zeebo/blake3"
 )
 
@@ -17,6 +25,19 @@ const (
 	OptionSkewDefault       = uint(1)           // ± 1 period of tolerance.
 )
 
+// pepperInfo is the HKDF info to stretch the secret with the pepper. See
+// WithSecretPepper().
+const pepperInfo = "go-totp pepper v1"
+
+// pepperOutLenMax is the maximum output length of HKDF-SHA256 in bytes. Longer
+// secrets are stretched to this length.
+const pepperOutLenMax = 255 * 32
+
+// OptionSecretSizeDefaultMD5 is the default secret size for the MD5 algorithm.
+// It is the natural size of the MD5 hash, which is also the minimum secret size
+// recommended in RFC-4226 (SecretSizeMin).
+const OptionSecretSizeDefaultMD5 = uint(16) // 16 Bytes.
+
 // OptionKDFDefault is the default key derivation function (KDF) for TOTP secret
 // key derivation from ECDH shared secret. The underlying KDF is BLAKE3.
 func OptionKDFDefault(secret, ctx []byte, outLen uint) ([]byte, error) {
@@ -47,6 +68,9 @@ func OptionKDFDefault(secret, ctx []byte, outLen uint) ([]byte, error) {
 // Options is a struct that holds the options for a TOTP key. Use SetDefault()
 // to set the default values.
 type Options struct {
+	// acceptedPeriods is the allowlist of the periods in seconds. Empty means
+	// unrestricted. See WithAcceptedPeriods().
+	acceptedPeriods []uint
 	// AccountName is the name of the secret key owner. (eg, email address)
 	AccountName string
 	// Algorithm to use for HMAC to generate the TOTP passcode.
@@ -55,6 +79,16 @@ type Options struct {
 	// Note that this is not the same hash algorithm used for the secret key
 	// generated via ECDH.
 	Algorithm Algorithm
+	// checksum appends the checksum digit to the passcodes if true. See
+	// WithChecksum().
+	checksum bool
+	// counter is the counter of the HOTP keys. Nil means the key is TOTP. See
+	// GenHOTPKeyFromURI().
+	counter *uint64
+	// Created is the time the key was generated in UTC. It is set by GenerateKey
+	// and stored in the PEM header and the URI. Zero if unknown, such as keys
+	// imported from the URIs of other apps. See Key.Age().
+	Created time.Time
 	// Digits to request TOTP code. DigitsSix or DigitsEight. (Default: DigitsSix)
 	Digits Digits
 	// Context used for generating TOTP secret from ECDH shared secret. If both
@@ -66,22 +100,77 @@ type Options struct {
 	// ECDH public key of the correspondent. If both ecdhPrivateKey and ecdhPublicKey
 	// are set, the secret will be generated from them.
 	ecdhPublicKey *ecdh.PublicKey
+	// encoder is the encoding of the passcodes. The default is the decimal digits.
+	// See WithValidationEncoder().
+	encoder otp.Encoder
 	// Issuer is the name of the issuer of the secret key.
 	// (eg, organization, company, domain)
 	Issuer string
+	// issuerNotInPath omits the issuer from the path (label) of the URI if true.
+	// It is inverted so that the zero value keeps the issuer in the path.
+	issuerNotInPath bool
 	// kdf is the key derivation function used to derive the TOTP secret key if the
 	// ECDH private and public keys are set.
 	kdf func(secret, ctx []byte, outLen uint) ([]byte, error)
+	// labelSeparator is the separator between the issuer and the account name
+	// in the label of the URI. Empty means the standard colon. See
+	// WithLabelSeparator().
+	labelSeparator string
+	// lenientIssuer relaxes the issuer check on parsing URIs. If true, the
+	// issuers in the path and the query do not need to match.
+	lenientIssuer bool
+	// lenientPassCode removes the spaces and hyphens in the passcode before the
+	// validation if true. See WithLenientPassCode().
+	lenientPassCode bool
+	// logger logs the events of the package. Nil means no logging. See
+	// WithLogger().
+	logger Logger
+	// lowercaseAlgorithm emits the algorithm in lowercase in the URI if true.
+	// See WithLowercaseAlgorithmURI().
+	lowercaseAlgorithm bool
+	// metrics counts the validation events. Nil means no counting. See
+	// WithMetrics().
+	metrics Metrics
+	// minEntropy is the minimum Shannon entropy in bits per byte that the
+	// generated secret must have. Zero disables the check. See WithMinEntropy().
+	minEntropy float64
+	// minimalURI omits the algorithm, digits and period parameters from the URI
+	// if they are equal to the default values.
+	minimalURI bool
+	// now is the fixed time to use instead of the current time if not zero. See
+	// WithNow().
+	now time.Time
+	// passCodeNotStrict disables the check of the passcode to be a number of the
+	// Digits before the validation. It is inverted so that the zero value keeps
+	// the check. See WithStrictPassCode().
+	passCodeNotStrict bool
+	// pepper is the site-wide secret to stretch the secret with before HMAC. Nil
+	// means no pepper. See WithSecretPepper().
+	pepper []byte
 	// Period is the number of seconds a TOTP hash is valid for.
 	// (Default: 30 seconds)
 	Period uint
-	// SecretSize is the size of the generated Secret. (Default: 128 bytes)
+	// redactedStringer replaces the secret in Key.String() with "REDACTED" if
+	// true. See WithRedactedStringer().
+	redactedStringer bool
+	// SecretSize is the size of the generated Secret.
+	// (Default: 128 bytes, 16 bytes for MD5)
 	SecretSize uint
 	// Skew is the periods before or after the current time to allow. (Default: 1)
 	//
 	// Value of 1 allows up to Period of either side of the specified time.
 	// Values greater than 1 are likely sketchy.
 	Skew uint
+	// skewCenter is the number of periods to shift the center of the validation
+	// window from the validation time. (Default: 0)
+	//
+	// It is used to compensate a persistent clock drift of the client. See
+	// WithSkewCenter() and Key.ValidateResync() for details.
+	skewCenter int
+	// skewDuration is the tolerance set by WithSkewDuration(). It is resolved
+	// to Skew after all the options are applied, so that it does not depend on
+	// the order of WithPeriod(). Nil if not set.
+	skewDuration *time.Duration
 }
 
 // ----------------------------------------------------------------------------
@@ -104,10 +193,319 @@ func NewOptions(issuer, accountName string) (*Options, error) {
 	return opt, nil
 }
 
+// ResolveOptions returns the options that GenerateKey would use with the same
+// arguments, with the default values and the given options applied. No key or
+// secret is generated. Such as to preview the effective configuration in the
+// settings page before generating the key.
+//
+// The ECDH keys, context and the KDF are cleared from the returned options
+// since they are the secret material. The creation time is left zero as it is
+// set on generation. It returns an error if the issuer or account name is empty
+// or any of the options fails to apply, as GenerateKey does.
+func ResolveOptions(issuer, accountName string, opts ...Option) (Options, error) {
+	resolved, err := resolveOptions(issuer, accountName, opts...)
+	if err != nil {
+		return Options{}, err
+	}
+
+	resolved.ecdhCtx = ""
+	resolved.ecdhPrivateKey = nil
+	resolved.ecdhPublicKey = nil
+	resolved.kdf = nil
+
+	return *resolved, nil
+}
+
+// resolveOptions creates the options with the default values and applies the
+// given options in order. It is the common part of the key generation functions,
+// such as GenerateKey and GenKeyFromMasterKey, and ResolveOptions.
+func resolveOptions(issuer, accountName string, opts ...Option) (*Options, error) {
+	// Create options with default values.
+	optsCustom, err := NewOptions(issuer, accountName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create options during key generation")
+	}
+
+	// Apply custom options.
+	for _, fn := range opts {
+		if err := fn(optsCustom); err != nil {
+			return nil, errors.Wrap(err, "failed to apply custom options")
+		}
+	}
+
+	optsCustom.resolveSkewDuration()
+
+	return optsCustom, nil
+}
+
+// withOptions returns the option to replace the options with the given ones.
+// The undefined fields are set to their default values. It is for the functions
+// that take the Options object to be resolved the same way as the Option ones.
+func withOptions(options Options) Option {
+	return func(opts *Options) error {
+		if opts == nil {
+			return errors.New(errNilOptions)
+		}
+
+		*opts = options
+		opts.SetDefault()
+
+		return nil
+	}
+}
+
 // ----------------------------------------------------------------------------
 //  Methods
 // ----------------------------------------------------------------------------
 
+// Diff returns the differences between the options and the other options.
+//
+// The returned map is keyed by the field name and the value is a pair of the
+// string representation of the field in [old, new] order. Where "old" is the
+// value of the receiver and "new" is the value of the other. Fields that are
+// equal are not included, so an empty map means no difference.
+//
+// Only the exported fields are compared. The ECDH keys, context and the KDF
+// are excluded. Also the creation time (Created) is excluded since it is a
+// metadata of the key and not a parameter of the passcode.
+func (opts *Options) Diff(other Options) map[string][2]string {
+	diff := make(map[string][2]string)
+
+	for _, field := range []struct {
+		name     string
+		old, new string
+	}{
+		{"AccountName", opts.AccountName, other.AccountName},
+		{"Algorithm", opts.Algorithm.String(), other.Algorithm.String()},
+		{"Digits", opts.Digits.String(), other.Digits.String()},
+		{"Issuer", opts.Issuer, other.Issuer},
+		{"Period", strconv.FormatUint(uint64(opts.Period), 10), strconv.FormatUint(uint64(other.Period), 10)},
+		{"SecretSize", strconv.FormatUint(uint64(opts.SecretSize), 10), strconv.FormatUint(uint64(other.SecretSize), 10)},
+		{"Skew", strconv.FormatUint(uint64(opts.Skew), 10), strconv.FormatUint(uint64(other.Skew), 10)},
+	} {
+		if field.old != field.new {
+			diff[field.name] = [2]string{field.old, field.new}
+		}
+	}
+
+	return diff
+}
+
+// GoString is an implementation of the fmt.GoStringer interface. It is used by
+// the "%#v" format and redacts the secret-bearing fields, such as the ECDH
+// private key and the pepper. So the options are safe to be printed for
+// debugging. The other fields are shown as is.
+func (opts Options) GoString() string {
+	kdf := "nil"
+	if opts.kdf != nil {
+		kdf = "func(...)"
+	}
+
+	counter := "nil"
+	if opts.counter != nil {
+		counter = strconv.FormatUint(*opts.counter, 10)
+	}
+
+	skewDuration := "nil"
+	if opts.skewDuration != nil {
+		skewDuration = opts.skewDuration.String()
+	}
+
+	fields := []string{
+		fmt.Sprintf("acceptedPeriods:%#v", opts.acceptedPeriods),
+		fmt.Sprintf("AccountName:%#v", opts.AccountName),
+		fmt.Sprintf("Algorithm:%#v", opts.Algorithm),
+		fmt.Sprintf("checksum:%#v", opts.checksum),
+		"counter:" + counter,
+		fmt.Sprintf("Created:%#v", opts.Created),
+		fmt.Sprintf("Digits:%#v", opts.Digits),
+		fmt.Sprintf("ecdhCtx:%#v", opts.ecdhCtx),
+		"ecdhPrivateKey:" + redactedGoString(opts.ecdhPrivateKey == nil, opts.ecdhPrivateKey),
+		fmt.Sprintf("ecdhPublicKey:%#v", opts.ecdhPublicKey),
+		fmt.Sprintf("encoder:%#v", opts.encoder),
+		fmt.Sprintf("Issuer:%#v", opts.Issuer),
+		fmt.Sprintf("issuerNotInPath:%#v", opts.issuerNotInPath),
+		"kdf:" + kdf,
+		fmt.Sprintf("labelSeparator:%#v", opts.labelSeparator),
+		fmt.Sprintf("lenientIssuer:%#v", opts.lenientIssuer),
+		fmt.Sprintf("lenientPassCode:%#v", opts.lenientPassCode),
+		fmt.Sprintf("logger:%#v", opts.logger),
+		fmt.Sprintf("lowercaseAlgorithm:%#v", opts.lowercaseAlgorithm),
+		fmt.Sprintf("metrics:%#v", opts.metrics),
+		fmt.Sprintf("minEntropy:%#v", opts.minEntropy),
+		fmt.Sprintf("minimalURI:%#v", opts.minimalURI),
+		fmt.Sprintf("now:%#v", opts.now),
+		fmt.Sprintf("passCodeNotStrict:%#v", opts.passCodeNotStrict),
+		"pepper:" + redactedGoString(len(opts.pepper) == 0, opts.pepper),
+		fmt.Sprintf("Period:%#v", opts.Period),
+		fmt.Sprintf("redactedStringer:%#v", opts.redactedStringer),
+		fmt.Sprintf("SecretSize:%#v", opts.SecretSize),
+		fmt.Sprintf("Skew:%#v", opts.Skew),
+		fmt.Sprintf("skewCenter:%#v", opts.skewCenter),
+		"skewDuration:" + skewDuration,
+	}
+
+	return "totp.Options{" + strings.Join(fields, ", ") + "}"
+}
+
+// labelSep returns the label separator of the URI. Which is a colon unless
+// set by WithLabelSeparator().
+func (opts *Options) labelSep() string {
+	if opts.labelSeparator == "" {
+		return ":"
+	}
+
+	return opts.labelSeparator
+}
+
+// log returns the logger set by WithLogger() if any. Otherwise a no-op logger.
+func (opts *Options) log() Logger {
+	if opts.logger == nil {
+		return nopLogger{}
+	}
+
+	return opts.logger
+}
+
+// otpOpts returns the options for the underlying OTP library. It is shared by
+// the passcode generation and validation so that they always agree.
+func (opts *Options) otpOpts() totp.ValidateOpts {
+	return totp.ValidateOpts{
+		Period:    opts.Period,
+		Skew:      opts.Skew,
+		Digits:    opts.Digits.OTPDigits(),
+		Algorithm: opts.Algorithm.OTPAlgorithm(),
+		Encoder:   opts.encoder,
+	}
+}
+
+// checkTOTP returns an error wrapping ErrHOTPKey if the key is HOTP. Such as to
+// reject the HOTP keys before generating or validating the TOTP passcodes.
+func (opts *Options) checkTOTP() error {
+	if opts.counter != nil {
+		return errors.Wrapf(ErrHOTPKey, "counter %d", *opts.counter)
+	}
+
+	return nil
+}
+
+// otpType returns the type of the OTP of the key. HOTP if the counter is set,
+// otherwise TOTP.
+func (opts *Options) otpType() OTPType {
+	if opts.counter != nil {
+		return OTPTypeHOTP
+	}
+
+	return OTPTypeTOTP
+}
+
+// pepperSecret returns the secret to compute the HMAC of the passcodes with. It
+// is the secret itself unless a pepper is set by WithSecretPepper(). See the
+// option for the derivation.
+func (opts *Options) pepperSecret(secret Secret) Secret {
+	if len(opts.pepper) == 0 || len(secret) == 0 {
+		return secret
+	}
+
+	outLen := uint(min(len(secret), pepperOutLenMax))
+
+	peppered, err := hkdfSHA256(secret, opts.pepper, []byte(pepperInfo), outLen)
+	if err != nil {
+		// Unreachable since the output length is in range
+		return secret
+	}
+
+	return peppered
+}
+
+// pepperSecretBase32 is similar to pepperSecret but for the base32 encoded
+// secret. Malformed secrets are returned as is to be reported by the caller.
+func (opts *Options) pepperSecretBase32(secret string) string {
+	if len(opts.pepper) == 0 {
+		return secret
+	}
+
+	decoded, err := decodeBase32Tolerant(secret)
+	if err != nil {
+		return secret
+	}
+
+	return opts.pepperSecret(decoded).Base32()
+}
+
+// preparePassCode normalizes the passcode to validate and checks it before
+// computing any HMAC. If WithChecksum() is set, the checksum digit is verified
+// and removed. It returns the normalized passcode or an error wrapping
+// ErrEmptyPasscode or ErrMalformedPasscode.
+func (opts *Options) preparePassCode(passcode string) (string, error) {
+	passcode = opts.normalizePassCode(passcode)
+
+	if opts.checksum {
+		var err error
+		if passcode, err = stripChecksum(passcode); err != nil {
+			return "", err
+		}
+	}
+
+	if opts.passCodeNotStrict {
+		if passcode == "" {
+			return "", errors.WithStack(ErrEmptyPasscode)
+		}
+
+		return passcode, nil
+	}
+
+	// Only the length is checked for the non-numeric encodings
+	if opts.encoder != otp.EncoderDefault {
+		if width := opts.Digits.OTPDigits().Length(); len(passcode) != width {
+			return "", errors.Wrapf(ErrMalformedPasscode, "it should be %d characters but got %d", width, len(passcode))
+		}
+
+		return passcode, nil
+	}
+
+	if err := checkPassCode(passcode, opts.Digits); err != nil {
+		return "", err
+	}
+
+	return passcode, nil
+}
+
+// normalizePassCode returns the passcode without the surrounding spaces. If
+// WithLenientPassCode() is set, all the spaces and hyphens are removed as well.
+func (opts *Options) normalizePassCode(passcode string) string {
+	if !opts.lenientPassCode {
+		return strings.TrimSpace(passcode)
+	}
+
+	return strings.Map(func(char rune) rune {
+		if char == '-' || unicode.IsSpace(char) {
+			return -1
+		}
+
+		return char
+	}, passcode)
+}
+
+// resolveSkewDuration sets Skew from the tolerance of WithSkewDuration() with
+// the current Period. The duration is converted to the nearest number of
+// periods, with halves rounded up. It does nothing if the tolerance is not set.
+func (opts *Options) resolveSkewDuration() {
+	if opts.skewDuration == nil {
+		return
+	}
+
+	tolerance := *opts.skewDuration
+	period := time.Duration(periodOrDefault(*opts)) * time.Second
+
+	skew := tolerance / period
+	if (tolerance%period)*2 >= period {
+		skew++
+	}
+
+	opts.Skew = uint(skew) //nolint:gosec // not negative
+}
+
 // SetDefault sets the undefined options to its default value.
 func (opts *Options) SetDefault() {
 	if opts.Algorithm == "" {
@@ -123,7 +521,7 @@ func (opts *Options) SetDefault() {
 	}
 
 	if opts.SecretSize == 0 {
-		opts.SecretSize = OptionSecretSizeDefault
+		opts.SecretSize = secretSizeDefault(opts.Algorithm)
 	}
 
 	// Fix #42
@@ -131,3 +529,75 @@ func (opts *Options) SetDefault() {
 		opts.Skew = OptionSkewDefault
 	}
 }
+
+// timeNow returns the fixed time set by WithNow() if any. Otherwise the current
+// time.
+func (opts *Options) timeNow() time.Time {
+	if !opts.now.IsZero() {
+		return opts.now
+	}
+
+	return time.Now()
+}
+
+// isPeriodAccepted returns true if the period is in the accepted periods. Any
+// period is accepted if WithAcceptedPeriods() is not set. The zero period is
+// treated as the default (OptionPeriodDefault) as the underlying OTP library
+// does.
+func (opts *Options) isPeriodAccepted(period uint) bool {
+	if len(opts.acceptedPeriods) == 0 {
+		return true
+	}
+
+	if period == 0 {
+		period = OptionPeriodDefault
+	}
+
+	return slices.Contains(opts.acceptedPeriods, period)
+}
+
+// Validate returns an error if the options are not usable to generate or
+// validate passcodes. It checks that the algorithm and digits are supported
+// and the period is not zero nor out of the accepted periods.
+//
+// The returned error wraps one of ErrUnsupportedAlgorithm, ErrUnsupportedDigits,
+// ErrZeroPeriod or ErrUnsupportedPeriod.
+func (opts *Options) Validate() error {
+	switch {
+	case !opts.Algorithm.IsSupported():
+		return errors.Wrapf(ErrUnsupportedAlgorithm, "invalid options: %q", opts.Algorithm)
+	case !opts.Digits.IsSupported():
+		return errors.Wrapf(ErrUnsupportedDigits, "invalid options: %d", opts.Digits)
+	case opts.Period == 0:
+		return errors.Wrap(ErrZeroPeriod, "invalid options")
+	case !opts.isPeriodAccepted(opts.Period):
+		return errors.Wrapf(ErrUnsupportedPeriod, "invalid options: %d. accepted periods are %v",
+			opts.Period, opts.acceptedPeriods)
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// redactedGoString returns the value in the "%#v" format if it is empty, so that
+// the unset fields are distinguishable. Otherwise, it returns the placeholder of
+// the redacted value.
+func redactedGoString(isEmpty bool, value any) string {
+	if isEmpty {
+		return fmt.Sprintf("%#v", value)
+	}
+
+	return redacted
+}
+
+// secretSizeDefault returns the default secret size for the given algorithm.
+func secretSizeDefault(algo Algorithm) uint {
+	if algo == "MD5" {
+		return OptionSecretSizeDefaultMD5
+	}
+
+	return OptionSecretSizeDefault
+}
@@ -1,7 +1,13 @@
 package totp
 
 import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -61,3 +67,200 @@ func TestOptionKDFDefault_invalid_length(t *testing.T) {
 			"returned key must be nil on error")
 	})
 }
+
+// ----------------------------------------------------------------------------
+//  ResolveOptions()
+// ----------------------------------------------------------------------------
+
+func TestResolveOptions(t *testing.T) {
+	t.Parallel()
+
+	resolved, err := ResolveOptions("Example.com", "alice@example.com",
+		WithAlgorithm(Algorithm("SHA256")), WithPeriod(60))
+	require.NoError(t, err)
+
+	require.Equal(t, "Example.com", resolved.Issuer)
+	require.Equal(t, "alice@example.com", resolved.AccountName)
+	require.Equal(t, Algorithm("SHA256"), resolved.Algorithm)
+	require.Equal(t, uint(60), resolved.Period)
+	require.Equal(t, OptionDigitsDefault, resolved.Digits, "defaults should be applied")
+	require.Equal(t, OptionSkewDefault, resolved.Skew, "defaults should be applied")
+	require.Zero(t, resolved.Created, "creation time should be left zero")
+
+	// Should be the same as the generated key
+	key, err := GenerateKey("Example.com", "alice@example.com",
+		WithAlgorithm(Algorithm("SHA256")), WithPeriod(60))
+	require.NoError(t, err)
+	require.Empty(t, resolved.Diff(key.Options))
+}
+
+func TestResolveOptions_clear_ecdh(t *testing.T) {
+	t.Parallel()
+
+	privKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	resolved, err := ResolveOptions("Example.com", "alice@example.com",
+		WithECDH(privKey, privKey.PublicKey(), "example.com alice@example.com TOTP secret v1"),
+		WithECDHKDF(OptionKDFDefault))
+	require.NoError(t, err)
+
+	require.Empty(t, resolved.ecdhCtx)
+	require.Nil(t, resolved.ecdhPrivateKey)
+	require.Nil(t, resolved.ecdhPublicKey)
+	require.Nil(t, resolved.kdf)
+}
+
+func TestResolveOptions_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := ResolveOptions("", "alice@example.com")
+	require.ErrorContains(t, err, "issuer and accountName are required")
+
+	_, err = ResolveOptions("Example.com", "alice@example.com", WithAlgorithm(Algorithm("BADALGO")))
+	require.ErrorContains(t, err, "failed to apply custom options")
+}
+
+func TestOptions_Diff(t *testing.T) {
+	t.Parallel()
+
+	optsOld, err := NewOptions("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create options during test")
+
+	optsNew := *optsOld
+
+	require.Empty(t, optsOld.Diff(optsNew), "same options should have no difference")
+
+	optsNew.Algorithm = Algorithm("SHA512")
+	optsNew.Period = 60
+	optsNew.ecdhCtx = "unexported fields should be ignored"
+
+	expect := map[string][2]string{
+		"Algorithm": {"SHA1", "SHA512"},
+		"Period":    {"30", "60"},
+	}
+	actual := optsOld.Diff(optsNew)
+
+	require.Equal(t, expect, actual)
+}
+
+// ----------------------------------------------------------------------------
+//  Options.GoString() and Key.GoString()
+// ----------------------------------------------------------------------------
+
+func TestOptions_GoString(t *testing.T) {
+	t.Parallel()
+
+	privKeyA, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privKeyB, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	opts, err := NewOptions("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, WithECDH(privKeyA, privKeyB.PublicKey(), "example.com alice bob TOTP v1")(opts))
+	require.NoError(t, WithSecretPepper([]byte("site-wide pepper"))(opts))
+
+	for _, out := range []string{fmt.Sprintf("%#v", *opts), fmt.Sprintf("%#v", opts)} {
+		require.Contains(t, out, "ecdhPrivateKey:REDACTED")
+		require.Contains(t, out, "pepper:REDACTED")
+		require.NotContains(t, out, fmt.Sprintf("%#v", privKeyA.Bytes()), "private key should not be exposed")
+		require.NotContains(t, out, fmt.Sprintf("%#v", []byte("site-wide pepper")), "pepper should not be exposed")
+		require.Contains(t, out, `Issuer:"Example.com"`, "non-sensitive fields should be shown")
+		require.Contains(t, out, `Algorithm:"SHA1"`)
+
+		// Every field should be listed. Update GoString() if a field is added.
+		optsType := reflect.TypeOf(Options{})
+		for index := range optsType.NumField() {
+			require.Contains(t, out, optsType.Field(index).Name+":", "missing field in GoString()")
+		}
+	}
+
+	// Unset secret-bearing fields are distinguishable from the redacted ones
+	//nolint:exhaustruct // allow missing fields
+	out := fmt.Sprintf("%#v", Options{})
+	require.Contains(t, out, "ecdhPrivateKey:(*ecdh.PrivateKey)(nil)")
+	require.Contains(t, out, "pepper:[]byte(nil)")
+	require.Contains(t, out, "kdf:nil")
+}
+
+func TestKey_GoString(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	for _, out := range []string{fmt.Sprintf("%#v", *key), fmt.Sprintf("%#v", key)} {
+		require.True(t, strings.HasPrefix(out, "totp.Key{Secret:REDACTED, Options:totp.Options{"), "got: %s", out)
+		require.NotContains(t, out, key.Secret.Base32())
+		require.NotContains(t, out, fmt.Sprintf("%#v", []byte(key.Secret)))
+	}
+
+	//nolint:exhaustruct // allow missing fields
+	require.Contains(t, fmt.Sprintf("%#v", Key{}), "Secret:totp.Secret(nil)")
+}
+
+// ----------------------------------------------------------------------------
+//  Options.Validate()
+// ----------------------------------------------------------------------------
+
+func TestOptions_Validate(t *testing.T) {
+	t.Parallel()
+
+	opts, err := NewOptions("Example.com", "alice@example.com")
+	require.NoError(t, err)
+	require.NoError(t, opts.Validate(), "default options should be valid")
+
+	for _, test := range []struct {
+		modify func(*Options)
+		expect error
+	}{
+		{func(o *Options) { o.Algorithm = "BAD" }, ErrUnsupportedAlgorithm},
+		{func(o *Options) { o.Algorithm = "" }, ErrUnsupportedAlgorithm},
+		{func(o *Options) { o.Digits = 7 }, ErrUnsupportedDigits},
+		{func(o *Options) { o.Period = 0 }, ErrZeroPeriod},
+	} {
+		optsBad := *opts
+		test.modify(&optsBad)
+
+		err := optsBad.Validate()
+		require.ErrorIs(t, err, test.expect)
+		require.Contains(t, err.Error(), "invalid options")
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Options.otpOpts()
+// ----------------------------------------------------------------------------
+
+func TestOptions_otpOpts_generation_and_validation_agree(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Unix(1700000000, 0)
+
+	for _, opts := range [][]Option{
+		{},
+		{WithAlgorithm(Algorithm("SHA256")), WithDigits(DigitsEight)},
+		{WithAlgorithm(Algorithm("SHA512")), WithPeriod(60), WithSkew(0)},
+		{WithPeriod(15), WithSkew(2)},
+	} {
+		key, err := GenerateKey("Example.com", "alice@example.com", append(opts, WithNow(fixedTime))...)
+		require.NoError(t, err, "failed to create key during test setup")
+
+		passcode, err := key.PassCode()
+		require.NoError(t, err)
+
+		compiled, err := key.Compile()
+		require.NoError(t, err)
+
+		require.True(t, key.Validate(passcode), "options: %+v", key.Options.otpOpts())
+		require.True(t, compiled.Validate(passcode), "options: %+v", key.Options.otpOpts())
+		require.True(t, Validate(passcode, key.Secret.Base32(), key.Options))
+
+		offset, ok := matchOffset(passcode, key.Secret.Base32(), fixedTime, key.Options)
+		require.True(t, ok)
+		require.Zero(t, offset)
+	}
+}
@@ -0,0 +1,26 @@
+package totp
+
+// ----------------------------------------------------------------------------
+//  Type: OTPType
+// ----------------------------------------------------------------------------
+
+// OTPType represents the type of the OTP in the host part of the otpauth URI.
+// See URI.OTPType().
+type OTPType string
+
+const (
+	// OTPTypeTOTP is the time-based OTP (RFC 6238). The type of the keys
+	// unless created by GenHOTPKeyFromURI().
+	OTPTypeTOTP OTPType = "totp"
+	// OTPTypeHOTP is the counter-based OTP (RFC 4226).
+	OTPTypeHOTP OTPType = "hotp"
+)
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// String is an implementation of the Stringer interface.
+func (t OTPType) String() string {
+	return string(t)
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"encoding/pem"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Functions
+// ----------------------------------------------------------------------------
+
+// GenKeysFromPEM creates the Key objects from all the BlockTypeTOTP blocks in the
+// PEM data, in the order of appearance. Such as the bundle of all the accounts
+// of a user written by WritePEMBundle(). The other types of blocks are skipped
+// as GenKeyFromPEM() does.
+//
+// It returns an error with the index of the block if any of the keys is
+// invalid, or if no TOTP block is found.
+func GenKeysFromPEM(pemData string) ([]*Key, error) {
+	var keys []*Key
+
+	rest := []byte(pemData)
+
+	for {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != BlockTypeTOTP {
+			continue
+		}
+
+		key, err := keyFromPEMBlock(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode key at index %d", len(keys))
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("failed to decode PEM block containing TOTP secret key")
+	}
+
+	return keys, nil
+}
+
+// WritePEMBundle writes the keys to w as the concatenated BlockTypeTOTP blocks
+// in PEM format. Such as to export all the accounts of a user to a single file.
+// Use GenKeysFromPEM() to read them back.
+//
+// Each key is written as soon as it is encoded. So if an error occurs, the keys
+// before it are already written. The error contains the index and the issuer of
+// the key.
+func WritePEMBundle(w io.Writer, keys []*Key) error {
+	for index, key := range keys {
+		if key == nil {
+			return errors.Errorf("failed to encode key at index %d: nil key", index)
+		}
+
+		out, err := key.PEMBytes()
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode key at index %d (issuer: %q)", index, key.Options.Issuer)
+		}
+
+		if _, err := w.Write(out); err != nil {
+			return errors.Wrapf(err, "failed to write key at index %d (issuer: %q)", index, key.Options.Issuer)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  WritePEMBundle() and GenKeysFromPEM()
+// ----------------------------------------------------------------------------
+
+func TestWritePEMBundle_round_trip(t *testing.T) {
+	t.Parallel()
+
+	keys := make([]*Key, 0, 3)
+
+	for _, issuer := range []string{"Example.com", "Example.org", "Example.net"} {
+		key, err := GenerateKey(issuer, "alice@example.com", WithPeriod(60), WithSecretSize(20))
+		require.NoError(t, err)
+
+		keys = append(keys, key)
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, WritePEMBundle(&buf, keys))
+
+	// Other types of blocks are skipped
+	pemData := "-----BEGIN DUMMY-----\nZHVtbXk=\n-----END DUMMY-----\n" + buf.String()
+
+	keysRead, err := GenKeysFromPEM(pemData)
+	require.NoError(t, err)
+	require.Len(t, keysRead, len(keys))
+
+	for index, key := range keys {
+		require.True(t, key.Secret.Equal(keysRead[index].Secret), "key #%d: secret mismatch", index)
+		require.Empty(t, key.Options.Diff(keysRead[index].Options), "key #%d: options mismatch", index)
+	}
+
+	// The single-key reader reads the first one
+	keyFirst, err := GenKeyFromPEM(buf.String())
+	require.NoError(t, err)
+	require.Equal(t, "Example.com", keyFirst.Options.Issuer)
+
+	// Empty bundle
+	buf.Reset()
+	require.NoError(t, WritePEMBundle(&buf, nil))
+	require.Empty(t, buf.String())
+}
+
+func TestGenKeysFromPEM_bad_input(t *testing.T) {
+	t.Parallel()
+
+	keys, err := GenKeysFromPEM("-----BEGIN DUMMY-----\nZHVtbXk=\n-----END DUMMY-----\n")
+	require.ErrorContains(t, err, "failed to decode PEM block containing TOTP secret key")
+	require.Nil(t, keys)
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	pemValid, err := key.PEM()
+	require.NoError(t, err)
+
+	pemShort := string(pem.EncodeToMemory(&pem.Block{Type: BlockTypeTOTP, Headers: nil, Bytes: []byte("short")}))
+
+	keys, err = GenKeysFromPEM(pemValid + pemShort)
+	require.ErrorIs(t, err, ErrSecretTooShort)
+	require.ErrorContains(t, err, "failed to decode key at index 1")
+	require.Nil(t, keys)
+}
+
+// errWriter is an io.Writer that always fails.
+type errWriter struct{}
+
+func (errWriter) Write(_ []byte) (int, error) {
+	return 0, errors.New("forced write error")
+}
+
+func TestWritePEMBundle_errors(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	err = WritePEMBundle(&bytes.Buffer{}, []*Key{key, nil})
+	require.ErrorContains(t, err, "failed to encode key at index 1: nil key")
+
+	err = WritePEMBundle(errWriter{}, []*Key{key})
+	require.ErrorContains(t, err, `failed to write key at index 0 (issuer: "Example.com"): forced write error`)
+}
+
+//nolint:paralleltest // disable parallel test due to monkey patching during test
+func TestWritePEMBundle_encode_error(t *testing.T) {
+	// Backup and defer restore
+	oldPemEncodeToMemory := pemEncodeToMemory
+	defer func() {
+		pemEncodeToMemory = oldPemEncodeToMemory
+	}()
+
+	// Mock pemEncodeToMemory to force return nil as an error
+	pemEncodeToMemory = func(_ *pem.Block) []byte {
+		return nil
+	}
+
+	//nolint:exhaustruct // allow missing fields
+	key := &Key{Options: Options{Issuer: "Example.com"}}
+
+	err := WritePEMBundle(&bytes.Buffer{}, []*Key{key})
+	require.ErrorContains(t, err, `failed to encode key at index 0 (issuer: "Example.com")`)
+}
@@ -0,0 +1,132 @@
+package totp
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: PresetRegistry
+// ----------------------------------------------------------------------------
+
+// PresetRegistry holds named presets of the options. Such as the TOTP policies
+// of each tenant in multi-tenant services, so that the handlers refer to a name
+// rather than duplicating the option chains.
+//
+// The zero value is an empty registry ready to use. It is safe for concurrent
+// use.
+type PresetRegistry struct {
+	presets map[string]Options
+	mutex   sync.RWMutex
+}
+
+// ----------------------------------------------------------------------------
+//  Constructor
+// ----------------------------------------------------------------------------
+
+// NewPresetRegistry returns an empty PresetRegistry.
+func NewPresetRegistry() *PresetRegistry {
+	return &PresetRegistry{
+		presets: make(map[string]Options),
+		mutex:   sync.RWMutex{},
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// GenerateKeyPreset creates a new Key object with the options of the preset of
+// the given name. It returns an error if the preset is not registered.
+func (r *PresetRegistry) GenerateKeyPreset(issuer, accountName, presetName string) (*Key, error) {
+	options, err := r.Preset(presetName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	if issuer == "" || accountName == "" {
+		return nil, errors.New("failed to generate key: issuer and accountName are required")
+	}
+
+	options.Issuer = issuer
+	options.AccountName = accountName
+
+	return GenerateKeyCustom(options)
+}
+
+// Names returns the names of the registered presets in sorted order.
+func (r *PresetRegistry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.presets))
+	for name := range r.presets {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Preset returns a copy of the options of the preset of the given name. It
+// returns an error if the preset is not registered.
+func (r *PresetRegistry) Preset(name string) (Options, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	options, ok := r.presets[name]
+	if !ok {
+		//nolint:exhaustruct // empty options on error
+		return Options{}, errors.Errorf("unknown preset: %q", name)
+	}
+
+	return options, nil
+}
+
+// Register registers a preset of the given name. The options of the preset are
+// the default values with the given Option functions applied, the same as
+// GenerateKey() does. The existing preset of the same name is replaced.
+//
+// It returns an error if the name is empty or any of the options is invalid.
+// The issuer, account name and creation time are set on key generation. See
+// GenerateKeyPreset().
+func (r *PresetRegistry) Register(name string, opts ...Option) error {
+	if name == "" {
+		return errors.New("empty preset name")
+	}
+
+	//nolint:exhaustruct // the rest of the fields are set by SetDefault()
+	options := Options{}
+	options.SetDefault()
+
+	for _, fn := range opts {
+		if err := fn(&options); err != nil {
+			return errors.Wrapf(err, "failed to apply options of preset %q", name)
+		}
+	}
+
+	options.resolveSkewDuration()
+
+	if err := options.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid options of preset %q", name)
+	}
+
+	options.Issuer = ""
+	options.AccountName = ""
+	options.Created = time.Time{}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.presets == nil {
+		r.presets = make(map[string]Options)
+	}
+
+	r.presets[name] = options
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  PresetRegistry
+// ----------------------------------------------------------------------------
+
+func TestPresetRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewPresetRegistry()
+
+	require.NoError(t, registry.Register("tenant-a"))
+	require.NoError(t, registry.Register("tenant-b",
+		WithAlgorithm(Algorithm("SHA256")), WithDigits(DigitsEight), WithPeriod(60)))
+	require.Equal(t, []string{"tenant-a", "tenant-b"}, registry.Names())
+
+	key, err := registry.GenerateKeyPreset("Example.com", "alice@example.com", "tenant-b")
+	require.NoError(t, err)
+	require.Equal(t, "Example.com", key.Options.Issuer)
+	require.Equal(t, "alice@example.com", key.Options.AccountName)
+	require.Equal(t, Algorithm("SHA256"), key.Options.Algorithm)
+	require.Equal(t, DigitsEight, key.Options.Digits)
+	require.Equal(t, uint(60), key.Options.Period)
+	require.False(t, key.Options.Created.IsZero())
+
+	// Defaults
+	key, err = registry.GenerateKeyPreset("Example.com", "bob@example.com", "tenant-a")
+	require.NoError(t, err)
+
+	keyDefault, err := GenerateKey("Example.com", "bob@example.com")
+	require.NoError(t, err)
+	require.Empty(t, keyDefault.Options.Diff(key.Options), "preset with no options should be the default")
+
+	// The preset is a copy
+	preset, err := registry.Preset("tenant-b")
+	require.NoError(t, err)
+
+	preset.Period = 10
+
+	preset, err = registry.Preset("tenant-b")
+	require.NoError(t, err)
+	require.Equal(t, uint(60), preset.Period)
+}
+
+func TestPresetRegistry_skew_duration(t *testing.T) {
+	t.Parallel()
+
+	registry := NewPresetRegistry()
+
+	require.NoError(t, registry.Register("tenant", WithSkewDuration(2*time.Minute), WithPeriod(60)))
+
+	preset, err := registry.Preset("tenant")
+	require.NoError(t, err)
+	require.Equal(t, uint(60), preset.Period)
+	require.Equal(t, uint(2), preset.Skew, "the skew duration should be resolved with the final period")
+
+	key, err := registry.GenerateKeyPreset("Example.com", "alice@example.com", "tenant")
+	require.NoError(t, err)
+	require.Equal(t, uint(2), key.Options.Skew)
+}
+
+func TestPresetRegistry_errors(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // the zero value should be usable
+	registry := &PresetRegistry{}
+
+	_, err := registry.GenerateKeyPreset("Example.com", "alice@example.com", "unknown")
+	require.ErrorContains(t, err, `unknown preset: "unknown"`)
+
+	require.ErrorContains(t, registry.Register(""), "empty preset name")
+	require.ErrorIs(t, registry.Register("bad", WithDigits(Digits(7))), ErrUnsupportedDigits)
+	require.ErrorIs(t, registry.Register("bad", WithPeriod(0)), ErrZeroPeriod)
+	require.Empty(t, registry.Names(), "invalid presets should not be registered")
+
+	require.NoError(t, registry.Register("good"))
+
+	_, err = registry.GenerateKeyPreset("", "alice@example.com", "good")
+	require.ErrorContains(t, err, "issuer and accountName are required")
+}
+
+func TestPresetRegistry_concurrent(t *testing.T) {
+	t.Parallel()
+
+	registry := NewPresetRegistry()
+	require.NoError(t, registry.Register("default"))
+
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_ = registry.Register("other", WithSkew(0))
+			_, _ = registry.Preset("default")
+			_ = registry.Names()
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, []string{"default", "other"}, registry.Names())
+}
@@ -0,0 +1,135 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// provisioningJWTHeader is the encoded JOSE header of the provisioning JWT. It
+// is fixed, so the parser rejects any other algorithms, such as "none".
+//
+//nolint:gochecknoglobals // constant value
+var provisioningJWTHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// provisioningClaims is the payload (claims) of the provisioning JWT.
+type provisioningClaims struct {
+	Issuer      string `json:"iss"`
+	AccountName string `json:"sub"`
+	URI         string `json:"uri"`
+	IssuedAt    int64  `json:"iat"` // Unix time in seconds
+	Expires     int64  `json:"exp"` // Unix time in seconds
+}
+
+// ProvisioningJWT returns a short-lived JWT (RFC 7519) signed with HS256 that
+// embeds the issuer, account name and URI of the key. Use ParseProvisioningJWT()
+// to verify and import it. Such as for a server to hand a time-limited token to
+// a single page application (SPA) that renders the QR code.
+//
+// The claims are:
+//
+//	iss: issuer
+//	sub: account name
+//	uri: URI of the key. See Key.URI().
+//	iat: issued at in Unix seconds
+//	exp: expiry in Unix seconds
+//
+// Like EnrollmentToken(), the token is signed but NOT encrypted. The TOTP secret
+// is readable by anyone who has the token, so deliver it through a trusted
+// channel and keep the TTL short.
+//
+// The times are computed from the time of the key options (see WithNow()). The
+// TTL must be at least a second since the times are in seconds.
+func (k *Key) ProvisioningJWT(signingKey []byte, ttl time.Duration) (string, error) {
+	if len(signingKey) == 0 {
+		return "", errors.New("empty signing key of the provisioning JWT")
+	}
+
+	if ttl < time.Second {
+		return "", errors.Errorf("invalid TTL of the provisioning JWT: %v. it should be a second or longer", ttl)
+	}
+
+	now := k.Options.timeNow()
+
+	payload, err := json.Marshal(provisioningClaims{
+		Issuer:      k.Options.Issuer,
+		AccountName: k.Options.AccountName,
+		URI:         k.URI(),
+		IssuedAt:    now.Unix(),
+		Expires:     now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal provisioning JWT")
+	}
+
+	signingInput := provisioningJWTHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := signEnrollmentToken(signingInput, signingKey) // HMAC-SHA256
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParseProvisioningJWT verifies the JWT created by Key.ProvisioningJWT() with the
+// given signing key and returns the Key object of the embedded URI.
+//
+// The opts are passed to GenKeyFromURI(). The expiry is checked against the
+// time of the options, such as WithNow(), the same as ParseEnrollmentToken().
+//
+// It returns an error wrapping ErrTokenTampered if the token is malformed, is not
+// HS256 or the signature does not match, and ErrTokenExpired if the token is
+// expired.
+func ParseProvisioningJWT(token string, signingKey []byte, opts ...Option) (*Key, error) {
+	const numParts = 3
+
+	if len(signingKey) == 0 {
+		return nil, errors.New("empty signing key to verify the provisioning JWT")
+	}
+
+	parseOpts, err := tokenOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse provisioning JWT")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != numParts {
+		return nil, errors.Wrap(ErrTokenTampered, "malformed provisioning JWT")
+	}
+
+	if parts[0] != provisioningJWTHeader {
+		return nil, errors.Wrap(ErrTokenTampered, "unsupported header of provisioning JWT. it should be HS256")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, signEnrollmentToken(parts[0]+"."+parts[1], signingKey)) {
+		return nil, errors.Wrap(ErrTokenTampered, "signature mismatch of provisioning JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(ErrTokenTampered, "malformed payload of provisioning JWT")
+	}
+
+	var claims provisioningClaims
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.Wrap(ErrTokenTampered, "malformed payload of provisioning JWT")
+	}
+
+	if expires := time.Unix(claims.Expires, 0); !parseOpts.timeNow().Before(expires) {
+		return nil, errors.Wrapf(ErrTokenExpired, "provisioning JWT expired at %s", expires.UTC().Format(time.RFC3339))
+	}
+
+	key, err := GenKeyFromURI(claims.URI, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to import the URI of provisioning JWT")
+	}
+
+	if key.Options.Issuer != claims.Issuer || key.Options.AccountName != claims.AccountName {
+		return nil, errors.Wrap(ErrTokenTampered, "issuer or account name mismatch of provisioning JWT")
+	}
+
+	return key, nil
+}
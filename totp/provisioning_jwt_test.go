@@ -0,0 +1,116 @@
+package totp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  Key.ProvisioningJWT() and ParseProvisioningJWT()
+// ----------------------------------------------------------------------------
+
+func TestKey_ProvisioningJWT(t *testing.T) {
+	t.Parallel()
+
+	signingKey := []byte("server side signing key")
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	token, err := key.ProvisioningJWT(signingKey, 5*time.Minute)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3, "JWT should have 3 parts")
+
+	// Claims are readable by the frontend
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims map[string]any
+
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	require.Equal(t, "Example.com", claims["iss"])
+	require.Equal(t, "alice@example.com", claims["sub"])
+	require.Equal(t, key.URI(), claims["uri"])
+	require.InDelta(t, 5*60, claims["exp"].(float64)-claims["iat"].(float64), 0)
+
+	keyParsed, err := ParseProvisioningJWT(token, signingKey)
+	require.NoError(t, err)
+	require.Equal(t, key.URI(), keyParsed.URI())
+
+	// Wrong signing key
+	_, err = ParseProvisioningJWT(token, []byte("other signing key"))
+	require.ErrorIs(t, err, ErrTokenTampered)
+
+	// Tampered and malformed
+	headerNone := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	signedGarbage := provisioningJWTHeader + "." + base64.RawURLEncoding.EncodeToString([]byte("garbage"))
+	signedGarbage += "." + base64.RawURLEncoding.EncodeToString(signEnrollmentToken(signedGarbage, signingKey))
+
+	for _, malformed := range []string{
+		"",
+		"a.b",
+		parts[0] + "." + parts[1],
+		headerNone + "." + parts[1] + ".",
+		parts[0] + ".f" + parts[1][1:] + "." + parts[2],
+		parts[0] + "." + parts[1] + ".!!!",
+		signedGarbage,
+	} {
+		keyParsed, err := ParseProvisioningJWT(malformed, signingKey)
+
+		require.ErrorIs(t, err, ErrTokenTampered, "token: %q", malformed)
+		require.Nil(t, keyParsed)
+	}
+}
+
+func TestKey_ProvisioningJWT_expired(t *testing.T) {
+	t.Parallel()
+
+	signingKey := []byte("server side signing key")
+
+	issuedAt := time.Unix(1700000000, 0)
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithNow(issuedAt))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	token, err := key.ProvisioningJWT(signingKey, 5*time.Minute)
+	require.NoError(t, err)
+
+	// The same clock as the issuer is used
+	keyParsed, err := ParseProvisioningJWT(token, signingKey, WithNow(issuedAt.Add(5*time.Minute-time.Second)))
+	require.NoError(t, err, "token should be valid just before the expiry")
+	require.Equal(t, key.URI(), keyParsed.URI())
+
+	keyParsed, err = ParseProvisioningJWT(token, signingKey, WithNow(issuedAt.Add(5*time.Minute)))
+
+	require.ErrorIs(t, err, ErrTokenExpired)
+	require.NotErrorIs(t, err, ErrTokenTampered, "expired and tampered tokens should be distinct")
+	require.Nil(t, keyParsed)
+}
+
+func TestKey_ProvisioningJWT_bad_args(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	_, err = key.ProvisioningJWT(nil, time.Minute)
+	require.ErrorContains(t, err, "empty signing key")
+
+	for _, ttl := range []time.Duration{-time.Minute, 0, 999 * time.Millisecond} {
+		_, err = key.ProvisioningJWT([]byte("signing key"), ttl)
+		require.ErrorContains(t, err, "invalid TTL", "ttl: %s", ttl)
+	}
+
+	_, err = ParseProvisioningJWT("a.b.c", []byte("signing key"), WithMinEntropy(0))
+	require.ErrorContains(t, err, "failed to apply custom options")
+
+	_, err = ParseProvisioningJWT("a.b.c", nil)
+	require.ErrorContains(t, err, "empty signing key")
+}
@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"image"
+
+	"github.com/boombuler/barcode"
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: QRRenderer
+// ----------------------------------------------------------------------------
+
+// QRRenderer renders the QR code image of a URI in various sizes. Use the
+// QRCode.Renderer() method to create one.
+//
+// The URI is encoded to a QR code matrix only once on creation and the matrix
+// is reused for each rendering. This avoids re-encoding the URI for every size,
+// which is CPU-heavy in batch rendering such as in high-traffic enrollment
+// services. The matrix is read-only once encoded, so it is safe for concurrent
+// use.
+type QRRenderer struct {
+	matrix barcode.Barcode // encoded QR code with no scaling
+}
+
+// ----------------------------------------------------------------------------
+//  Constructor
+// ----------------------------------------------------------------------------
+
+// Renderer returns a QRRenderer with the URI of the QR code encoded.
+func (q *QRCode) Renderer() (*QRRenderer, error) {
+	matrix, err := q.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return &QRRenderer{matrix: matrix}, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// Image returns an image.Image object of the QR code in the given size. Minimum
+// width and height is 49x49.
+func (r *QRRenderer) Image(width, height int) (image.Image, error) {
+	img, err := barcode.Scale(r.matrix, width, height)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scale QR code")
+	}
+
+	return img, nil
+}
+
+// PNG returns a PNG image of the QR code in bytes in the given size. Minimum
+// width and height is 49x49.
+func (r *QRRenderer) PNG(width, height int) ([]byte, error) {
+	img, err := r.Image(width, height)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate QR code PNG image")
+	}
+
+	return encodePNG(img)
+}
@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRendererURI = "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+	"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+// ----------------------------------------------------------------------------
+//  QRCode.Renderer()
+// ----------------------------------------------------------------------------
+
+func TestQRCode_Renderer_empty_uri(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // missing fields are intentional
+	qrCode := QRCode{}
+
+	renderer, err := qrCode.Renderer()
+
+	require.Error(t, err, "empty URI should return error")
+	require.Contains(t, err.Error(), "failed to encode URI to QR code: empty URI")
+	require.Nil(t, renderer)
+}
+
+// ----------------------------------------------------------------------------
+//  QRRenderer.PNG()
+// ----------------------------------------------------------------------------
+
+func TestQRRenderer_PNG_same_as_qrcode(t *testing.T) {
+	t.Parallel()
+
+	qrCode := QRCode{
+		URI:   URI(testRendererURI),
+		Level: FixLevelDefault,
+	}
+
+	renderer, err := qrCode.Renderer()
+	require.NoError(t, err)
+
+	for _, size := range []int{64, 128, 256} {
+		expect, err := qrCode.PNG(size, size)
+		require.NoError(t, err)
+
+		actual, err := renderer.PNG(size, size)
+		require.NoError(t, err)
+
+		require.Equal(t, expect, actual, "renderer should render the same image as QRCode.PNG")
+	}
+
+	// Too small
+	img, err := renderer.PNG(10, 10)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to scale QR code")
+	require.Nil(t, img)
+}
+
+// ----------------------------------------------------------------------------
+//  Benchmarks
+// ----------------------------------------------------------------------------
+
+var benchSizes = []int{64, 128, 256} //nolint:gochecknoglobals // for benchmarks
+
+func BenchmarkQRCode_PNG(b *testing.B) {
+	qrCode := QRCode{
+		URI:   URI(testRendererURI),
+		Level: FixLevelDefault,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		for _, size := range benchSizes {
+			if _, err := qrCode.PNG(size, size); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkQRRenderer_PNG(b *testing.B) {
+	qrCode := QRCode{
+		URI:   URI(testRendererURI),
+		Level: FixLevelDefault,
+	}
+
+	renderer, err := qrCode.Renderer()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		for _, size := range benchSizes {
+			if _, err := renderer.PNG(size, size); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
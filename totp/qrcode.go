@@ -2,8 +2,13 @@ package totp
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"image"
+	"image/color"
 	"image/png"
+	"os"
+	"strconv"
 
 	"github.com/boombuler/barcode"
 	"github.com/boombuler/barcode/qr"
@@ -14,28 +19,102 @@ import (
 type QRCode struct {
 	URI   URI      // URI object to be encoded to QR code image.
 	Level FixLevel // Level is the error correction level for the QR code.
+	Mode  QRMode   // Mode is the encoding mode hint. (Default: QRModeAuto)
+}
+
+// QRMode is the encoding mode of the QR code. Use `QRMode*` constants to set
+// the mode.
+type QRMode byte
+
+// Encoding modes for QR code.
+const (
+	// QRModeAuto picks the densest mode that can encode the content. Since
+	// otpauth URIs are mixed-case with symbols, it is usually the byte mode.
+	QRModeAuto QRMode = iota
+	// QRModeByte encodes the content as bytes (UTF-8). Any content is allowed.
+	QRModeByte
+	// QRModeAlphanumeric encodes the content in the alphanumeric mode, which is
+	// denser than the byte mode. Only digits, uppercase letters and the symbols
+	// " $%*+-./:" are allowed. Such as the base32 secret from Key.SecretQRCode().
+	QRModeAlphanumeric
+)
+
+// qrSizeMin is the minimum width and height of the QR code image in pixels.
+const qrSizeMin = 49
+
+// grayThreshold is the threshold of the gray level to consider a pixel as a dark
+// module.
+const grayThreshold = 128
+
+// etagVersion is the domain separator of QRCode.ETag(). Bump it if the rendering
+// changes so that the cached images are invalidated.
+const etagVersion = "go-totp qrcode etag v1"
+
+// ETag returns a stable hash of the inputs of the QR code, the URI, error
+// correction level and mode, as a quoted strong entity tag. e.g.:
+//
+//	"3f7a4c0e9b1d5a2e6c8f0b4d7e1a9c3b"
+//
+// HTTP handlers of the enrollment endpoints can set it to the "ETag" header and
+// skip rendering if it matches the "If-None-Match" header of the request. It is
+// cheap since the inputs are hashed, not the rendered image.
+//
+// The image size is given on rendering, such as PNG(), thus not included. If the
+// size varies, combine it with the tag. The tag is non-reversible, but it is
+// derived from the secret in the URI. So the same URI always has the same tag.
+func (q *QRCode) ETag() string {
+	const lenTag = 16
+
+	hash := sha256.New()
+	for _, field := range []string{
+		etagVersion,
+		q.URI.String(),
+		strconv.Itoa(int(q.Level)),
+		strconv.Itoa(int(q.Mode)),
+	} {
+		// Length-prefixed to avoid ambiguity between the fields
+		hash.Write([]byte(strconv.Itoa(len(field)) + ":" + field))
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:lenTag]) + `"`
 }
 
 // Image returns an image.Image object of the QR code. Minimum width and height
 // is 49x49.
 func (q *QRCode) Image(width, height int) (image.Image, error) {
-	uri := q.URI.String()
+	renderer, err := q.Renderer()
+	if err != nil {
+		return nil, err
+	}
 
-	qrCode, err := qr.Encode(uri, qr.M, qr.Auto)
-	if err != nil || uri == "" {
-		if uri == "" {
-			err = errors.New("empty URI")
-		}
+	return renderer.Image(width, height)
+}
 
-		return nil, errors.Wrap(err, "failed to encode URI to QR code")
+// Matrix returns the modules of the QR code as a 2D slice of booleans in
+// [row][column] order. True means a dark module. The quiet zone (margin) is
+// not included.
+//
+// This is useful to render the QR code in other formats, such as vector images
+// or terminals. See also the PDF() method.
+func (q *QRCode) Matrix() ([][]bool, error) {
+	qrCode, err := q.encode()
+	if err != nil {
+		return nil, err
 	}
 
-	qrCode, err = barcode.Scale(qrCode, width, height)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to scale QR code")
+	bounds := qrCode.Bounds()
+	matrix := make([][]bool, bounds.Dy())
+
+	for row := range matrix {
+		matrix[row] = make([]bool, bounds.Dx())
+
+		for col := range matrix[row] {
+			gray, _ := color.GrayModel.Convert(qrCode.At(bounds.Min.X+col, bounds.Min.Y+row)).(color.Gray)
+			matrix[row][col] = gray.Y < grayThreshold
+		}
 	}
 
-	return qrCode, nil
+	return matrix, nil
 }
 
 //nolint:gochecknoglobals // allow private global variable to mock during tests
@@ -49,6 +128,92 @@ func (q *QRCode) PNG(width, height int) ([]byte, error) {
 		return nil, errors.Wrap(err, "failed to generate QR code PNG image")
 	}
 
+	return encodePNG(img)
+}
+
+// PNGSet returns a set of square PNG images of the QR code in bytes for each
+// of the given sizes. The returned map is keyed by the size.
+//
+// The URI is encoded only once and then scaled to each size. It stops at the
+// first size that fails to render and returns an error with the failed size.
+// Minimum size is 49 (49x49). See also the Renderer() method.
+func (q *QRCode) PNGSet(sizes []int) (map[int][]byte, error) {
+	renderer, err := q.Renderer()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int][]byte, len(sizes))
+
+	for _, size := range sizes {
+		if size < qrSizeMin {
+			return nil, errors.Errorf(
+				"failed to generate QR code PNG image of size %d: it should be at least %dx%d",
+				size, qrSizeMin, qrSizeMin,
+			)
+		}
+
+		pngImg, err := renderer.PNG(size, size)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate QR code PNG image of size %d", size)
+		}
+
+		result[size] = pngImg
+	}
+
+	return result, nil
+}
+
+// Save renders the QR code as a PNG image and writes it to the given path with
+// the given permission. If perm is zero, 0o600 (owner only) is used since the
+// image embeds the secret.
+//
+// The file is written atomically. It writes to a temporary file first and then
+// renames it, so a partially-written file is never left on crash.
+func (q *QRCode) Save(path string, width, height int, perm os.FileMode) error {
+	pngImg, err := q.PNG(width, height)
+	if err != nil {
+		return errors.Wrap(err, "failed to render QR code image")
+	}
+
+	if err := writeFileAtomic(path, pngImg, perm); err != nil {
+		return errors.Wrap(err, "failed to write QR code image")
+	}
+
+	return nil
+}
+
+// encode encodes the URI to a QR code barcode object with no scaling.
+func (q *QRCode) encode() (barcode.Barcode, error) {
+	uri := q.URI.String()
+
+	var encoding qr.Encoding
+
+	switch q.Mode {
+	case QRModeAuto:
+		encoding = qr.Auto
+	case QRModeByte:
+		encoding = qr.Unicode
+	case QRModeAlphanumeric:
+		encoding = qr.AlphaNumeric
+	default:
+		return nil, errors.Errorf("failed to encode URI to QR code: unsupported mode %d", q.Mode)
+	}
+
+	qrCode, err := qr.Encode(uri, qr.M, encoding)
+	if err != nil || uri == "" {
+		if uri == "" {
+			err = errors.New("empty URI")
+		}
+
+		return nil, errors.Wrap(err, "failed to encode URI to QR code")
+	}
+
+	return qrCode, nil
+}
+
+// encodePNG encodes the given image to PNG format in bytes.
+func encodePNG(img image.Image) ([]byte, error) {
 	var buf bytes.Buffer
 
 	if err := pngEncode(&buf, img); err != nil {
@@ -0,0 +1,124 @@
+package totp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/pkg/errors"
+)
+
+// qrAnimatedModuleSize is the width and height of each module of the animated
+// QR code in pixels.
+const qrAnimatedModuleSize = 4
+
+// gifDelayUnit is the unit of the frame delay in GIF, which is 1/100 second.
+const gifDelayUnit = 10 * time.Millisecond
+
+// gifDelayMax is the maximum frame delay that GIF can hold.
+const gifDelayMax = 65535 * gifDelayUnit
+
+//nolint:gochecknoglobals // allow private global variable to mock during tests
+var gifEncodeAll = gif.EncodeAll
+
+// AnimatedQR returns an animated GIF image in bytes that cycles through the QR
+// codes of the given URIs in order and loops forever. Each frame encodes one
+// URI, such as a chunk of a migration payload that is too long for a single QR
+// code. The URIs are encoded as is, so any text is allowed.
+//
+// The frames have the same size, which fits the largest QR code including the
+// quiet zone of 4 modules, and each module is 4x4 pixels. The frameDelay is
+// rounded up to 1/100 second, the unit of GIF. It should be between 10ms and
+// about 11 minutes. Something around 500ms to 1s is recommended.
+//
+// Note that only a few scanner apps can read animated QR codes. Most of them,
+// including the common authenticator apps, read only the frame shown at the
+// time of scanning and do not collect the rest. Provide the static QR codes of
+// each chunk, such as via QRCode.PNG(), as a fallback.
+func AnimatedQR(uris []string, level FixLevel, frameDelay time.Duration) ([]byte, error) {
+	if len(uris) == 0 {
+		return nil, errors.New("failed to generate animated QR code: no URIs to encode")
+	}
+
+	if !level.isValid() {
+		return nil, errors.Errorf("failed to generate animated QR code: unsupported fix level: %d", level)
+	}
+
+	if frameDelay < gifDelayUnit || frameDelay > gifDelayMax {
+		return nil, errors.Errorf(
+			"failed to generate animated QR code: invalid frame delay: %v. it should be between %v and %v",
+			frameDelay, gifDelayUnit, gifDelayMax)
+	}
+
+	codes := make([]barcode.Barcode, len(uris))
+	maxModules := 0
+
+	for index, uri := range uris {
+		if uri == "" {
+			return nil, errors.Errorf("failed to generate animated QR code: empty URI at frame %d", index)
+		}
+
+		code, err := qr.Encode(uri, level.qrFixLevel(), qr.Auto)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate animated QR code: failed to encode frame %d", index)
+		}
+
+		codes[index] = code
+		maxModules = max(maxModules, code.Bounds().Dx())
+	}
+
+	numModules := maxModules + qrQuietZone*2
+	size := numModules * qrAnimatedModuleSize
+	palette := color.Palette{color.White, color.Black} // index 0 is the background
+	delay := int((frameDelay + gifDelayUnit - 1) / gifDelayUnit)
+
+	//nolint:exhaustruct // the rest of the fields are optional
+	anim := &gif.GIF{
+		LoopCount: 0, // loop forever
+	}
+
+	for _, code := range codes {
+		img := image.NewPaletted(image.Rect(0, 0, size, size), palette)
+		drawQRModules(img, code, (numModules-code.Bounds().Dx())/2)
+
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	var buf bytes.Buffer
+
+	if err := gifEncodeAll(&buf, anim); err != nil {
+		return nil, errors.Wrap(err, "failed to encode animated QR code to GIF")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// drawQRModules draws the dark modules of the QR code on the paletted image with
+// the palette index 1. The offset is the position of the QR code in modules from
+// the top-left corner.
+func drawQRModules(img *image.Paletted, code barcode.Barcode, offset int) {
+	bounds := code.Bounds()
+
+	for row := range bounds.Dy() {
+		for col := range bounds.Dx() {
+			gray, _ := color.GrayModel.Convert(code.At(bounds.Min.X+col, bounds.Min.Y+row)).(color.Gray)
+			if gray.Y >= grayThreshold {
+				continue
+			}
+
+			posX := (offset + col) * qrAnimatedModuleSize
+			posY := (offset + row) * qrAnimatedModuleSize
+
+			for y := posY; y < posY+qrAnimatedModuleSize; y++ {
+				for x := posX; x < posX+qrAnimatedModuleSize; x++ {
+					img.SetColorIndex(x, y, 1)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package totp
+
+import (
+	"bytes"
+	"image/gif"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  AnimatedQR()
+// ----------------------------------------------------------------------------
+
+func TestAnimatedQR(t *testing.T) {
+	t.Parallel()
+
+	uris := []string{
+		"otpauth-migration://offline?data=" + strings.Repeat("A", 300),
+		"otpauth-migration://offline?data=B",
+		"otpauth-migration://offline?data=" + strings.Repeat("C", 100),
+	}
+
+	gifImg, err := AnimatedQR(uris, FixLevel30, 750*time.Millisecond)
+	require.NoError(t, err)
+
+	anim, err := gif.DecodeAll(bytes.NewReader(gifImg))
+	require.NoError(t, err)
+
+	require.Len(t, anim.Image, len(uris), "each URI should be a frame")
+	require.Equal(t, []int{75, 75, 75}, anim.Delay, "delay should be in 1/100 second")
+	require.Zero(t, anim.LoopCount, "it should loop forever")
+
+	// All the frames fit the largest QR code
+	largest, err := qr.Encode(uris[0], qr.H, qr.Auto)
+	require.NoError(t, err)
+
+	size := (largest.Bounds().Dx() + qrQuietZone*2) * qrAnimatedModuleSize
+
+	for index, uri := range uris {
+		frame := anim.Image[index]
+		require.Equal(t, size, frame.Bounds().Dx(), "frame %d has a different width", index)
+		require.Equal(t, size, frame.Bounds().Dy(), "frame %d has a different height", index)
+
+		// Rasterize the frame back to the modules and compare
+		code, err := qr.Encode(uri, qr.H, qr.Auto)
+		require.NoError(t, err)
+
+		numModules := largest.Bounds().Dx() + qrQuietZone*2
+		offset := (numModules - code.Bounds().Dx()) / 2
+
+		for row := range code.Bounds().Dy() {
+			for col := range code.Bounds().Dx() {
+				expect := code.At(col, row)
+				actual := frame.At(
+					(offset+col)*qrAnimatedModuleSize+qrAnimatedModuleSize/2,
+					(offset+row)*qrAnimatedModuleSize+qrAnimatedModuleSize/2,
+				)
+
+				expR, _, _, _ := expect.RGBA()
+				actR, _, _, _ := actual.RGBA()
+				require.Equal(t, expR, actR, "frame %d: module (%d, %d) mismatch", index, row, col)
+			}
+		}
+
+		// Quiet zone is light
+		r, g, b, _ := frame.At(0, 0).RGBA()
+		require.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b})
+	}
+
+	// Delay is rounded up
+	gifImg, err = AnimatedQR(uris[1:2], FixLevelDefault, 15*time.Millisecond)
+	require.NoError(t, err)
+
+	anim, err = gif.DecodeAll(bytes.NewReader(gifImg))
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, anim.Delay)
+}
+
+func TestAnimatedQR_bad_input(t *testing.T) {
+	t.Parallel()
+
+	uris := []string{"otpauth-migration://offline?data=A"}
+
+	for index, test := range []struct {
+		uris   []string
+		level  FixLevel
+		delay  time.Duration
+		expect string
+	}{
+		{nil, FixLevelDefault, time.Second, "no URIs to encode"},
+		{uris, FixLevel(100), time.Second, "unsupported fix level: 100"},
+		{uris, FixLevelDefault, time.Millisecond, "invalid frame delay"},
+		{uris, FixLevelDefault, 24 * time.Hour, "invalid frame delay"},
+		{[]string{uris[0], ""}, FixLevelDefault, time.Second, "empty URI at frame 1"},
+		{[]string{strings.Repeat("A", 8000)}, FixLevelDefault, time.Second, "failed to encode frame 0"},
+	} {
+		gifImg, err := AnimatedQR(test.uris, test.level, test.delay)
+
+		require.Error(t, err, "test #%d should fail", index+1)
+		require.Contains(t, err.Error(), test.expect, "test #%d: unexpected error message", index+1)
+		require.Nil(t, gifImg)
+	}
+}
+
+//nolint:paralleltest // disable parallel test due to monkey patching during test
+func TestAnimatedQR_encode_fail(t *testing.T) {
+	// Backup and defer restore
+	oldGifEncodeAll := gifEncodeAll
+	defer func() {
+		gifEncodeAll = oldGifEncodeAll
+	}()
+
+	// Mock gifEncodeAll to force return error
+	gifEncodeAll = func(_ io.Writer, _ *gif.GIF) error {
+		return errors.New("forced error")
+	}
+
+	gifImg, err := AnimatedQR([]string{"otpauth-migration://offline?data=A"}, FixLevelDefault, time.Second)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to encode animated QR code to GIF")
+	require.Contains(t, err.Error(), "forced error")
+	require.Nil(t, gifImg)
+}
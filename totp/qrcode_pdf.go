@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// qrQuietZone is the width of the quiet zone (margin) around the QR code in
+// modules. The QR code specification requires at least 4 modules.
+const qrQuietZone = 4
+
+// PDF returns a minimal single-page PDF document of the QR code in bytes. The
+// QR code is drawn as black vector rectangles on white, so it has no
+// rasterization artifacts when printed. Such as enrollment sheets.
+//
+// The moduleSize is the width and height of each module in points (1/72 inch).
+// The page size fits the QR code including the quiet zone of 4 modules, which
+// is required to be scanned.
+func (q *QRCode) PDF(moduleSize float64) ([]byte, error) {
+	if moduleSize <= 0 || math.IsInf(moduleSize, 0) || math.IsNaN(moduleSize) {
+		return nil, errors.Errorf("invalid module size: %v. it should be a positive number", moduleSize)
+	}
+
+	matrix, err := q.Matrix()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate QR code PDF")
+	}
+
+	numModules := len(matrix) + qrQuietZone*2
+	pageSize := formatPDFNum(float64(numModules) * moduleSize)
+	size := formatPDFNum(moduleSize)
+
+	// Content stream. White background then the dark modules. The origin of PDF
+	// is the bottom-left corner, so the rows are flipped.
+	var content bytes.Buffer
+
+	fmt.Fprintf(&content, "1 g 0 0 %s %s re f\n0 g\n", pageSize, pageSize)
+
+	for row, cols := range matrix {
+		posY := formatPDFNum(float64(numModules-qrQuietZone-row-1) * moduleSize)
+
+		for col, isDark := range cols {
+			if isDark {
+				posX := formatPDFNum(float64(qrQuietZone+col) * moduleSize)
+				fmt.Fprintf(&content, "%s %s %s %s re\n", posX, posY, size, size)
+			}
+		}
+	}
+
+	content.WriteString("f\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 " + pageSize + " " + pageSize + "] " +
+			"/Contents 4 0 R /Resources << >> >>",
+		"<< /Length " + strconv.Itoa(content.Len()) + " >>\nstream\n" + content.String() + "endstream",
+	}
+
+	return writePDF(objects), nil
+}
+
+// formatPDFNum formats the number for PDF in the shortest form.
+func formatPDFNum(num float64) string {
+	return strconv.FormatFloat(num, 'f', -1, 64)
+}
+
+// writePDF writes the objects as a PDF document with the cross-reference table.
+// The first object must be the catalog.
+func writePDF(objects []string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+
+	for index, obj := range objects {
+		offsets[index] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", index+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, xrefOffset)
+
+	return buf.Bytes()
+}
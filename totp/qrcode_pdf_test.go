@@ -0,0 +1,100 @@
+package totp
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  QRCode.PDF()
+// ----------------------------------------------------------------------------
+
+func TestQRCode_PDF_golden(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	matrix, err := qrCode.Matrix()
+	require.NoError(t, err)
+
+	pdf, err := qrCode.PDF(2.5)
+	require.NoError(t, err)
+
+	require.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+
+	// Page size includes the quiet zone
+	pageSize := formatPDFNum(float64(len(matrix)+qrQuietZone*2) * 2.5)
+	require.Contains(t, string(pdf), "/MediaBox [0 0 "+pageSize+" "+pageSize+"]")
+
+	// One rectangle for the background plus one for each dark module
+	numDark := 0
+
+	for _, row := range matrix {
+		for _, isDark := range row {
+			if isDark {
+				numDark++
+			}
+		}
+	}
+
+	require.Equal(t, numDark+1, bytes.Count(pdf, []byte(" re")))
+
+	// Top-left module of the finder pattern is placed right after the quiet zone
+	posTop := formatPDFNum(float64(len(matrix)+qrQuietZone-1) * 2.5)
+	require.Contains(t, string(pdf), "\n10 "+posTop+" 2.5 2.5 re\n")
+
+	// Cross-reference table points to each object
+	startXref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, startXref)
+
+	xrefOffset, err := strconv.Atoi(string(startXref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(pdf[xrefOffset:], []byte("xref\n")))
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1)
+	require.Len(t, offsets, 4)
+
+	for index, offset := range offsets {
+		pos, err := strconv.Atoi(string(offset[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(pdf[pos:], []byte(strconv.Itoa(index+1)+" 0 obj\n")),
+			"offset of object %d is wrong", index+1)
+	}
+}
+
+func TestQRCode_PDF_bad_args(t *testing.T) {
+	t.Parallel()
+
+	qrCode := QRCode{
+		URI:   URI("otpauth://totp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"),
+		Level: FixLevelDefault,
+	}
+
+	for _, size := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		pdf, err := qrCode.PDF(size)
+
+		require.Error(t, err, "size: %v", size)
+		require.Contains(t, err.Error(), "invalid module size")
+		require.Nil(t, pdf)
+	}
+
+	//nolint:exhaustruct // missing fields are intentional
+	qrEmpty := QRCode{}
+
+	pdf, err := qrEmpty.PDF(1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to generate QR code PDF")
+	require.Nil(t, pdf)
+}
@@ -0,0 +1,144 @@
+package totp
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: ModuleStyle
+// ----------------------------------------------------------------------------
+
+// ModuleStyle is the shape of the data modules of a styled QR code. See the
+// QRCode.ImageStyled() method.
+type ModuleStyle int
+
+const (
+	// ModuleSquare draws the modules as squares. This is the default and the
+	// same as QRCode.Image().
+	ModuleSquare ModuleStyle = iota
+	// ModuleDot draws the modules as circles.
+	ModuleDot
+	// ModuleRounded draws the modules as squares with rounded corners.
+	ModuleRounded
+)
+
+// finderSize is the width and height of the finder patterns in modules.
+const finderSize = 7
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// ImageStyled is similar to Image() but draws the data modules in the given
+// style for branded enrollment. Such as dots or rounded squares.
+//
+// The finder patterns on the three corners are always drawn as squares to keep
+// them recognizable for scanners. Each module is drawn in the same integer size
+// and the QR code is centered in the image. Minimum width and height is the
+// number of modules of the QR code, which is at least 21x21.
+func (q *QRCode) ImageStyled(width, height int, style ModuleStyle) (image.Image, error) {
+	if style < ModuleSquare || style > ModuleRounded {
+		return nil, errors.Errorf("unsupported module style: %d", style)
+	}
+
+	matrix, err := q.Matrix()
+	if err != nil {
+		return nil, err
+	}
+
+	numModules := len(matrix)
+	moduleSize := min(width, height) / numModules
+
+	if moduleSize < 1 {
+		return nil, errors.Errorf(
+			"failed to draw QR code: size %dx%d is too small. it should be at least %dx%d",
+			width, height, numModules, numModules,
+		)
+	}
+
+	offsetX := (width - moduleSize*numModules) / 2
+	offsetY := (height - moduleSize*numModules) / 2
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+
+	for i := range img.Pix {
+		img.Pix[i] = 0xff // white background
+	}
+
+	for row, cols := range matrix {
+		for col, isDark := range cols {
+			if !isDark {
+				continue
+			}
+
+			moduleStyle := style
+			if isFinderPattern(row, col, numModules) {
+				moduleStyle = ModuleSquare
+			}
+
+			drawModule(img, offsetX+col*moduleSize, offsetY+row*moduleSize, moduleSize, moduleStyle)
+		}
+	}
+
+	return img, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// drawModule draws a dark module in the given style at the given position.
+func drawModule(img *image.Gray, posX, posY, size int, style ModuleStyle) {
+	// Work in doubled coordinates to keep the center of the pixels integer.
+	// The radius of the rounded corners is 1/3 of the module size.
+	radiusCorner := size * 2 / 3
+
+	for y := range size {
+		for x := range size {
+			// Distance from the center of the module to the center of the pixel
+			distX := abs(x*2 + 1 - size)
+			distY := abs(y*2 + 1 - size)
+
+			switch style {
+			case ModuleDot:
+				if distX*distX+distY*distY > size*size {
+					continue
+				}
+			case ModuleRounded:
+				// Outside of the rounded corner
+				cornerX := distX - (size - radiusCorner)
+				cornerY := distY - (size - radiusCorner)
+
+				if cornerX > 0 && cornerY > 0 && cornerX*cornerX+cornerY*cornerY > radiusCorner*radiusCorner {
+					continue
+				}
+			case ModuleSquare:
+			}
+
+			img.SetGray(posX+x, posY+y, color.Gray{Y: 0})
+		}
+	}
+}
+
+// isFinderPattern returns true if the module is in one of the finder patterns
+// on the top-left, top-right and bottom-left corners.
+func isFinderPattern(row, col, numModules int) bool {
+	isTop := row < finderSize
+	isLeft := col < finderSize
+	isRight := col >= numModules-finderSize
+	isBottom := row >= numModules-finderSize
+
+	return isTop && (isLeft || isRight) || isBottom && isLeft
+}
+
+// abs returns the absolute value of the integer.
+func abs(num int) int {
+	if num < 0 {
+		return -num
+	}
+
+	return num
+}
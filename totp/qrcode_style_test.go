@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  QRCode.ImageStyled()
+// ----------------------------------------------------------------------------
+
+func TestQRCode_ImageStyled_golden(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	matrix, err := qrCode.Matrix()
+	require.NoError(t, err)
+
+	const width, height = 300, 320
+
+	numModules := len(matrix)
+	moduleSize := width / numModules
+	offsetX := (width - moduleSize*numModules) / 2
+	offsetY := (height - moduleSize*numModules) / 2
+
+	isDarkAt := func(img image.Image, x, y int) bool {
+		gray, _ := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+
+		return gray.Y < grayThreshold
+	}
+
+	for _, style := range []ModuleStyle{ModuleSquare, ModuleDot, ModuleRounded} {
+		img, err := qrCode.ImageStyled(width, height, style)
+		require.NoError(t, err, "style: %d", style)
+		require.Equal(t, image.Rect(0, 0, width, height), img.Bounds())
+
+		// The modules are still readable by sampling the center of each module.
+		for row, cols := range matrix {
+			for col, isDark := range cols {
+				centerX := offsetX + col*moduleSize + moduleSize/2
+				centerY := offsetY + row*moduleSize + moduleSize/2
+
+				require.Equal(t, isDark, isDarkAt(img, centerX, centerY),
+					"style: %d, module (%d, %d) mismatch", style, row, col)
+			}
+		}
+
+		// Finder patterns are always square. The corner pixel is dark.
+		require.True(t, isDarkAt(img, offsetX, offsetY),
+			"style: %d, finder pattern should be square", style)
+	}
+}
+
+func TestQRCode_ImageStyled_dot_shape(t *testing.T) {
+	t.Parallel()
+
+	qrCode := QRCode{
+		URI:   URI("otpauth://totp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"),
+		Level: FixLevelDefault,
+	}
+
+	matrix, err := qrCode.Matrix()
+	require.NoError(t, err)
+
+	numModules := len(matrix)
+	moduleSize := 10
+	size := numModules * moduleSize
+
+	imgSquare, err := qrCode.ImageStyled(size, size, ModuleSquare)
+	require.NoError(t, err)
+
+	imgDot, err := qrCode.ImageStyled(size, size, ModuleDot)
+	require.NoError(t, err)
+
+	// Find a dark data module outside of the finder patterns
+	for row, cols := range matrix {
+		for col, isDark := range cols {
+			if !isDark || isFinderPattern(row, col, numModules) {
+				continue
+			}
+
+			x, y := col*moduleSize, row*moduleSize
+
+			require.Equal(t, color.Gray{Y: 0}, imgSquare.At(x, y), "square module corner should be dark")
+			require.Equal(t, color.Gray{Y: 0xff}, imgDot.At(x, y), "dot module corner should be light")
+
+			return
+		}
+	}
+
+	t.Fatal("no dark data module found")
+}
+
+func TestQRCode_ImageStyled_bad_args(t *testing.T) {
+	t.Parallel()
+
+	qrCode := QRCode{
+		URI:   URI("otpauth://totp/Example.com:alice@example.com?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"),
+		Level: FixLevelDefault,
+	}
+
+	img, err := qrCode.ImageStyled(100, 100, ModuleStyle(99))
+	require.ErrorContains(t, err, "unsupported module style: 99")
+	require.Nil(t, img)
+
+	img, err = qrCode.ImageStyled(10, 100, ModuleDot)
+	require.ErrorContains(t, err, "size 10x100 is too small")
+	require.Nil(t, img)
+
+	//nolint:exhaustruct // missing fields are intentional
+	qrEmpty := QRCode{}
+
+	img, err = qrEmpty.ImageStyled(100, 100, ModuleDot)
+	require.ErrorContains(t, err, "empty URI")
+	require.Nil(t, img)
+}
@@ -0,0 +1,56 @@
+package totp
+
+import "strings"
+
+// Terminal returns the QR code as a string of Unicode half-block characters to
+// be printed in terminals, such as for the enrollment over SSH. Each character
+// represents two modules vertically. It includes the quiet zone (margin) of 4
+// modules around the QR code and each line ends with a newline.
+//
+// The light modules are drawn with the blocks and the dark modules are left
+// blank. So it is meant to be printed in terminals with a dark background and
+// a light foreground, which is the most common.
+func (q *QRCode) Terminal() (string, error) {
+	matrix, err := q.Matrix()
+	if err != nil {
+		return "", err
+	}
+
+	numModules := len(matrix) + qrQuietZone*2
+
+	// isLight returns true if the module at the position, including the quiet
+	// zone, is light. The positions out of range are light as well.
+	isLight := func(row, col int) bool {
+		row -= qrQuietZone
+		col -= qrQuietZone
+
+		if row < 0 || row >= len(matrix) || col < 0 || col >= len(matrix[row]) {
+			return true
+		}
+
+		return !matrix[row][col]
+	}
+
+	var out strings.Builder
+
+	for row := 0; row < numModules; row += 2 {
+		for col := range numModules {
+			top, bottom := isLight(row, col), isLight(row+1, col)
+
+			switch {
+			case top && bottom:
+				out.WriteRune('█')
+			case top:
+				out.WriteRune('▀')
+			case bottom:
+				out.WriteRune('▄')
+			default:
+				out.WriteRune(' ')
+			}
+		}
+
+		out.WriteByte('\n')
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  QRCode.Terminal() and Key.QRCodeTerminal()
+// ----------------------------------------------------------------------------
+
+func TestKey_QRCodeTerminal_rasterize(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSecretSize(20))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	qrString, err := key.QRCodeTerminal(FixLevelDefault)
+	require.NoError(t, err)
+
+	qrCode, err := key.QRCode(FixLevelDefault)
+	require.NoError(t, err)
+
+	matrix, err := qrCode.Matrix()
+	require.NoError(t, err)
+
+	// Rasterize the string back to the modules. True means a dark module.
+	lines := strings.Split(strings.TrimSuffix(qrString, "\n"), "\n")
+	raster := make([][]bool, 0, len(lines)*2)
+
+	for _, line := range lines {
+		top := []bool{}
+		bottom := []bool{}
+
+		for _, char := range line {
+			switch char {
+			case '█':
+				top, bottom = append(top, false), append(bottom, false)
+			case '▀':
+				top, bottom = append(top, false), append(bottom, true)
+			case '▄':
+				top, bottom = append(top, true), append(bottom, false)
+			case ' ':
+				top, bottom = append(top, true), append(bottom, true)
+			default:
+				t.Fatalf("unexpected character: %q", char)
+			}
+		}
+
+		raster = append(raster, top, bottom)
+	}
+
+	numModules := len(matrix) + qrQuietZone*2
+	require.Len(t, raster[0], numModules, "width should include the quiet zone")
+	require.GreaterOrEqual(t, len(raster), numModules, "height should include the quiet zone")
+
+	for row := range raster {
+		for col := range raster[row] {
+			inRow := row - qrQuietZone
+			inCol := col - qrQuietZone
+
+			expect := false // quiet zone is light
+			if inRow >= 0 && inRow < len(matrix) && inCol >= 0 && inCol < len(matrix) {
+				expect = matrix[inRow][inCol]
+			}
+
+			require.Equal(t, expect, raster[row][col], "module mismatch at row %d, col %d", row, col)
+		}
+	}
+}
+
+func TestKey_QRCodeTerminal_bad_fix_level(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	qrString, err := key.QRCodeTerminal(FixLevel(100))
+
+	require.ErrorContains(t, err, "unsupported fix level")
+	require.Empty(t, qrString)
+}
+
+func TestQRCode_Terminal_empty_uri(t *testing.T) {
+	t.Parallel()
+
+	//nolint:exhaustruct // missing fields are intentional
+	qrCode := QRCode{}
+
+	qrString, err := qrCode.Terminal()
+
+	require.Error(t, err)
+	require.Empty(t, qrString)
+}
@@ -5,6 +5,8 @@ import (
 	"image"
 	"image/png"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/boombuler/barcode"
@@ -13,6 +15,34 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestQRCode_ETag(t *testing.T) {
+	t.Parallel()
+
+	uri := URI("otpauth://totp/Example.com:alice@example.com?issuer=Example.com&" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+
+	//nolint:exhaustruct // allow missing fields
+	qrCode := &QRCode{URI: uri, Level: FixLevelDefault}
+
+	etag := qrCode.ETag()
+
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, etag, "should be a quoted strong entity tag")
+	require.Equal(t, etag, qrCode.ETag(), "should be stable")
+	//nolint:exhaustruct // allow missing fields
+	require.Equal(t, etag, (&QRCode{URI: uri, Level: FixLevelDefault}).ETag(), "same inputs should have the same tag")
+	require.NotContains(t, etag, "QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+
+	for _, other := range []*QRCode{
+		//nolint:exhaustruct // allow missing fields
+		{URI: uri + "&period=60", Level: FixLevelDefault},
+		//nolint:exhaustruct // allow missing fields
+		{URI: uri, Level: FixLevel30},
+		{URI: uri, Level: FixLevelDefault, Mode: QRModeByte},
+	} {
+		require.NotEqual(t, etag, other.ETag(), "different inputs should have different tags: %+v", other)
+	}
+}
+
 func TestQRCode_PNG_golden(t *testing.T) {
 	t.Parallel()
 
@@ -109,6 +139,121 @@ func TestQRCode_Image_failed_to_scale(t *testing.T) {
 	require.Nil(t, img, "it should be nil on error")
 }
 
+func TestQRCode_PNGSet_golden(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	sizes := []int{64, 128, 256}
+
+	pngSet, err := qrCode.PNGSet(sizes)
+	require.NoError(t, err, "failed to generate PNG set")
+	require.Len(t, pngSet, len(sizes))
+
+	for _, size := range sizes {
+		expect := genPNG(t, origin, size, size)
+		actual := pngSet[size]
+
+		require.Equal(t, expect, actual, "PNG image of size %d does not match", size)
+	}
+}
+
+func TestQRCode_PNGSet_too_small(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	pngSet, err := qrCode.PNGSet([]int{128, 48, 256})
+
+	require.Error(t, err, "size smaller than 49 should return error")
+	require.Contains(t, err.Error(), "of size 48: it should be at least 49x49")
+	require.Nil(t, pngSet, "it should be nil on error")
+}
+
+func TestQRCode_PNGSet_empty_uri(t *testing.T) {
+	t.Parallel()
+
+	qrCode := QRCode{
+		URI:   URI(""),
+		Level: FixLevelDefault,
+	}
+
+	pngSet, err := qrCode.PNGSet([]int{128})
+
+	require.Error(t, err, "empty URI should return error")
+	require.Contains(t, err.Error(), "failed to encode URI to QR code: empty URI")
+	require.Nil(t, pngSet, "it should be nil on error")
+}
+
+func TestQRCode_Save_golden(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	pathFile := filepath.Join(t.TempDir(), "qr-code.png")
+
+	err := qrCode.Save(pathFile, 100, 100, 0)
+	require.NoError(t, err, "failed to save QR code image")
+
+	info, err := os.Stat(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, filePermDefault, info.Mode().Perm(), "zero permission should default to 0o600")
+
+	actual, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+
+	expect := genPNG(t, origin, 100, 100)
+	require.Equal(t, expect, actual, "saved image should be the same as the PNG output")
+}
+
+func TestQRCode_Save_errors(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	dirTemp := t.TempDir()
+
+	// Render error
+	err := qrCode.Save(filepath.Join(dirTemp, "small.png"), 10, 10, 0o600)
+
+	require.Error(t, err, "too small image should return error")
+	require.Contains(t, err.Error(), "failed to render QR code image")
+
+	// Write error
+	err = qrCode.Save(filepath.Join(dirTemp, "missing", "dir", "qr.png"), 100, 100, 0o600)
+
+	require.Error(t, err, "missing directory should return error")
+	require.Contains(t, err.Error(), "failed to write QR code image")
+
+	entries, err := os.ReadDir(dirTemp)
+	require.NoError(t, err)
+	require.Empty(t, entries, "no file should be left on error")
+}
+
 // ----------------------------------------------------------------------------
 //  Helper functions
 // ----------------------------------------------------------------------------
@@ -136,3 +281,87 @@ func genPNG(t *testing.T, uri string, width, height int) []byte {
 
 	return buf.Bytes()
 }
+
+// ----------------------------------------------------------------------------
+//  QRCode.Matrix()
+// ----------------------------------------------------------------------------
+
+func TestQRCode_Matrix(t *testing.T) {
+	t.Parallel()
+
+	origin := "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	qrCode := QRCode{
+		URI:   URI(origin),
+		Level: FixLevelDefault,
+	}
+
+	matrix, err := qrCode.Matrix()
+	require.NoError(t, err)
+
+	size := len(matrix)
+	require.Zero(t, (size-21)%4, "QR code size should be 21 + 4n modules")
+
+	for _, row := range matrix {
+		require.Len(t, row, size, "matrix should be square")
+	}
+
+	// Finder patterns on the corners. Dark border with a light ring inside.
+	for _, corner := range [][2]int{{0, 0}, {0, size - 7}, {size - 7, 0}} {
+		require.True(t, matrix[corner[0]][corner[1]], "corner %v should be dark", corner)
+		require.False(t, matrix[corner[0]+1][corner[1]+1], "inner ring of %v should be light", corner)
+		require.True(t, matrix[corner[0]+3][corner[1]+3], "center of %v should be dark", corner)
+	}
+
+	//nolint:exhaustruct // missing fields are intentional
+	qrEmpty := QRCode{}
+
+	matrix, err = qrEmpty.Matrix()
+	require.Error(t, err)
+	require.Nil(t, matrix)
+}
+
+// ----------------------------------------------------------------------------
+//  QRCode.Mode
+// ----------------------------------------------------------------------------
+
+func TestQRCode_Mode(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com", WithSecretSize(32))
+	require.NoError(t, err, "failed to create key during test setup")
+
+	qrSecret, err := key.SecretQRCode(FixLevelDefault)
+	require.NoError(t, err)
+
+	sizes := make(map[QRMode]int)
+
+	for _, mode := range []QRMode{QRModeAuto, QRModeByte, QRModeAlphanumeric} {
+		qrSecret.Mode = mode
+
+		matrix, err := qrSecret.Matrix()
+		require.NoError(t, err, "mode: %d", mode)
+
+		sizes[mode] = len(matrix)
+	}
+
+	require.Less(t, sizes[QRModeAlphanumeric], sizes[QRModeByte],
+		"alphanumeric mode should be denser than byte mode for base32")
+	require.Equal(t, sizes[QRModeAlphanumeric], sizes[QRModeAuto],
+		"auto mode should pick the alphanumeric mode for base32")
+
+	// URI with lowercase letters can not be encoded in alphanumeric mode
+	qrURI, err := key.QRCode(FixLevelDefault)
+	require.NoError(t, err)
+
+	qrURI.Mode = QRModeAlphanumeric
+
+	_, err = qrURI.PNG(100, 100)
+	require.ErrorContains(t, err, "failed to encode URI to QR code")
+
+	qrURI.Mode = QRMode(99)
+
+	_, err = qrURI.PNG(100, 100)
+	require.ErrorContains(t, err, "unsupported mode 99")
+}
@@ -1,9 +1,14 @@
 package totp
 
 import (
+	"crypto/subtle"
+	"encoding/asn1"
 	"encoding/base32"
 	"encoding/base64"
+	"encoding/hex"
+	"math"
 	"math/big"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -12,9 +17,39 @@ import (
 //  Type: Secret
 // ----------------------------------------------------------------------------
 
+// SecretSizeMin is the minimum size of a secret in bytes. According to the
+// RFC4226, the secret MUST be at least 128 bits = 16 bytes.
+// See:
+//
+//	https://www.rfc-editor.org/rfc/rfc4226#section-4
+const SecretSizeMin = 16
+
 // Secret is a byte slice that represents a secret key.
 type Secret []byte
 
+// encCrockford is the Crockford's base32 encoding with no padding. It excludes
+// the ambiguous letters I, L, O and U. See:
+//
+//	https://www.crockford.com/base32.html
+//
+//nolint:gochecknoglobals // read-only encoding table
+var encCrockford = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+// encBase62 is the base of the base62 encoding. The alphabet is 0-9, a-z and
+// A-Z in this order as math/big does.
+const encBase62 = 62
+
+// maxEntropy is the maximum Shannon entropy of a byte in bits.
+const maxEntropy = 8
+
+// minEntropyAttempts is the number of attempts to generate a secret that meets
+// the minimum entropy set by WithMinEntropy().
+const minEntropyAttempts = 3
+
+// maskedVisible is the number of characters left visible at each end of the
+// masked secret. See Secret.Masked().
+const maskedVisible = 4
+
 // ----------------------------------------------------------------------------
 //  Constructiors
 // ----------------------------------------------------------------------------
@@ -25,8 +60,12 @@ func NewSecretBytes(input []byte) Secret {
 }
 
 // NewSecretBase32 creates a new Secret object from a base32 encoded string.
-// The string must be encoded with no padding.
+// The string is usually encoded with no padding as in TOTP URIs. The trailing
+// padding (RFC 4648), such as the output of Secret.Base32Padded(), is accepted
+// as well.
 func NewSecretBase32(base32string string) (Secret, error) {
+	base32string = strings.TrimRight(base32string, "=")
+
 	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(base32string)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to decode base32 string")
@@ -35,18 +74,60 @@ func NewSecretBase32(base32string string) (Secret, error) {
 	return Secret(decoded), nil
 }
 
+// NewSecretBase32Crockford creates a new Secret object from a Crockford's
+// base32 encoded string. Such as the one from Secret.Base32Crockford().
+//
+// The string is case-insensitive and hyphens are ignored for readability. The
+// ambiguous letters are decoded as the digits they resemble, "O" as "0" and
+// "I" or "L" as "1". Note that this is NOT the encoding used in TOTP URIs.
+func NewSecretBase32Crockford(crockfordString string) (Secret, error) {
+	normalized := strings.NewReplacer(
+		"-", "",
+		"O", "0",
+		"I", "1",
+		"L", "1",
+	).Replace(strings.ToUpper(strings.TrimSpace(crockfordString)))
+
+	decoded, err := encCrockford.DecodeString(normalized)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode Crockford's base32 string")
+	}
+
+	return Secret(decoded), nil
+}
+
 // NewSecretBase62 creates a new Secret object from a base62 encoded string.
+//
+// The string must consist of the base62 alphabet only, which is 0-9, a-z and
+// A-Z. Sign prefixes and other characters are rejected. Each leading "0" is
+// decoded to a leading zero byte. See Secret.Base62() for details.
 func NewSecretBase62(base62string string) (Secret, error) {
-	var i big.Int
+	if base62string == "" {
+		return nil, errors.New("failed to decode base62 string: empty string")
+	}
+
+	for index, char := range base62string {
+		if !isBase62(char) {
+			return nil, errors.Errorf("failed to decode base62 string: invalid character %q at %d", char, index)
+		}
+	}
+
+	// Leading zero bytes
+	numZeros := len(base62string) - len(strings.TrimLeft(base62string, "0"))
+	decoded := make([]byte, numZeros)
 
-	encBase62 := 62
+	if rest := base62string[numZeros:]; rest != "" {
+		var i big.Int
 
-	decoded, ok := i.SetString(base62string, encBase62)
-	if !ok {
-		return nil, errors.New("failed to decode base62 string")
+		value, ok := i.SetString(rest, encBase62)
+		if !ok {
+			return nil, errors.New("failed to decode base62 string")
+		}
+
+		decoded = append(decoded, value.Bytes()...)
 	}
 
-	return Secret(decoded.Bytes()), nil
+	return Secret(decoded), nil
 }
 
 // NewSecretBase64 creates a new Secret object from a base64 encoded string.
@@ -60,6 +141,17 @@ func NewSecretBase64(base64string string) (Secret, error) {
 	return Secret(decoded), nil
 }
 
+// NewSecretHex creates a new Secret object from a hex encoded string. Both upper
+// and lower case letters are accepted.
+func NewSecretHex(hexString string) (Secret, error) {
+	decoded, err := hex.DecodeString(hexString)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode hex string")
+	}
+
+	return Secret(decoded), nil
+}
+
 // ----------------------------------------------------------------------------
 //  Methods
 // ----------------------------------------------------------------------------
@@ -70,13 +162,44 @@ func (s Secret) Base32() string {
 	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(s)
 }
 
-// Base62 returns the secret as a base62 encoded string.
+// Base32Padded returns the secret as a base32 encoded string with padding
+// (RFC 4648). Use it for the consumers that require the padding. Note that the
+// TOTP URIs use the one with no padding, which is Base32().
+func (s Secret) Base32Padded() string {
+	return base32.StdEncoding.EncodeToString(s)
+}
+
+// Base32Crockford returns the secret as a Crockford's base32 encoded string
+// with no padding.
+//
+// It has no ambiguous letters (I, L, O and U), which is handy for manual entry
+// UIs. Note that this is NOT the encoding used in TOTP URIs, use Base32() for
+// them.
+func (s Secret) Base32Crockford() string {
+	return encCrockford.EncodeToString(s)
+}
+
+// Base62 returns the secret as a base62 encoded string of the alphabet 0-9,
+// a-z and A-Z.
+//
+// Each leading zero byte is encoded as a leading "0", the same way as base58 of
+// Bitcoin does. So the secrets with leading zero bytes round-trip through
+// NewSecretBase62() without losing them.
 func (s Secret) Base62() string {
-	var i big.Int
+	numZeros := 0
+	for numZeros < len(s) && s[numZeros] == 0 {
+		numZeros++
+	}
+
+	encoded := strings.Repeat("0", numZeros)
 
-	encBase62 := 62
+	if numZeros < len(s) {
+		var i big.Int
 
-	return i.SetBytes(s[:]).Text(encBase62)
+		encoded += i.SetBytes(s[numZeros:]).Text(encBase62)
+	}
+
+	return encoded
 }
 
 // Base64 returns the secret as a base64 encoded string with padding (RFC 4648).
@@ -91,8 +214,239 @@ func (s Secret) Bytes() []byte {
 	return s
 }
 
+// DER returns the secret as a DER encoded ASN.1 OCTET STRING. Which is the raw
+// secret prefixed with the tag (0x04) and the length.
+//
+// It is the common format to import a generic secret key, such as the HMAC key
+// of TOTP, into HSMs and key management tools. Note that this is NOT the format
+// used in TOTP URIs or PEM() of the Key.
+func (s Secret) DER() ([]byte, error) {
+	out, err := asn1.Marshal([]byte(s))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode secret to DER")
+	}
+
+	return out, nil
+}
+
+// Entropy returns the Shannon entropy of the secret in bits per byte. Which is
+// in the range of 0 (e.g. all bytes are the same) to 8. Zero if empty.
+//
+// Note that it is an estimate from the byte distribution and its maximum is
+// limited by the size of the secret, which is log2(size) for secrets shorter
+// than 256 bytes.
+func (s Secret) Entropy() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+
+	for _, b := range s {
+		counts[b]++
+	}
+
+	total := float64(len(s))
+	entropy := 0.0
+
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+
+		prob := float64(count) / total
+		entropy -= prob * math.Log2(prob)
+	}
+
+	return entropy
+}
+
+// Equal returns true if the secret is the same as the other secret. The raw
+// bytes are compared in constant time, so it does not matter which encoding
+// the secrets were created from.
+//
+// Empty (or nil) secrets are never equal to anything, including another empty
+// secret, since an empty secret is not a valid secret.
+func (s Secret) Equal(other Secret) bool {
+	if len(s) == 0 || len(other) == 0 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(s, other) == 1
+}
+
+// Hex returns the secret as a lower case hex encoded string. Which is the raw
+// HMAC key that some HSMs accept on import.
+func (s Secret) Hex() string {
+	return hex.EncodeToString(s)
+}
+
+// Len returns the length of the secret in bytes.
+func (s Secret) Len() int {
+	return len(s)
+}
+
+// MarshalText is an implementation of the encoding.TextMarshaler interface. It
+// returns the secret as a base32 encoded string with no padding. The same as
+// Base32().
+//
+// This allows the Secret to be used in text-based formats such as JSON, TOML,
+// query strings and flag values in the same encoding as the TOTP URIs.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.Base32()), nil
+}
+
+// Masked returns the secret in base32 with all but the first and last 4
+// characters replaced by "•". Such as to show the secret on a settings page for
+// the users to verify it. e.g.:
+//
+//	QF7N••••••••••••••••••••••••G3Z3
+//
+// The length is kept. If the base32 is 16 characters or shorter, all the
+// characters are masked so that at most half of it is revealed.
+//
+// It is a presentation helper for display only and NOT a security control. Even
+// the visible characters are part of the secret, so do not log it. Use the
+// fingerprints for identifying the secrets, such as Key.SharedSecretFingerprint().
+func (s Secret) Masked() string {
+	const (
+		bullet    = "•"
+		lenMinVis = maskedVisible * 4 // reveal at most half of the secret
+	)
+
+	encoded := s.Base32()
+	if len(encoded) <= lenMinVis {
+		return strings.Repeat(bullet, len(encoded))
+	}
+
+	return encoded[:maskedVisible] +
+		strings.Repeat(bullet, len(encoded)-maskedVisible*2) +
+		encoded[len(encoded)-maskedVisible:]
+}
+
+// MeetsRFC4226Minimum returns true if the secret is at least SecretSizeMin
+// bytes long, which is the minimum length required by RFC4226.
+func (s Secret) MeetsRFC4226Minimum() bool {
+	return s.Len() >= SecretSizeMin
+}
+
 // String is an implementation of the Stringer interface. It is an alias for
 // Base32().
 func (s Secret) String() string {
 	return s.Base32()
 }
+
+// Validate returns an error if the secret is shorter than SecretSizeMin bytes,
+// the minimum length required by RFC4226. The error wraps ErrSecretTooShort.
+//
+// A base32 string of a partially pasted secret decodes fine but to fewer bytes.
+// Use this method to catch such truncated or corrupted secrets early.
+func (s Secret) Validate() error {
+	if !s.MeetsRFC4226Minimum() {
+		return errors.Wrapf(ErrSecretTooShort, "%d bytes. it should be at least %d bytes (RFC4226 minimum)",
+			s.Len(), SecretSizeMin)
+	}
+
+	return nil
+}
+
+// UnmarshalText is an implementation of the encoding.TextUnmarshaler interface.
+// It decodes the base32 encoded text to the secret.
+//
+// The decoding is tolerant. Surrounding spaces, lower case letters and padding
+// are accepted.
+//
+// For backward compatibility, the standard base64 text is also accepted. It is
+// the form that encoding/json used for the Secret before MarshalText() was
+// implemented. The base64 is detected by the characters out of the base32
+// alphabet, such as "+", "/", "0", "1", "8" and "9", or the mixed case letters.
+// Otherwise, the text is decoded as base32 first and then as base64.
+func (s *Secret) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+
+	if isLegacyBase64(str) {
+		if decoded, err := base64.StdEncoding.DecodeString(str); err == nil {
+			*s = decoded
+
+			return nil
+		}
+	}
+
+	decoded, err := decodeBase32Tolerant(str)
+	if err != nil {
+		legacy, errLegacy := base64.StdEncoding.DecodeString(str)
+		if errLegacy != nil {
+			return errors.Wrap(err, "failed to unmarshal secret")
+		}
+
+		decoded = legacy
+	}
+
+	*s = decoded
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// secretSizeFromBase32Length returns the largest secret size in bytes whose
+// base32 encoding is exactly the given number of characters. See
+// WithSecretBase32Length() for the details.
+func secretSizeFromBase32Length(chars int, padded bool) (uint, error) {
+	const (
+		bitsPerChar = 5
+		bitsPerByte = 8
+		groupChars  = 8
+	)
+
+	if chars <= 0 {
+		return 0, errors.Errorf("invalid base32 length: %d. it should be positive", chars)
+	}
+
+	if padded && chars%groupChars != 0 {
+		return 0, errors.Errorf("invalid base32 length: %d. it should be a multiple of 8 with padding", chars)
+	}
+
+	size := chars * bitsPerChar / bitsPerByte
+
+	// Not achievable if the size encodes to a different length
+	if encLen := base32.StdEncoding.WithPadding(base32.NoPadding).EncodedLen(size); !padded && encLen != chars {
+		return 0, errors.Errorf("invalid base32 length: %d. the closest achievable length is %d", chars, encLen)
+	}
+
+	if size < SecretSizeMin {
+		return 0, errors.Errorf("invalid base32 length: %d. the secret size %d is smaller than %d bytes",
+			chars, size, SecretSizeMin)
+	}
+
+	return uint(size), nil
+}
+
+// decodeBase32Tolerant decodes the base32 encoded string with tolerance for
+// surrounding spaces, lower case letters and padding.
+func decodeBase32Tolerant(base32string string) ([]byte, error) {
+	normalized := strings.TrimRight(strings.ToUpper(strings.TrimSpace(base32string)), "=")
+
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalized)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode base32 string")
+	}
+
+	return decoded, nil
+}
+
+// isLegacyBase64 returns true if the string can not be a base32 string but can
+// be a base64 one. Such as the secrets marshaled to JSON as []byte by the older
+// versions. See Secret.UnmarshalText().
+func isLegacyBase64(str string) bool {
+	isMixedCase := strings.ToUpper(str) != str && strings.ToLower(str) != str
+
+	return isMixedCase || strings.ContainsAny(str, "+/0189")
+}
+
+// isBase62 returns true if the char is in the base62 alphabet.
+func isBase62(char rune) bool {
+	return ('0' <= char && char <= '9') || ('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z')
+}
@@ -1,8 +1,11 @@
 package totp
 
 import (
+	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/require"
 )
@@ -20,6 +23,34 @@ func TestNewSecretBase32_invalid_string(t *testing.T) {
 	require.Contains(t, err.Error(), "failed to decode base32 string")
 }
 
+// ----------------------------------------------------------------------------
+//  NewSecretBase32Crockford()
+// ----------------------------------------------------------------------------
+
+func TestNewSecretBase32Crockford(t *testing.T) {
+	t.Parallel()
+
+	expect := Secret("foo bar buzz")
+
+	for _, input := range []string{
+		"CSQPY832C5S20RKNF9X0",
+		"csqpy832c5s20rknf9x0",    // case-insensitive
+		"CSQPY-832C5-S20RK-NF9X0", // hyphens for readability
+		"CSQPY832C5S2ORKNF9XO",    // O as 0
+		" CSQPY832C5S20RKNF9X0\n", // surrounding spaces
+	} {
+		actual, err := NewSecretBase32Crockford(input)
+
+		require.NoError(t, err, "input: %q", input)
+		require.Equal(t, expect, actual, "input: %q", input)
+	}
+
+	_, err := NewSecretBase32Crockford("CSQPY832C5S20RKNF9XU") // U is not in the alphabet
+
+	require.Error(t, err, "invalid string should return error")
+	require.Contains(t, err.Error(), "failed to decode Crockford's base32 string")
+}
+
 // ----------------------------------------------------------------------------
 //  NewSecretBase62()
 // ----------------------------------------------------------------------------
@@ -33,6 +64,45 @@ func TestNewSecretBase62_invalid_string(t *testing.T) {
 	require.Contains(t, err.Error(), "failed to decode base62 string")
 }
 
+func TestNewSecretBase62_non_canonical(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"",
+		"+FegjEGvm7g03GQye", // sign prefix
+		"-FegjEGvm7g03GQye",
+		"FegjEGvm_7g03GQye", // underscore
+		" FegjEGvm7g03GQye", // space
+	} {
+		secret, err := NewSecretBase62(input)
+
+		require.Error(t, err, "input: %q", input)
+		require.Contains(t, err.Error(), "failed to decode base62 string")
+		require.Nil(t, secret)
+	}
+}
+
+func TestNewSecretBase62_leading_zeros_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, secret := range []Secret{
+		{0x00, 0x00, 0x01, 0x02},
+		{0x00},
+		{0x00, 0x00},
+		{0x00, 0xff, 0x00},
+		Secret("foo bar buzz"),
+	} {
+		encoded := secret.Base62()
+
+		decoded, err := NewSecretBase62(encoded)
+		require.NoError(t, err, "encoded: %q", encoded)
+		require.Equal(t, secret, decoded, "encoded: %q", encoded)
+	}
+
+	require.Equal(t, "002", Secret{0x00, 0x00, 0x02}.Base62(),
+		"each leading zero byte should be encoded as a leading zero")
+}
+
 // ----------------------------------------------------------------------------
 //  NewSecretBase64()
 // ----------------------------------------------------------------------------
@@ -64,6 +134,53 @@ func TestSecret_Base32_golden(t *testing.T) {
 		"method String() should be an alias for Base32()")
 }
 
+// ----------------------------------------------------------------------------
+//  Secret.Base32Padded()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Base32Padded_golden(t *testing.T) {
+	t.Parallel()
+
+	secret := Secret("foo bar buzz")
+
+	require.Equal(t, "MZXW6IDCMFZCAYTVPJ5A====", secret.Base32Padded())
+	require.Equal(t, "MZXW6IDCMFZCAYTVPJ5A", secret.Base32(), "Base32() should stay unpadded")
+
+	// Round trip
+	decoded, err := NewSecretBase32(secret.Base32Padded())
+	require.NoError(t, err, "padded base32 should be accepted")
+	require.Equal(t, secret, decoded)
+
+	// No padding needed
+	secret20 := Secret("12345678901234567890")
+	require.Equal(t, secret20.Base32(), secret20.Base32Padded())
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.Base32Crockford()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Base32Crockford_golden(t *testing.T) {
+	t.Parallel()
+
+	secret := Secret("foo bar buzz")
+
+	expect := "CSQPY832C5S20RKNF9X0"
+	actual := secret.Base32Crockford()
+
+	require.Equal(t, expect, actual)
+
+	// Round-trip with random secrets
+	for range 10 {
+		key, err := GenerateKey("Example.com", "alice@example.com")
+		require.NoError(t, err)
+
+		secret, err := NewSecretBase32Crockford(key.Secret.Base32Crockford())
+		require.NoError(t, err)
+		require.Equal(t, key.Secret, secret, "round-trip should be lossless")
+	}
+}
+
 // ----------------------------------------------------------------------------
 //  Secret.Base62()
 // ----------------------------------------------------------------------------
@@ -108,3 +225,273 @@ func TestSecret_Bytes_golden(t *testing.T) {
 
 	require.Equal(t, expect, actual)
 }
+
+// ----------------------------------------------------------------------------
+//  Secret.Entropy()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Entropy(t *testing.T) {
+	t.Parallel()
+
+	for index, test := range []struct {
+		input  Secret
+		expect float64
+	}{
+		{nil, 0},
+		{Secret(make([]byte, 32)), 0},
+		{Secret("abababab"), 1},
+		{Secret("abcdabcd"), 2},
+		{Secret(func() []byte {
+			out := make([]byte, 256)
+			for i := range out {
+				out[i] = byte(i)
+			}
+
+			return out
+		}()), 8},
+	} {
+		require.InDelta(t, test.expect, test.input.Entropy(), 1e-9,
+			"test #%d failed", index+1)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.Equal()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Equal(t *testing.T) {
+	t.Parallel()
+
+	secret32, err := NewSecretBase32("MZXW6IDCMFZCAYTVPJ5A")
+	require.NoError(t, err)
+
+	secret64, err := NewSecretBase64("Zm9vIGJhciBidXp6")
+	require.NoError(t, err)
+
+	require.True(t, secret32.Equal(secret64), "same secret from different encodings should be equal")
+	require.False(t, secret32.Equal(Secret([]byte("foo bar buzZ"))), "different secrets should not be equal")
+	require.False(t, secret32.Equal(Secret([]byte("foo bar"))), "different lengths should not be equal")
+	require.False(t, secret32.Equal(nil), "nil secret should not be equal")
+	require.False(t, Secret(nil).Equal(Secret{}), "empty secrets should not be equal")
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.Len()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Len_golden(t *testing.T) {
+	t.Parallel()
+
+	secret := Secret([]byte("foo bar buzz"))
+
+	require.Equal(t, 12, secret.Len())
+	require.Zero(t, Secret(nil).Len(), "nil secret should have zero length")
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.Masked()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Masked(t *testing.T) {
+	t.Parallel()
+
+	secret, err := NewSecretBase32("QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+	require.NoError(t, err)
+
+	masked := secret.Masked()
+
+	require.Equal(t, "QF7N"+strings.Repeat("•", 24)+"G3Z3", masked)
+	require.Equal(t, len(secret.Base32()), utf8.RuneCountInString(masked), "length should be kept")
+
+	for _, test := range []struct {
+		secret Secret
+		expect string
+	}{
+		{Secret(nil), ""},
+		{Secret("a"), "••"},
+		{Secret("0123456789"), strings.Repeat("•", 16)},
+		{Secret("01234567890"), "GAYT" + strings.Repeat("•", 10) + "BZGA"},
+	} {
+		require.Equal(t, test.expect, test.secret.Masked(), "secret: %q", test.secret)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.MeetsRFC4226Minimum()
+// ----------------------------------------------------------------------------
+
+func TestSecret_MeetsRFC4226Minimum(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		length int
+		expect bool
+	}{
+		{0, false},
+		{SecretSizeMin - 1, false},
+		{SecretSizeMin, true},
+		{SecretSizeMin + 1, true},
+	} {
+		secret := Secret(make([]byte, test.length))
+
+		require.Equal(t, test.expect, secret.MeetsRFC4226Minimum(),
+			"unexpected result for %d bytes of secret", test.length)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.MarshalText() and Secret.UnmarshalText()
+// ----------------------------------------------------------------------------
+
+func TestSecret_MarshalText_json_round_trip(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Secret Secret `json:"secret"`
+	}
+
+	cfgOrig := config{Secret: Secret([]byte("foo bar buzz"))}
+
+	jsonData, err := json.Marshal(cfgOrig)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"secret":"MZXW6IDCMFZCAYTVPJ5A"}`, string(jsonData),
+		"secret should be marshaled as base32 with no padding")
+
+	var cfgLoaded config
+
+	require.NoError(t, json.Unmarshal(jsonData, &cfgLoaded))
+	require.Equal(t, cfgOrig.Secret, cfgLoaded.Secret)
+}
+
+func TestSecret_UnmarshalText_tolerant(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"MZXW6IDCMFZCAYTVPJ5A",
+		"mzxw6idcmfzcaytvpj5a",
+		"MZXW6IDCMFZCAYTVPJ5A====",
+		"  MZXW6IDCMFZCAYTVPJ5A\n",
+	} {
+		var secret Secret
+
+		require.NoError(t, secret.UnmarshalText([]byte(input)), "input: %q", input)
+		require.Equal(t, []byte("foo bar buzz"), secret.Bytes(), "input: %q", input)
+	}
+}
+
+func TestSecret_UnmarshalText_legacy_json(t *testing.T) {
+	t.Parallel()
+
+	// The JSON form of the older versions, where the secret was marshaled as
+	// []byte in base64.
+	type legacyConfig struct {
+		Secret []byte `json:"secret"`
+	}
+
+	type config struct {
+		Secret Secret `json:"secret"`
+	}
+
+	for _, raw := range [][]byte{
+		[]byte("foo bar buzz"),
+		[]byte("12345678901234567890"),
+		{0xfb, 0xff, 0x00, 0x01, 0x02, 0x03},
+	} {
+		legacyJSON, err := json.Marshal(legacyConfig{Secret: raw})
+		require.NoError(t, err)
+
+		var cfgLoaded config
+
+		require.NoError(t, json.Unmarshal(legacyJSON, &cfgLoaded), "JSON: %s", legacyJSON)
+		require.Equal(t, raw, cfgLoaded.Secret.Bytes(), "JSON: %s", legacyJSON)
+	}
+
+	// Key persisted by the older versions
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	legacyJSON := `{"Secret":"` + key.Secret.Base64() + `"}`
+
+	var keyLoaded Key
+
+	require.NoError(t, json.Unmarshal([]byte(legacyJSON), &keyLoaded))
+	require.Equal(t, key.Secret, keyLoaded.Secret, "legacy JSON of the key should be decoded")
+}
+
+func TestSecret_UnmarshalText_invalid(t *testing.T) {
+	t.Parallel()
+
+	secret := Secret([]byte("keep"))
+
+	err := secret.UnmarshalText([]byte("invalid string"))
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to unmarshal secret: failed to decode base32 string")
+	require.Equal(t, []byte("keep"), secret.Bytes(), "secret should not change on error")
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.DER()
+// ----------------------------------------------------------------------------
+
+func TestSecret_DER(t *testing.T) {
+	t.Parallel()
+
+	secret := Secret("12345678901234567890")
+
+	der, err := secret.DER()
+	require.NoError(t, err)
+
+	// OCTET STRING (0x04) of 20 bytes (0x14)
+	require.Equal(t, append([]byte{0x04, 0x14}, secret...), der)
+
+	// Long form length for secrets of 128 bytes or more
+	secretLong := Secret(make([]byte, 128))
+
+	der, err = secretLong.DER()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x04, 0x81, 0x80}, der[:3])
+	require.Len(t, der, 3+128)
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.Hex() and NewSecretHex()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Hex_round_trip(t *testing.T) {
+	t.Parallel()
+
+	secret := Secret{0x00, 0x01, 0xab, 0xff}
+
+	require.Equal(t, "0001abff", secret.Hex())
+
+	for _, input := range []string{"0001abff", "0001ABFF"} {
+		decoded, err := NewSecretHex(input)
+		require.NoError(t, err, "input: %q", input)
+		require.Equal(t, secret, decoded)
+	}
+
+	for _, input := range []string{"0001abf", "zz"} {
+		decoded, err := NewSecretHex(input)
+		require.ErrorContains(t, err, "failed to decode hex string", "input: %q", input)
+		require.Nil(t, decoded)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Secret.Validate()
+// ----------------------------------------------------------------------------
+
+func TestSecret_Validate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, NewSecretBytes(make([]byte, SecretSizeMin)).Validate())
+
+	// Partially pasted base32 secret
+	secret, err := NewSecretBase32("QF7N673VMVHYW")
+	require.NoError(t, err, "truncated base32 string should decode")
+
+	err = secret.Validate()
+	require.ErrorIs(t, err, ErrSecretTooShort)
+	require.Contains(t, err.Error(), "8 bytes. it should be at least 16 bytes")
+}
@@ -18,12 +18,89 @@ import "github.com/KEINOS/go-totp/totp"
 package totp
 
 import (
+	"crypto/subtle"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/pquerna/otp/totp"
 )
 
+// filePermDefault is the default permission of the files written by this
+// package. Since the files contain the secret, only the owner can read/write.
+const filePermDefault os.FileMode = 0o600
+
+// CounterToTime returns the start time of the time step (counter) in UTC for the
+// given period in seconds. It is the inverse of TimeToCounter().
+//
+// If the period is zero, OptionPeriodDefault (30 seconds) is used as the
+// underlying OTP library does.
+func CounterToTime(counter uint64, period uint) time.Time {
+	if period == 0 {
+		period = OptionPeriodDefault
+	}
+
+	// Saturate to avoid overflow on huge counters
+	seconds := counter * uint64(period)
+	if seconds/uint64(period) != counter || seconds > math.MaxInt64 {
+		seconds = math.MaxInt64
+	}
+
+	return time.Unix(int64(seconds), 0).UTC()
+}
+
+// DynamicTruncate returns the 31-bit value extracted from the HMAC result by the
+// dynamic truncation of RFC-4226, section 5.3. The passcode is the value modulo
+// 10^digits. It is the building block of HOTP and TOTP, exposed for testing and
+// custom OTP variants.
+//
+//	offset = hmacResult[len(hmacResult)-1] & 0x0f
+//	value  = (hmacResult[offset] & 0x7f) << 24 | hmacResult[offset+1] << 16 | ...
+//
+// It returns an error if the HMAC result is too short for the offset. Such as
+// MD5, whose 16 bytes result is too short if the offset is larger than 12. The
+// offset is not adjusted, so that the value is the same as the passcodes of the
+// underlying OTP library.
+//
+// See: https://www.rfc-editor.org/rfc/rfc4226#section-5.3
+func DynamicTruncate(hmacResult []byte) (uint32, error) {
+	const (
+		lenBinary  = 4
+		maskOffset = 0x0f
+		maskSign   = 0x7fffffff
+	)
+
+	if len(hmacResult) == 0 {
+		return 0, errors.New("empty HMAC result")
+	}
+
+	offset := int(hmacResult[len(hmacResult)-1] & maskOffset)
+	if offset+lenBinary > len(hmacResult) {
+		return 0, errors.Errorf("HMAC result too short: %d bytes for the offset %d", len(hmacResult), offset)
+	}
+
+	return binary.BigEndian.Uint32(hmacResult[offset:offset+lenBinary]) & maskSign, nil
+}
+
+// GenerateURI creates a new Key object with the given options and returns the
+// key in OTP URI format. It is a shorthand of GenerateKey() and Key.URI().
+//
+// Note that the Key object itself is not returned. Therefore, the secret is
+// only available in the returned URI.
+func GenerateURI(issuer, accountName string, opts ...Option) (string, error) {
+	key, err := GenerateKey(issuer, accountName, opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate URI")
+	}
+
+	return key.URI(), nil
+}
+
 // StrToUint converts a string to an unsigned integer. If the string is not a
 // valid integer or out of range of int32, it returns 0.
 func StrToUint(number string) uint {
@@ -40,6 +117,26 @@ func StrToUint(number string) uint {
 	return uint(u)
 }
 
+// TimeToCounter returns the time step (counter) of the given time for the given
+// period in seconds. It is the value used as the moving factor of TOTP in
+// RFC-6238. Times before the Unix epoch return zero.
+//
+// This is useful to correlate logs across systems with different periods. If
+// the period is zero, OptionPeriodDefault (30 seconds) is used as the underlying
+// OTP library does.
+func TimeToCounter(t time.Time, period uint) uint64 {
+	if period == 0 {
+		period = OptionPeriodDefault
+	}
+
+	unixTime := t.Unix()
+	if unixTime < 0 {
+		return 0
+	}
+
+	return uint64(unixTime) / uint64(period)
+}
+
 // Validate returns true if the given passcode is valid for the secret and
 // options at the current time.
 //
@@ -48,30 +145,252 @@ func StrToUint(number string) uint {
 //
 // Usually, Key.Validate() method is used to validate the passcode. Use this
 // function if you have the values and simply want to validate the passcode.
+//
+// If the options have a fixed time set (see WithNow), it is used instead of the
+// current time.
 func Validate(passcode, secret string, options Options) bool {
-	validationTime := time.Now()
+	validationTime := options.timeNow()
 
 	return ValidateCustom(passcode, secret, validationTime, options)
 }
 
 // ValidateCustom is similar to Validate() but allows you to specify the time to
 // validate the passcode.
+//
+// If the options have a skew center set (see Key.ValidateResync), the validation
+// window is centered on it.
 func ValidateCustom(passcode, secret string, validationTime time.Time, options Options) bool {
-	isValid, err := totp.ValidateCustom(
-		passcode,
-		secret,
-		validationTime.UTC(),
-		totp.ValidateOpts{
-			Period:    options.Period,
-			Skew:      options.Skew,
-			Digits:    options.Digits.OTPDigits(),
-			Algorithm: options.Algorithm.OTPAlgorithm(),
-		},
-	)
+	isValid, _ := validateCustomE(passcode, secret, validationTime, options)
+
+	return isValid
+}
+
+// ValidateE is similar to Validate() but returns an error if the validation
+// could not be performed. Such as a malformed passcode, a malformed secret or
+// an unsupported algorithm in the options. So the callers can distinguish an
+// invalid passcode from a misconfiguration.
+//
+// A well-formed but wrong passcode returns false with no error. The error wraps
+// ErrEmptyPasscode, ErrMalformedPasscode, ErrUnsupportedAlgorithm or
+// ErrUnsupportedPeriod if it is the cause.
+func ValidateE(passcode, secret string, options Options) (bool, error) {
+	return validateCustomE(passcode, secret, options.timeNow(), options)
+}
+
+// checkPassCode returns an error if the passcode is empty or is not a number of
+// the given digits. The surrounding spaces are ignored as the underlying OTP
+// library does. The returned error wraps ErrEmptyPasscode or ErrMalformedPasscode.
+func checkPassCode(passcode string, digits Digits) error {
+	passcode = strings.TrimSpace(passcode)
+	width := digits.OTPDigits().Length()
+
+	if passcode == "" {
+		return errors.WithStack(ErrEmptyPasscode)
+	}
+
+	if len(passcode) != width {
+		return errors.Wrapf(ErrMalformedPasscode, "it should be %d digits but got %d", width, len(passcode))
+	}
+
+	for _, char := range passcode {
+		if char < '0' || char > '9' {
+			return errors.Wrap(ErrMalformedPasscode, "it should be numeric")
+		}
+	}
+
+	return nil
+}
+
+// matchOffset searches the window where the passcode matches within the skew
+// around the skew center of the given time. It returns the offset of the matched
+// window in periods relative to the given time. If the passcode matches more
+// than one window, the one closest to the center is returned, the later one on
+// a tie.
+func matchOffset(passcode, secret string, validationTime time.Time, options Options) (int, bool) {
+	if !options.Algorithm.IsSupported() || options.Skew > math.MaxInt32 || options.checkTOTP() != nil {
+		return 0, false
+	}
+
+	passcode = strings.TrimSpace(passcode)
+	matched, found := 0, false
+
+	forEachWindow(passcode, secret, validationTime, validationTime, options,
+		func(_ time.Time, offset int, match bool) bool {
+			if match && (!found || abs(offset-options.skewCenter) <= abs(matched-options.skewCenter)) {
+				matched, found = offset, true
+			}
+
+			return true
+		})
+
+	return matched, found
+}
+
+// forEachWindow calls fn for each window whose passcode is valid at any time
+// within the range of from and to (inclusive), in chronological order. Which are
+// the windows from (center - skew) periods of from to (center + skew) periods of
+// to. If from and to are the same, they are the windows Validate() checks.
+//
+// The fn receives the start time of the window, its offset in periods from the
+// window of from, and whether the passcode matches the passcode of the window.
+// The passcode must be prepared (see Options.preparePassCode()), an empty one
+// never matches. It stops if fn returns false.
+//
+// The callers must check the algorithm and bound the skew beforehand.
+func forEachWindow(
+	passcode, secret string, from, to time.Time, options Options,
+	fn func(window time.Time, offset int, match bool) bool,
+) {
+	first, last := windowRange(from, to, options)
+	period := periodOrDefault(options)
+	origin := TimeToCounter(from, period)
+
+	otpOpts := options.otpOpts()
+	otpOpts.Period = period
+	otpOpts.Skew = 0
+
+	for counter := first; counter <= last; counter++ {
+		window := CounterToTime(counter, period)
+
+		expect, err := totp.GenerateCodeCustom(secret, window, otpOpts)
+		match := err == nil && passcode != "" && subtle.ConstantTimeCompare([]byte(expect), []byte(passcode)) == 1
+
+		//nolint:gosec // the range is bounded by the callers
+		if !fn(window, int(counter)-int(origin), match) {
+			return
+		}
+	}
+}
+
+// windowRange returns the counters of the first and the last windows iterated
+// by forEachWindow(). The passcode of a window validates from (center + skew)
+// periods before the window to (center - skew) periods after the window.
+func windowRange(from, to time.Time, options Options) (uint64, uint64) {
+	period := periodOrDefault(options)
+	periodDur := time.Duration(period) * time.Second
+	skew := int(options.Skew) //nolint:gosec // bounded by the callers
+
+	first := TimeToCounter(from.Add(time.Duration(options.skewCenter-skew)*periodDur), period)
+	last := TimeToCounter(to.Add(time.Duration(options.skewCenter+skew)*periodDur), period)
+
+	return first, last
+}
+
+// periodOrDefault returns the period of the options or the default period if
+// not set. The same as the underlying OTP library does.
+func periodOrDefault(options Options) uint {
+	if options.Period == 0 {
+		return OptionPeriodDefault
+	}
+
+	return options.Period
+}
+
+// shiftSkewCenter shifts the given time by the skew center of the options.
+func shiftSkewCenter(validationTime time.Time, options Options) time.Time {
+	if options.skewCenter == 0 {
+		return validationTime
+	}
+
+	period := time.Duration(periodOrDefault(options)) * time.Second
+
+	return validationTime.Add(time.Duration(options.skewCenter) * period)
+}
+
+// validateCustomE is the implementation of ValidateCustom() and ValidateE().
+func validateCustomE(passcode, secret string, validationTime time.Time, options Options) (bool, error) {
+	secret = options.pepperSecretBase32(secret)
+
+	// Reject empty or malformed passcodes early
+	passcode, err := options.preparePassCode(passcode)
+	if err != nil {
+		observeFailure(options)
+
+		return false, errors.Wrap(err, "invalid passcode")
+	}
+
+	// The underlying OTP library panics on unsupported algorithms
+	if !options.Algorithm.IsSupported() {
+		observeFailure(options)
+
+		return false, errors.Wrapf(ErrUnsupportedAlgorithm, "invalid options: %q", options.Algorithm)
+	}
+
+	if !options.isPeriodAccepted(options.Period) {
+		observeFailure(options)
+
+		return false, errors.Wrapf(ErrUnsupportedPeriod, "invalid options: %d", options.Period)
+	}
+
+	if err := options.checkTOTP(); err != nil {
+		observeFailure(options)
+
+		return false, errors.Wrap(err, "invalid options")
+	}
+
+	if _, err := decodeBase32Tolerant(secret); err != nil {
+		observeFailure(options)
+
+		return false, errors.Wrap(err, "failed to validate passcode")
+	}
+
+	offset, isValid := matchOffset(passcode, secret, validationTime.UTC(), options)
+	observeValidation(options, passcode, secret, validationTime.UTC(), offset, isValid)
+
+	if !isValid && options.logger != nil {
+		options.logger.Debugf("totp: validation failed within skew: skew=%d period=%d issuer=%q account=%q fingerprint=%s",
+			options.Skew, options.Period, options.Issuer, options.AccountName, fingerprintBase32(secret))
+	}
+
+	return isValid, nil
+}
+
+// writeFileAtomic writes the data to the path atomically. It writes to a
+// temporary file in the same directory first and then renames it to the path,
+// so that a partially-written file is never left on crash.
+//
+// If perm is zero, filePermDefault (0o600) is used.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if perm == 0 {
+		perm = filePermDefault
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+
+	tmpName := tmpFile.Name()
+
+	// Remove the temporary file on failure. It is a no-op on success since the
+	// file is already renamed.
+	defer func() {
+		_ = os.Remove(tmpName)
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+
+		return errors.Wrap(err, "failed to write to temporary file")
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+
+		return errors.Wrap(err, "failed to sync temporary file")
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temporary file")
+	}
+
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return errors.Wrap(err, "failed to set file permission")
+	}
 
-	if !isValid || err != nil {
-		return false
+	if err := os.Rename(tmpName, path); err != nil {
+		return errors.Wrap(err, "failed to rename temporary file")
 	}
 
-	return true
+	return nil
 }
@@ -0,0 +1,147 @@
+package totp
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  DynamicTruncate()
+// ----------------------------------------------------------------------------
+
+func TestDynamicTruncate_rfc4226_example(t *testing.T) {
+	t.Parallel()
+
+	// The example of RFC-4226, section 5.4
+	hmacResult, err := hex.DecodeString("1f8698690e02ca16618550ef7f19da8e945b555a")
+	require.NoError(t, err)
+
+	value, err := DynamicTruncate(hmacResult)
+	require.NoError(t, err)
+
+	require.Equal(t, uint32(0x50ef7f19), value)
+	require.Equal(t, uint32(1357872921), value)
+	require.Equal(t, uint32(872921), value%1_000_000, "6 digit passcode should be 872921")
+}
+
+func TestDynamicTruncate_short_input(t *testing.T) {
+	t.Parallel()
+
+	_, err := DynamicTruncate(nil)
+	require.ErrorContains(t, err, "empty HMAC result")
+
+	_, err = DynamicTruncate([]byte{0xf0, 0xf0, 0xf0})
+	require.ErrorContains(t, err, "HMAC result too short")
+
+	// The sign bit is masked
+	value, err := DynamicTruncate([]byte{0xff, 0xff, 0xff, 0xf0})
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x7ffffff0), value)
+
+	// MD5 length. The offset is not adjusted, so the offsets larger than 12 are
+	// out of range.
+	hmacResult := make([]byte, 16)
+	hmacResult[15] = 0x0c
+	hmacResult[12] = 0x01
+
+	value, err = DynamicTruncate(hmacResult)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x0100000c), value, "offset should be 12")
+
+	hmacResult[15] = 0x0d
+
+	_, err = DynamicTruncate(hmacResult)
+	require.ErrorContains(t, err, "HMAC result too short: 16 bytes for the offset 13")
+}
+
+// ----------------------------------------------------------------------------
+//  GenerateURI()
+// ----------------------------------------------------------------------------
+
+func TestGenerateURI_error(t *testing.T) {
+	t.Parallel()
+
+	uri, err := GenerateURI("", "alice@example.com")
+
+	require.Error(t, err, "missing issuer should return error")
+	require.Contains(t, err.Error(), "failed to generate URI")
+	require.Empty(t, uri, "it should be empty on error")
+}
+
+// ----------------------------------------------------------------------------
+//  ValidateE()
+// ----------------------------------------------------------------------------
+
+func TestValidateE(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err, "failed to create key during test setup")
+
+	passcode, err := key.PassCode()
+	require.NoError(t, err)
+
+	isValid, err := ValidateE(passcode, key.Secret.Base32(), key.Options)
+	require.NoError(t, err)
+	require.True(t, isValid)
+
+	// Wrong but well-formed passcode
+	wrong := "000000"
+	if passcode == wrong {
+		wrong = "111111"
+	}
+
+	isValid, err = ValidateE(wrong, key.Secret.Base32(), key.Options)
+	require.NoError(t, err, "wrong passcode should not be an error")
+	require.False(t, isValid)
+
+	// Unsupported algorithm. It used to panic in the underlying library.
+	optsBad := key.Options
+	optsBad.Algorithm = "BAD"
+
+	isValid, err = ValidateE(passcode, key.Secret.Base32(), optsBad)
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	require.False(t, isValid)
+	require.False(t, Validate(passcode, key.Secret.Base32(), optsBad),
+		"boolean API should return false as well")
+
+	// Malformed secret
+	isValid, err = ValidateE(passcode, "not base32!", key.Options)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to validate passcode")
+	require.False(t, isValid)
+}
+
+// ----------------------------------------------------------------------------
+//  CounterToTime() and TimeToCounter()
+// ----------------------------------------------------------------------------
+
+func TestCounterToTime_TimeToCounter(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		time    time.Time
+		period  uint
+		counter uint64
+		start   time.Time
+	}{
+		// Test vector of RFC-6238 (T = 00000000023523ED)
+		{time.Unix(1111111111, 0), 30, 0x23523ED, time.Unix(1111111110, 0)},
+		{time.Unix(1111111111, 0), 0, 0x23523ED, time.Unix(1111111110, 0)}, // default period
+		{time.Unix(1111111111, 0), 60, 18518518, time.Unix(1111111080, 0)},
+		{time.Unix(59, 0), 60, 0, time.Unix(0, 0)},
+		{time.Unix(-1, 0), 30, 0, time.Unix(0, 0)}, // before the epoch
+	} {
+		counter := TimeToCounter(test.time, test.period)
+
+		require.Equal(t, test.counter, counter, "time: %v, period: %d", test.time, test.period)
+		require.Equal(t, test.start.UTC(), CounterToTime(counter, test.period))
+	}
+
+	require.Equal(t, int64(math.MaxInt64), CounterToTime(math.MaxUint64, 30).Unix(),
+		"huge counters should saturate")
+}
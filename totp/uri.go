@@ -1,9 +1,14 @@
 package totp
 
 import (
+	"fmt"
 	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -31,24 +36,44 @@ func NewURI(uri string) URI {
 	return URI(uri)
 }
 
+// reOTPAuthURI matches the candidates of otpauth URIs in a text. It stops at
+// whitespaces, quotes and angle brackets that are not allowed in URIs.
+//
+//nolint:gochecknoglobals // compile once
+var reOTPAuthURI = regexp.MustCompile(`otpauth://[^\s"'<>` + "`" + `]+`)
+
+// ExtractURIs extracts all the valid otpauth URIs from the given text, such as
+// config blobs or emails, in the order of appearance.
+//
+// Each candidate is validated with the Check() method and the malformed ones,
+// including URIs split by line breaks, are skipped. Trailing punctuation such
+// as "." or ")" is removed. Missing algorithm, digits and period are implied as
+// default the same as GenKeyFromURI. Use GenKeyFromURI to import the results.
+func ExtractURIs(text string) []URI {
+	var uris []URI
+
+	for _, candidate := range reOTPAuthURI.FindAllString(text, -1) {
+		uri := URI(strings.TrimRight(candidate, ".,;:!?)]}"))
+
+		if err := uri.withDefaults().Check(); err != nil {
+			continue
+		}
+
+		uris = append(uris, uri)
+	}
+
+	return uris
+}
+
 // ----------------------------------------------------------------------------
 //  Methods
 // ----------------------------------------------------------------------------
 
 // AccountName returns the account name from the URI.
 func (u URI) AccountName() string {
-	path := strings.TrimPrefix(u.Path(), "/")
-	if path == "" {
-		return ""
-	}
+	_, accountName := u.splitLabel(":")
 
-	index := strings.Index(path, ":")
-
-	if index == -1 {
-		return path
-	}
-
-	return path[index+1:]
+	return accountName
 }
 
 // Algorithm returns the algorithm from the URI.
@@ -61,20 +86,97 @@ func (u URI) Algorithm() string {
 	return parsedURI.Query().Get("algorithm")
 }
 
+// Canonical returns the URI in a canonical form for reliable comparison and
+// deduplication of semantically identical URIs. It returns an empty URI if the
+// URI is malformed.
+//
+// The canonical form has:
+//
+//   - Lowercased scheme and host.
+//   - Normalized label encoding. Spaces as "%20" and the separator as ":".
+//   - Fixed parameter order. The "secret", "issuer", "algorithm", "digits" and
+//     "period" first, then the others in alphabetical order.
+//   - Uppercased secret and algorithm with no padding in the secret.
+//   - Spaces in the parameter values as "%20".
+//
+// Note that it does not validate the URI. Use the Check() method for that.
+func (u URI) Canonical() URI {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil || parsedURI.Scheme == "" {
+		return ""
+	}
+
+	query := parsedURI.Query()
+
+	if query.Has("secret") {
+		query.Set("secret", strings.TrimRight(strings.ToUpper(query.Get("secret")), "="))
+	}
+
+	if query.Has("algorithm") {
+		query.Set("algorithm", strings.ToUpper(query.Get("algorithm")))
+	}
+
+	keysFirst := []string{"secret", "issuer", "algorithm", "digits", "period"}
+	keysRest := make([]string, 0, len(query))
+
+	for key := range query {
+		if !slices.Contains(keysFirst, key) {
+			keysRest = append(keysRest, key)
+		}
+	}
+
+	slices.Sort(keysRest)
+
+	params := make([]string, 0, len(query))
+
+	for _, key := range append(keysFirst, keysRest...) {
+		for _, value := range query[key] {
+			params = append(params, escapeQuery(key)+"="+escapeQuery(value))
+		}
+	}
+
+	issuer, accountName := u.splitLabel(":")
+
+	label := escapePath(accountName)
+	if issuer != "" {
+		label = strings.ReplaceAll(escapePath(issuer), "+", "%2B") + ":" + label
+	}
+
+	canonical := strings.ToLower(parsedURI.Scheme) + "://" + strings.ToLower(parsedURI.Host) + "/" + label
+	if len(params) > 0 {
+		canonical += "?" + strings.Join(params, "&")
+	}
+
+	return URI(canonical)
+}
+
 // Check returns true if the URI is correctly formatted and required fields are
 // set.
 //
-//nolint:cyclop // cyclomatic complexity is 11 but it's fine
+// The issuer in the path (label) and the query must match if both are set. See
+// the Issuer() method for details.
 func (u URI) Check() error {
+	//nolint:exhaustruct // default options for parsing
+	return u.check(Options{})
+}
+
+// check is the implementation of Check(). The parsing related options, such as
+// lenientIssuer and labelSeparator, are taken into account.
+//
+//nolint:cyclop // cyclomatic complexity is 12 but it's fine
+func (u URI) check(opts Options) error {
+	issuer := u.issuerWith(opts.labelSep(), opts.lenientIssuer)
+	_, accountName := u.splitLabel(opts.labelSep())
+
 	// Check required fields
 	switch {
 	case u.Scheme() != "otpauth":
 		return errors.New("invalid scheme. it always should be `otpauth`")
-	case u.Host() != "totp":
-		return errors.New("invalid host. it always should be `totp`")
-	case u.Issuer() == "":
+	case u.Host() != opts.otpType().String():
+		return errors.Errorf("invalid host. it always should be `%s`", opts.otpType())
+	case issuer == "":
 		return errors.New("missing issuer or issuer is not set correctly")
-	case u.AccountName() == "":
+	case accountName == "":
 		return errors.New("missing account name")
 	case u.Secret() == nil:
 		return errors.New("missing secret")
@@ -86,24 +188,70 @@ func (u URI) Check() error {
 		return errors.New("missing period or zero period set")
 	}
 
-	// Check supported algorithms
-	if algo := Algorithm(u.Algorithm()); !algo.IsSupported() {
+	// Check supported algorithms. Case-insensitive since some apps use lowercase
+	if algo := Algorithm(strings.ToUpper(u.Algorithm())); !algo.IsSupported() {
 		return errors.Errorf("unsupported algorithm: %s", algo)
 	}
 
-	// Check length of secret. According to the RFC4226, the secret MUST be at
-	// least 128 bits = 16 bytes.
-	// See:
-	//   https://www.rfc-editor.org/rfc/rfc4226#section-4
-	minLenBytes := 16
+	// Check supported digits. Unsupported digits are silently treated as 6
+	// digits by Digits.OTPDigits() which causes validation mismatches.
+	if digits := Digits(u.Digits()); !digits.IsSupported() {
+		return errors.Errorf("unsupported digits: %d. it should be 6 or 8", u.Digits())
+	}
 
-	if len(u.Secret().Bytes()) < minLenBytes {
-		return errors.New("secret is too short. it should be at least 16 bytes")
+	if !opts.isPeriodAccepted(u.Period()) {
+		return errors.Wrapf(ErrUnsupportedPeriod, "period %d is not in the accepted periods %v",
+			u.Period(), opts.acceptedPeriods)
+	}
+
+	// Check length of secret. See the SecretSizeMin constant for details.
+	if err := u.Secret().Validate(); err != nil {
+		return errors.Wrap(err, "invalid secret. it may be truncated or corrupted")
 	}
 
 	return nil
 }
 
+// Counter returns the value of the "counter" parameter from the URI query and
+// true if it is set as an unsigned integer.
+//
+// The parameter is used by HOTP (otpauth://hotp/...) URIs and not by TOTP. Only
+// the HOTP keys from GenHOTPKeyFromURI() emit it, GenKeyFromURI() rejects HOTP
+// URIs.
+func (u URI) Counter() (uint64, bool) {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil {
+		return 0, false
+	}
+
+	base10 := 10
+	bitSize := 64
+
+	counter, err := strconv.ParseUint(parsedURI.Query().Get("counter"), base10, bitSize)
+	if err != nil {
+		return 0, false
+	}
+
+	return counter, true
+}
+
+// Created returns the creation time of the key from the "created" parameter of
+// the URI query in RFC3339. It returns the zero time if the parameter is not set
+// or malformed. See Key.URIValues().
+func (u URI) Created() time.Time {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil {
+		return time.Time{}
+	}
+
+	created, err := time.Parse(time.RFC3339, parsedURI.Query().Get("created"))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return created.UTC()
+}
+
 // Digits returns the number of digits a TOTP hash should have from the URI query.
 func (u URI) Digits() uint {
 	parsedURI, err := url.Parse(string(u))
@@ -122,6 +270,63 @@ func (u URI) Digits() uint {
 	return 0
 }
 
+// DisplayLabel returns the "Issuer:Account" label of the URI for display, such
+// as in the UIs with limited width. It is a presentation helper and not meant to
+// be parsed. See Issuer() and AccountName() for the raw values.
+//
+// If the label is longer than maxLen characters (runes), it is truncated with an
+// ellipsis ("…"). The longer of the issuer and the local part of the account
+// name (before "@") is shortened first, so that the domain of the account, such
+// as "@example.com", is preserved when possible. If it still does not fit, the
+// whole label is truncated at the end.
+//
+// The issuer is taken leniently as IssuerLenient() does and omitted with the
+// colon if empty. Zero or negative maxLen returns the label as is.
+func (u URI) DisplayLabel(maxLen int) string {
+	const sep = ":"
+
+	issuer := []rune(u.IssuerLenient())
+	local := []rune(u.AccountName())
+
+	var domain []rune
+
+	if index := strings.LastIndex(string(local), "@"); index > 0 && index < len(string(local))-1 {
+		account := string(local)
+		local, domain = []rune(account[:index]), []rune(account[index:])
+	}
+
+	render := func(lenIssuer, lenLocal int) string {
+		label := ellipsize(local, lenLocal) + string(domain)
+		if len(issuer) > 0 {
+			label = ellipsize(issuer, lenIssuer) + sep + label
+		}
+
+		return label
+	}
+
+	full := render(len(issuer), len(local))
+	if maxLen <= 0 || utf8.RuneCountInString(full) <= maxLen {
+		return full
+	}
+
+	// Shrink the longer part one by one. Each part keeps at least the ellipsis.
+	lenIssuer, lenLocal := len(issuer), len(local)
+
+	for lenIssuer > 1 || lenLocal > 1 {
+		if lenIssuer >= lenLocal {
+			lenIssuer--
+		} else {
+			lenLocal--
+		}
+
+		if label := render(lenIssuer, lenLocal); utf8.RuneCountInString(label) <= maxLen {
+			return label
+		}
+	}
+
+	return ellipsize([]rune(full), maxLen)
+}
+
 // Host returns the host name from the URI. This should be `totp`.
 func (u URI) Host() string {
 	parsedURI, err := url.Parse(string(u))
@@ -135,42 +340,55 @@ func (u URI) Host() string {
 // Issuer returns the issuer from the URI. Similar to IssuerFromPath() but returns
 // the issuer from the query string instead of the path.
 func (u URI) Issuer() string {
+	return u.issuerWith(":", false)
+}
+
+// IssuerLenient is similar to Issuer() but does not require the issuers in the
+// path (label) and the query to match. It returns the issuer from the path if
+// present, otherwise the issuer from the query.
+//
+// This is useful to import URIs from apps that set the issuer in only one of
+// them or set them differently.
+func (u URI) IssuerLenient() string {
+	return u.issuerWith(":", true)
+}
+
+// IssuerMatch is similar to Issuer() but compares the issuers in the path
+// (label) and the query under the given mode. It returns the issuer in the query
+// if both are set and match. Otherwise the same as Issuer().
+//
+// This accommodates apps that differ only in case between the label and the
+// query issuer. Issuer() is the same as IssuerMatch(MatchExact).
+func (u URI) IssuerMatch(mode MatchMode) string {
 	parsedURI, err := url.Parse(string(u))
 	if err != nil {
 		return ""
 	}
 
-	issuerPath := u.IssuerFromPath()
+	issuerPath, _ := u.splitLabel(":")
 	issuerQuery := parsedURI.Query().Get("issuer")
 
-	// Search issuer from the query string
-	switch {
-	case issuerQuery != "" && issuerPath == "":
-		return issuerQuery
-	case issuerQuery == "" && issuerPath != "":
-		return issuerPath
-	case issuerQuery == issuerPath:
-		return issuerQuery
+	if issuerPath != "" && issuerQuery != "" {
+		if mode.equal(issuerPath, issuerQuery) {
+			return issuerQuery
+		}
+
+		return ""
 	}
 
-	return ""
+	return u.issuerWith(":", false)
 }
 
 // IssuerFromPath returns the issuer from the URI. Similar to Issuer() but returns
 // the issuer from the path instead of the query string.
+//
+// Some exporters encode the spaces in the issuer of the label as "+" rather than
+// "%20". Therefore, "+" in the issuer is treated as a space, the same as in the
+// query. Use "%2B" for a literal "+".
 func (u URI) IssuerFromPath() string {
-	path := strings.TrimPrefix(u.Path(), "/")
-	if path == "" {
-		return ""
-	}
-
-	index := strings.Index(path, ":")
+	issuer, _ := u.splitLabel(":")
 
-	if index == -1 {
-		return ""
-	}
-
-	return path[:index]
+	return issuer
 }
 
 // Path returns the path from the URI. Which is used as a "label" for the TOTP.
@@ -186,6 +404,30 @@ func (u URI) Path() string {
 	return parsedURI.Path
 }
 
+// OTPType returns the type of the OTP of the URI. Such as to branch the import
+// flows before creating the Key object, since GenKeyFromURI() accepts TOTP URIs
+// only. It is the typed form of Host() and the host is case-insensitive.
+//
+// It returns an error if the URI is malformed, the scheme is not "otpauth" or
+// the type is unknown.
+func (u URI) OTPType() (OTPType, error) {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse URI")
+	}
+
+	if !strings.EqualFold(parsedURI.Scheme, "otpauth") {
+		return "", errors.Errorf("invalid scheme: %q. it always should be `otpauth`", parsedURI.Scheme)
+	}
+
+	switch otpType := OTPType(strings.ToLower(parsedURI.Host)); otpType {
+	case OTPTypeTOTP, OTPTypeHOTP:
+		return otpType, nil
+	default:
+		return "", errors.Errorf("unknown OTP type: %q", parsedURI.Host)
+	}
+}
+
 // Period returns the number of seconds a TOTP hash is valid for from the URI.
 // If the period is not set or the URL is invalid, it returns 0.
 func (u URI) Period() uint {
@@ -235,3 +477,122 @@ func (u URI) Secret() Secret {
 func (u URI) String() string {
 	return string(u)
 }
+
+// ellipsize returns the runes as a string truncated to the given length with an
+// ellipsis ("…") at the end if longer. The ellipsis counts as one.
+func ellipsize(runes []rune, length int) string {
+	switch {
+	case len(runes) <= length:
+		return string(runes)
+	case length <= 0:
+		return ""
+	default:
+		return string(runes[:length-1]) + "…"
+	}
+}
+
+// escapeQuery escapes the string to be used in the query of a URL. Unlike
+// url.QueryEscape, spaces are escaped as "%20".
+func escapeQuery(query string) string {
+	return strings.ReplaceAll(url.QueryEscape(query), "+", "%20")
+}
+
+// escapePath escapes the string to be used in the path of a URL.
+func escapePath(path string) string {
+	//nolint:exhaustruct // only the path is used for escaping
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// issuerWith returns the issuer with the given label separator. If lenient is
+// true, the issuers in the path and the query do not need to match. See the
+// Issuer() and IssuerLenient() methods for details.
+func (u URI) issuerWith(sep string, lenient bool) string {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil {
+		return ""
+	}
+
+	issuerPath, _ := u.splitLabel(sep)
+	issuerQuery := parsedURI.Query().Get("issuer")
+
+	if lenient && issuerPath != "" {
+		return issuerPath
+	}
+
+	// Search issuer from the query string
+	switch {
+	case issuerQuery != "" && issuerPath == "":
+		return issuerQuery
+	case issuerQuery == "" && issuerPath != "":
+		return issuerPath
+	case issuerQuery == issuerPath:
+		return issuerQuery
+	}
+
+	return ""
+}
+
+// splitLabel splits the label (path) of the URI into the issuer and the account
+// name. The separator is the first occurrence of sep, either literal or
+// percent-encoded. Such as ":" or "%3A" for the standard colon.
+//
+// The "+" in the issuer is decoded as a space. The "+" in the account name is
+// kept as is, since it is common in email addresses. Such as "alice+totp@example.com".
+func (u URI) splitLabel(sep string) (string, string) {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil {
+		return "", ""
+	}
+
+	label := strings.TrimPrefix(parsedURI.EscapedPath(), "/")
+
+	// Percent-encoded form of the separator. Such as "%3A" for ":".
+	var sepEnc strings.Builder
+	for _, b := range []byte(sep) {
+		fmt.Fprintf(&sepEnc, "%%%02X", b)
+	}
+
+	index, lenSep := strings.Index(label, sep), len(sep)
+	if indexEnc := strings.Index(strings.ToUpper(label), sepEnc.String()); indexEnc != -1 && (index == -1 || indexEnc < index) {
+		index, lenSep = indexEnc, sepEnc.Len()
+	}
+
+	if sep == "" || index == -1 {
+		accountName, _ := url.PathUnescape(label)
+
+		return "", accountName
+	}
+
+	issuer, _ := url.PathUnescape(strings.ReplaceAll(label[:index], "+", "%20"))
+	accountName, _ := url.PathUnescape(label[index+lenSep:])
+
+	return issuer, accountName
+}
+
+// withDefaults returns a copy of the URI with the missing algorithm, digits and
+// period parameters set to their default values. Parameters present in the URI,
+// even if empty or invalid, are left as is.
+//
+// It is used to import URIs generated with the WithMinimalURI() option.
+func (u URI) withDefaults() URI {
+	parsedURI, err := url.Parse(string(u))
+	if err != nil {
+		return u
+	}
+
+	query := parsedURI.Query()
+
+	for key, value := range map[string]string{
+		"algorithm": OptionAlgorithmDefault.String(),
+		"digits":    OptionDigitsDefault.String(),
+		"period":    strconv.FormatUint(uint64(OptionPeriodDefault), 10),
+	} {
+		if !query.Has(key) {
+			query.Set(key, value)
+		}
+	}
+
+	parsedURI.RawQuery = query.Encode()
+
+	return URI(parsedURI.String())
+}
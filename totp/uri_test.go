@@ -17,32 +17,32 @@ var badURIs = []struct {
 }{
 	{
 		"ipfs://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"invalid scheme",
 	},
 	{
 		"otpauth://hotp/Example.com:alice@example.com?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"invalid host",
 	},
 	{
 		"otpauth://totp/alice@example.com?algorithm=SHA1&" +
-			"digits=12&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"missing issuer or issuer is not set correctly",
 	},
 	{
 		"otpauth://totp/Example.com:?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"missing account name",
 	},
 	{
 		"otpauth://totp/Example.com:alice@example.com?" +
-			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"missing algorithm",
 	},
 	{
 		"otpauth://totp/Example.com:alice@example.com?algorithm=BLAKE3&" +
-			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"unsupported algorithm",
 	},
 	{
@@ -57,23 +57,33 @@ var badURIs = []struct {
 	},
 	{
 		"otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"missing period or zero period set",
 	},
 	{
 		"otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&period=0&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+			"digits=6&issuer=Example.com&period=0&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
 		"missing period or zero period set",
 	},
 	{
 		"otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&period=60",
+			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+		"unsupported digits: 12",
+	},
+	{
+		"otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+			"digits=7&issuer=Example.com&period=60&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3",
+		"unsupported digits: 7",
+	},
+	{
+		"otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+			"digits=6&issuer=Example.com&period=60",
 		"missing secret",
 	},
 	{
 		"otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
-			"digits=12&issuer=Example.com&period=60&secret=QF7N673VMVHYW",
-		"secret is too short. it should be at least 16 bytes",
+			"digits=6&issuer=Example.com&period=60&secret=QF7N673VMVHYW",
+		"it should be at least 16 bytes (RFC4226 minimum): secret is too short",
 	},
 }
 
@@ -117,6 +127,39 @@ func TestURI_malformed_uri(t *testing.T) {
 	require.Zero(t, uri.Period(), "the Period() should be zero on malformed URI")
 }
 
+// ----------------------------------------------------------------------------
+//  ExtractURIs()
+// ----------------------------------------------------------------------------
+
+func TestExtractURIs(t *testing.T) {
+	t.Parallel()
+
+	const (
+		uriAlice = "otpauth://totp/Example.com:alice@example.com?algorithm=SHA1&" +
+			"digits=6&issuer=Example.com&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+		uriBob = "otpauth://totp/Example.com:bob@example.com?issuer=Example.com&" +
+			"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3" // minimal URI
+	)
+
+	text := "Hi,\nHere are your keys: " + uriAlice + ".\n" +
+		"Backup (" + uriBob + ")\n" +
+		`<a href="` + uriAlice + `">link</a>` + "\n" +
+		"Broken: otpauth://totp/Example.com:carol@example.com?issuer=Example.com&secr\n" +
+		"et=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3\n" +
+		"Malformed: otpauth://hotp/foo otpauth://"
+
+	actual := ExtractURIs(text)
+
+	require.Equal(t, []URI{URI(uriAlice), URI(uriBob), URI(uriAlice)}, actual)
+
+	for _, uri := range actual {
+		_, err := GenKeyFromURI(uri.String())
+		require.NoError(t, err, "extracted URI should be importable")
+	}
+
+	require.Nil(t, ExtractURIs("no URIs here"))
+}
+
 // ----------------------------------------------------------------------------
 //  URI.AccountName()
 // ----------------------------------------------------------------------------
@@ -130,6 +173,66 @@ func TestURI_AccountName_name_in_label_with_no_colon(t *testing.T) {
 	require.Equal(t, "example.com", uri.AccountName(), "label without colon should treat as account name")
 }
 
+func TestURI_plus_as_space_in_label(t *testing.T) {
+	t.Parallel()
+
+	// Issuer label encoded with "+" as space, like some exporters do
+	uri := URI("otpauth://totp/Example+Co:alice+totp@example.com?algorithm=SHA1&" +
+		"digits=6&issuer=Example+Co&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+
+	require.Equal(t, "Example Co", uri.IssuerFromPath(), "plus in the issuer should be a space")
+	require.Equal(t, "Example Co", uri.Issuer(), "issuers in the path and query should match")
+	require.Equal(t, "alice+totp@example.com", uri.AccountName(), "plus in the account name should be kept")
+	require.NoError(t, uri.Check())
+
+	// %20, %2B and %3A
+	uri = URI("otpauth://totp/Example%20Co%2B%3Aalice?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3")
+
+	require.Equal(t, "Example Co+", uri.IssuerFromPath())
+	require.Equal(t, "alice", uri.AccountName())
+
+	// Round-trip of a literal plus in the issuer
+	key, err := GenerateKey("Example+Co", "alice+totp@example.com")
+	require.NoError(t, err)
+
+	keyImported, err := GenKeyFromURI(key.URI())
+	require.NoError(t, err)
+	require.Equal(t, "Example+Co", keyImported.Options.Issuer)
+	require.Equal(t, "alice+totp@example.com", keyImported.Options.AccountName)
+}
+
+// ----------------------------------------------------------------------------
+//  URI.Canonical()
+// ----------------------------------------------------------------------------
+
+func TestURI_Canonical(t *testing.T) {
+	t.Parallel()
+
+	expect := URI("otpauth://totp/Example%20Co:alice%20smith@example.com?" +
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&issuer=Example%20Co&algorithm=SHA1&digits=6&period=30&image=foo")
+
+	for index, uri := range []URI{
+		expect,
+		"otpauth://totp/Example%20Co:alice%20smith@example.com?" +
+			"algorithm=sha1&digits=6&image=foo&issuer=Example+Co&period=30&secret=qf7n673vmvhywatkicrua7v5mugfg3z3",
+		"OTPAUTH://TOTP/Example+Co%3Aalice%20smith@example.com?" +
+			"period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3====&image=foo&digits=6&issuer=Example%20Co&algorithm=SHA1",
+	} {
+		require.Equal(t, expect, uri.Canonical(), "test #%d", index+1)
+	}
+
+	// Same as Key.URI() except the parameter order
+	key, err := GenKeyFromURI(string(expect))
+	require.NoError(t, err)
+	require.Equal(t, URI("otpauth://totp/Example%20Co:alice%20smith@example.com?"+
+		"secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&issuer=Example%20Co&algorithm=SHA1&digits=6&period=30"),
+		URI(key.URI()).Canonical())
+
+	// Malformed
+	require.Empty(t, URI("otpauth://totp/%zz?secret=foo").Canonical())
+	require.Empty(t, URI("not a uri").Canonical())
+}
+
 // ----------------------------------------------------------------------------
 //  URI.Check()
 // ----------------------------------------------------------------------------
@@ -146,6 +249,79 @@ func TestURI_Check_error_msg(t *testing.T) {
 	}
 }
 
+// ----------------------------------------------------------------------------
+//  URI.Counter()
+// ----------------------------------------------------------------------------
+
+func TestURI_Counter(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		uri     string
+		counter uint64
+		ok      bool
+	}{
+		{"otpauth://hotp/Example.com:alice?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=42", 42, true},
+		{"otpauth://hotp/Example.com:alice?counter=0", 0, true},
+		{"otpauth://hotp/Example.com:alice?counter=18446744073709551615", 18446744073709551615, true},
+		{"otpauth://hotp/Example.com:alice?counter=-1", 0, false},
+		{"otpauth://hotp/Example.com:alice?counter=foo", 0, false},
+		{testRendererURI, 0, false}, // TOTP
+		{"this is a bad URI" + string(rune(0x7f)), 0, false},
+	} {
+		counter, ok := URI(test.uri).Counter()
+
+		require.Equal(t, test.ok, ok, "uri: %v", test.uri)
+		require.Equal(t, test.counter, counter, "uri: %v", test.uri)
+	}
+
+	// TOTP keys never emit the counter
+	key, err := GenerateKey("Example.com", "alice@example.com")
+	require.NoError(t, err)
+
+	_, ok := URI(key.URI()).Counter()
+	require.False(t, ok)
+}
+
+// ----------------------------------------------------------------------------
+//  URI.DisplayLabel()
+// ----------------------------------------------------------------------------
+
+func TestURI_DisplayLabel(t *testing.T) {
+	t.Parallel()
+
+	const query = "?algorithm=SHA1&digits=6&period=30&secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3"
+
+	uriFull := URI("otpauth://totp/Example.com:alice@example.com" + query + "&issuer=Example.com")
+	uriNoIssuer := URI("otpauth://totp/alice@example.com" + query)
+
+	keyUnicode, err := GenerateKey("日本語サービス", "bob")
+	require.NoError(t, err)
+
+	for index, test := range []struct {
+		uri    URI
+		maxLen int
+		expect string
+	}{
+		{uriFull, 0, "Example.com:alice@example.com"},
+		{uriFull, 29, "Example.com:alice@example.com"},
+		{uriFull, 25, "Exampl…:alice@example.com"},
+		{uriFull, 18, "E…:al…@example.com"},
+		{uriFull, 10, "Example.c…"},
+		{uriFull, 1, "…"},
+		{uriNoIssuer, 14, "a…@example.com"},
+		{URI(keyUnicode.URI()), 8, "日本語…:bob"},
+	} {
+		actual := test.uri.DisplayLabel(test.maxLen)
+
+		require.Equal(t, test.expect, actual, "test #%d failed", index+1)
+
+		if test.maxLen > 0 {
+			require.LessOrEqual(t, len([]rune(actual)), test.maxLen, "test #%d is too long", index+1)
+		}
+	}
+}
+
 // ----------------------------------------------------------------------------
 //  URI.Issuer()
 // ----------------------------------------------------------------------------
@@ -181,6 +357,109 @@ func TestURI_Issuer_not_match_label_and_query(t *testing.T) {
 	require.Empty(t, uri.Issuer(), "unmatched issuer between label and query should be empty")
 }
 
+// ----------------------------------------------------------------------------
+//  URI.IssuerLenient()
+// ----------------------------------------------------------------------------
+
+func TestURI_IssuerLenient_golden(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		uri    string
+		issuer string
+		msg    string
+	}{
+		{"otpauth://totp/Example.com:alice@example.com", "Example.com", "issuer in path should be used"},
+		{"otpauth://totp/alice@example.com?issuer=Example.org", "Example.org", "issuer in query should be used"},
+		{"otpauth://totp/Example.org:alice@example.com?issuer=Example.com", "Example.org", "issuer in path should be preferred"},
+		{"otpauth://totp/alice@example.com", "", "missing issuer should be empty"},
+	} {
+		uri := URI(test.uri)
+
+		require.Equal(t, test.issuer, uri.IssuerLenient(), "%v; uri: %v", test.msg, test.uri)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  URI.IssuerMatch()
+// ----------------------------------------------------------------------------
+
+func TestURI_IssuerMatch_golden(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		uri    string
+		mode   MatchMode
+		issuer string
+	}{
+		// Same issuer
+		{"otpauth://totp/Example.com:alice?issuer=Example.com", MatchExact, "Example.com"},
+		{"otpauth://totp/Example.com:alice?issuer=Example.com", MatchCaseInsensitive, "Example.com"},
+		{"otpauth://totp/Example.com:alice?issuer=Example.com", MatchFold, "Example.com"},
+		// Case-differing issuer. The query one is returned.
+		{"otpauth://totp/EXAMPLE.COM:alice?issuer=Example.com", MatchExact, ""},
+		{"otpauth://totp/EXAMPLE.COM:alice?issuer=Example.com", MatchCaseInsensitive, "Example.com"},
+		{"otpauth://totp/EXAMPLE.COM:alice?issuer=Example.com", MatchFold, "Example.com"},
+		// Case-differing non-ASCII issuer
+		{"otpauth://totp/%CE%A9mega:alice?issuer=%CF%89MEGA", MatchCaseInsensitive, ""},
+		{"otpauth://totp/%CE%A9mega:alice?issuer=%CF%89MEGA", MatchFold, "ωMEGA"},
+		// Different issuer
+		{"otpauth://totp/Example.org:alice?issuer=Example.com", MatchFold, ""},
+		// Either one
+		{"otpauth://totp/Example.com:alice", MatchCaseInsensitive, "Example.com"},
+		{"otpauth://totp/alice?issuer=Example.com", MatchCaseInsensitive, "Example.com"},
+		// Unknown mode falls back to exact
+		{"otpauth://totp/EXAMPLE.COM:alice?issuer=Example.com", MatchMode(99), ""},
+	} {
+		uri := URI(test.uri)
+
+		require.Equal(t, test.issuer, uri.IssuerMatch(test.mode), "mode: %d; uri: %v", test.mode, test.uri)
+	}
+
+	// Default stays exact
+	uri := URI("otpauth://totp/Example.org:alice@example.com?issuer=Example.com")
+	require.Equal(t, uri.Issuer(), uri.IssuerMatch(MatchExact))
+}
+
+// ----------------------------------------------------------------------------
+//  URI.OTPType()
+// ----------------------------------------------------------------------------
+
+func TestURI_OTPType(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		uri    string
+		expect OTPType
+	}{
+		{"otpauth://totp/Example.com:alice?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3", OTPTypeTOTP},
+		{"otpauth://hotp/Example.com:alice?secret=QF7N673VMVHYWATKICRUA7V5MUGFG3Z3&counter=1", OTPTypeHOTP},
+		{"OTPAUTH://TOTP/Example.com:alice", OTPTypeTOTP},
+	} {
+		otpType, err := URI(test.uri).OTPType()
+
+		require.NoError(t, err, "uri: %v", test.uri)
+		require.Equal(t, test.expect, otpType, "uri: %v", test.uri)
+	}
+
+	for _, test := range []struct {
+		uri    string
+		expect string
+	}{
+		{"otpauth://%zz/Example.com:alice", "failed to parse URI"},
+		{"https://totp/Example.com:alice", "invalid scheme"},
+		{"otpauth://motp/Example.com:alice", `unknown OTP type: "motp"`},
+		{"", "invalid scheme"},
+	} {
+		otpType, err := URI(test.uri).OTPType()
+
+		require.ErrorContains(t, err, test.expect, "uri: %v", test.uri)
+		require.Empty(t, otpType)
+	}
+
+	require.Equal(t, "hotp", OTPTypeHOTP.String())
+}
+
 // ----------------------------------------------------------------------------
 //  URI.Secret()
 // ----------------------------------------------------------------------------